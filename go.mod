@@ -11,4 +11,5 @@ require (
 	k8s.io/apimachinery v0.21.2
 	k8s.io/client-go v0.21.2
 	sigs.k8s.io/controller-runtime v0.9.1
+	sigs.k8s.io/yaml v1.2.0
 )
@@ -13,3 +13,7 @@ var (
 func DisplayVersion() {
 	fmt.Printf("Version: %s\nBuildTime: %s\nGitCommit: %s\n", version, buildTime, gitCommit)
 }
+
+func Version() string {
+	return version
+}
@@ -14,5 +14,8 @@ func New(clientGetter types.ClientGetter) *cobra.Command {
 	rootCMD.AddCommand(newGenerateCmd(clientGetter))
 	rootCMD.AddCommand(newViewCmd(clientGetter))
 	rootCMD.AddCommand(newVerifyCmd(clientGetter))
+	rootCMD.AddCommand(newExpiryCmd(clientGetter))
+	rootCMD.AddCommand(newRenewCmd(clientGetter))
+	rootCMD.AddCommand(newCARolloverCmd(clientGetter))
 	return rootCMD
 }
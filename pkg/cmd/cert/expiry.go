@@ -0,0 +1,356 @@
+package cert
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/fabedge/fabctl/pkg/printer"
+	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusWarning  = "warning"
+	StatusCritical = "critical"
+	StatusExpired  = "expired"
+	StatusInvalid  = "invalid"
+
+	// exit codes of expiry command, 1 is kept for general errors
+	ExitCodeWarning  = 2
+	ExitCodeCritical = 3
+)
+
+// Expiry is the expiry information of the certificate in a secret
+type Expiry struct {
+	Namespace     string    `json:"namespace"`
+	Secret        string    `json:"secret"`
+	Subject       string    `json:"subject,omitempty"`
+	Issuer        string    `json:"issuer,omitempty"`
+	IsCA          bool      `json:"isCA"`
+	NotBefore     time.Time `json:"notBefore"`
+	NotAfter      time.Time `json:"notAfter"`
+	DaysRemaining int       `json:"daysRemaining"`
+	Status        string    `json:"status"`
+	Error         string    `json:"error,omitempty"`
+}
+
+type ExpiryList []Expiry
+
+// Count returns the number of certificates with one of specified statuses
+func (list ExpiryList) Count(statuses ...string) int {
+	count := 0
+	for _, e := range list {
+		for _, status := range statuses {
+			if e.Status == status {
+				count++
+				break
+			}
+		}
+	}
+
+	return count
+}
+
+func newExpiryCmd(clientGetter types.ClientGetter) *cobra.Command {
+	var (
+		caSecret     string
+		selector     string
+		warnDays     int
+		criticalDays int
+		textfile     string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "expiry [secretNames] [flags]",
+		Short: "Report expiry of certificates in CA secret and TLS secrets",
+		Long: `Report expiry of certificates in CA secret and TLS secrets, certificates are sorted by urgency.
+By default the CA secret and all TLS secrets matching the selector are checked, if secretNames are provided, only those secrets are checked.
+Exit code is 2 if any certificate expires within warn days, 3 if any certificate expires within critical days, is expired or can't be decoded.`,
+		Example: `Report expiry of CA certificate and certificates created by fabedge-operator:
+
+	fabctl cert expiry
+
+Report expiry of specified secrets:
+
+	fabctl cert expiry fabedge-agent-tls-edge1 connector-tls
+
+Write metrics to the directory of node-exporter's textfile collector:
+
+	fabctl cert expiry --prometheus-textfile=/var/lib/node_exporter/textfile/fabedge_certs.prom
+
+Print metrics in Prometheus text format:
+
+	fabctl cert expiry --prometheus-textfile=-`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if warnDays < 0 || criticalDays < 0 {
+				util.Exitf("warn days and critical days should not be negative\n")
+			}
+
+			if criticalDays > warnDays {
+				util.Exitf("critical days should not be greater than warn days\n")
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			cli := newClient(clientGetter)
+
+			list, err := checkExpiry(cli, caSecret, args, selector, warnDays, criticalDays, time.Now())
+			util.CheckError(err)
+
+			switch textfile {
+			case "":
+				p, err := clientGetter.GetPrinter()
+				util.CheckError(err)
+				util.CheckError(p.Print(list))
+			case "-":
+				list.WriteMetrics(os.Stdout)
+			default:
+				util.CheckError(writeTextfile(textfile, list))
+			}
+
+			if count := list.Count(StatusCritical, StatusExpired, StatusInvalid); count > 0 {
+				fmt.Fprintf(os.Stderr, "%d certificates expire within %d days, are expired or invalid\n", count, criticalDays)
+				os.Exit(ExitCodeCritical)
+			}
+
+			if count := list.Count(StatusWarning); count > 0 {
+				fmt.Fprintf(os.Stderr, "%d certificates expire within %d days\n", count, warnDays)
+				os.Exit(ExitCodeWarning)
+			}
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.StringVar(&caSecret, "ca-secret", "fabedge-ca", "The name of CA secret, it will be ignored if you provide a secretName. Set it to empty to skip CA certificate")
+	fs.StringVarP(&selector, "selector", "l", "fabedge.io/created-by=fabedge-operator", "Selector (label query) to filter TLS secrets. Selectors will be ignored if you provide a secretName.")
+	fs.IntVar(&warnDays, "warn-days", 30, "Certificates expire within the days are reported as warning")
+	fs.IntVar(&criticalDays, "critical-days", 7, "Certificates expire within the days are reported as critical")
+	fs.StringVar(&textfile, "prometheus-textfile", "", "Write metrics in Prometheus text format to the file for node-exporter's textfile collector, use '-' to write to stdout")
+
+	return cmd
+}
+
+func checkExpiry(cli secretClient, caSecret string, secretNames []string, selector string, warnDays, criticalDays int, now time.Time) (ExpiryList, error) {
+	var secrets []corev1.Secret
+	if len(secretNames) == 0 && caSecret != "" {
+		secret, err := cli.getSecret(caSecret)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secret)
+	}
+
+	found, err := cli.findSecrets(secretNames, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, secret := range found {
+		// CA secret may be labeled too
+		if len(secrets) > 0 && secret.Namespace == secrets[0].Namespace && secret.Name == caSecret {
+			continue
+		}
+		secrets = append(secrets, secret)
+	}
+
+	list := make(ExpiryList, 0, len(secrets))
+	for _, secret := range secrets {
+		list = append(list, newExpiry(secret, warnDays, criticalDays, now))
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if (a.Status == StatusInvalid) != (b.Status == StatusInvalid) {
+			return a.Status == StatusInvalid
+		}
+
+		if !a.NotAfter.Equal(b.NotAfter) {
+			return a.NotAfter.Before(b.NotAfter)
+		}
+
+		return a.Namespace+"/"+a.Secret < b.Namespace+"/"+b.Secret
+	})
+
+	return list, nil
+}
+
+func newExpiry(secret corev1.Secret, warnDays, criticalDays int, now time.Time) Expiry {
+	e := Expiry{
+		Namespace: secret.Namespace,
+		Secret:    secret.Name,
+	}
+
+	cert, err := parseCertificateFromSecret(secret)
+	if err != nil {
+		e.Status, e.Error = StatusInvalid, err.Error()
+		return e
+	}
+
+	e.Subject = cert.Subject.String()
+	e.Issuer = cert.Issuer.String()
+	e.IsCA = cert.IsCA
+	e.NotBefore = cert.NotBefore
+	e.NotAfter = cert.NotAfter
+	e.DaysRemaining = int(math.Floor(cert.NotAfter.Sub(now).Hours() / 24))
+
+	switch {
+	case !now.Before(cert.NotAfter):
+		e.Status = StatusExpired
+	case e.DaysRemaining < criticalDays:
+		e.Status = StatusCritical
+	case e.DaysRemaining < warnDays:
+		e.Status = StatusWarning
+	default:
+		e.Status = StatusOK
+	}
+
+	return e
+}
+
+// writeTextfile writes metrics to a temporary file then renames it to path,
+// so node-exporter won't read a partially written file
+func writeTextfile(path string, list ExpiryList) error {
+	var buf bytes.Buffer
+	list.WriteMetrics(&buf)
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// WriteMetrics writes expiry of certificates in Prometheus text format
+func (list ExpiryList) WriteMetrics(w io.Writer) {
+	fmt.Fprintln(w, "# HELP fabedge_certificate_expiry_timestamp_seconds The NotAfter of the certificate in a secret in unix seconds.")
+	fmt.Fprintln(w, "# TYPE fabedge_certificate_expiry_timestamp_seconds gauge")
+	for _, e := range list {
+		if e.Status != StatusInvalid {
+			fmt.Fprintf(w, "fabedge_certificate_expiry_timestamp_seconds{%s} %d\n", e.labels(), e.NotAfter.Unix())
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP fabedge_certificate_days_remaining Days remaining before the certificate in a secret expires.")
+	fmt.Fprintln(w, "# TYPE fabedge_certificate_days_remaining gauge")
+	for _, e := range list {
+		if e.Status != StatusInvalid {
+			fmt.Fprintf(w, "fabedge_certificate_days_remaining{%s} %d\n", e.labels(), e.DaysRemaining)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP fabedge_certificate_invalid Whether the certificate in a secret can't be decoded.")
+	fmt.Fprintln(w, "# TYPE fabedge_certificate_invalid gauge")
+	for _, e := range list {
+		invalid := 0
+		if e.Status == StatusInvalid {
+			invalid = 1
+		}
+		fmt.Fprintf(w, "fabedge_certificate_invalid{namespace=\"%s\",secret=\"%s\"} %d\n", escapeLabelValue(e.Namespace), escapeLabelValue(e.Secret), invalid)
+	}
+}
+
+func (e Expiry) labels() string {
+	return fmt.Sprintf(`namespace="%s",secret="%s",subject="%s",issuer="%s",is_ca="%t"`,
+		escapeLabelValue(e.Namespace),
+		escapeLabelValue(e.Secret),
+		escapeLabelValue(e.Subject),
+		escapeLabelValue(e.Issuer),
+		e.IsCA,
+	)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
+
+func (e Expiry) formatNotAfter() string {
+	if e.NotAfter.IsZero() {
+		return ""
+	}
+
+	return e.NotAfter.Format(time.RFC3339)
+}
+
+func (e Expiry) formatDaysRemaining() string {
+	if e.Status == StatusInvalid {
+		return ""
+	}
+
+	return fmt.Sprint(e.DaysRemaining)
+}
+
+func (list ExpiryList) Describe(w io.Writer) {
+	for _, e := range list {
+		kvs := []printer.KeyValue{
+			{Key: "Secret", Value: e.Namespace + "/" + e.Secret},
+			{Key: "Status", Value: e.Status},
+		}
+
+		if e.Status == StatusInvalid {
+			kvs = append(kvs, printer.KeyValue{Key: "Error", Value: e.Error})
+		} else {
+			kvs = append(kvs,
+				printer.KeyValue{Key: "Subject", Value: e.Subject},
+				printer.KeyValue{Key: "Issuer", Value: e.Issuer},
+				printer.KeyValue{Key: "IsCA", Value: fmt.Sprint(e.IsCA)},
+				printer.KeyValue{Key: "Not After", Value: e.formatNotAfter()},
+				printer.KeyValue{Key: "Days Remaining", Value: e.formatDaysRemaining()},
+			)
+		}
+
+		printer.Describe(w, kvs...)
+	}
+}
+
+func (list ExpiryList) Header(wide bool) []string {
+	header := []string{"SECRET", "SUBJECT", "ISSUER", "NOT-AFTER", "DAYS-REMAINING", "STATUS"}
+	if wide {
+		header = append(header, "NAMESPACE", "IS-CA", "NOT-BEFORE", "ERROR")
+	}
+
+	return header
+}
+
+func (list ExpiryList) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, e := range list {
+		row := []string{e.Secret, e.Subject, e.Issuer, e.formatNotAfter(), e.formatDaysRemaining(), e.Status}
+		if wide {
+			notBefore := ""
+			if !e.NotBefore.IsZero() {
+				notBefore = e.NotBefore.Format(time.RFC3339)
+			}
+			row = append(row, e.Namespace, fmt.Sprint(e.IsCA), notBefore, e.Error)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
@@ -15,6 +15,7 @@ import (
 func newGenerateCmd(clientGetter types.ClientGetter) *cobra.Command {
 	var commonOptions CommonOptions
 	var certOptions CertOptions
+	var keyOptions KeyOptions
 	var saveOptions SaveOptions
 
 	cmd := &cobra.Command{
@@ -32,6 +33,10 @@ Create a pair of certificate and private key with commonName edge and save data
 Remotely create a pair of certificate and private key with commonName edge:
 	
 	fabctl gen edge --api-server-address=http://host-cluster/
+
+Create a pair of certificate and ECDSA private key with commonName edge:
+
+	fabctl cert gen edge --key-algorithm=ecdsa-p256
 `,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			if len(args) == 0 {
@@ -47,6 +52,10 @@ Remotely create a pair of certificate and private key with commonName edge:
 					util.Exitf("invalid IP: %s", v)
 				}
 			}
+
+			if err := keyOptions.Validate(); err != nil {
+				util.Exitf("%s\n", err)
+			}
 		},
 
 		Run: func(cmd *cobra.Command, args []string) {
@@ -66,7 +75,7 @@ Remotely create a pair of certificate and private key with commonName edge:
 
 				usages := []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}
 				cfg := certOptions.AsConfig(commonName, false, usages)
-				certDER, keyDER, err = certutil.NewCertFromCA2(caDER, caKeyDER, cfg)
+				certDER, keyDER, err = newCertFromCA(caDER, caKeyDER, cfg, keyOptions)
 				if err != nil {
 					util.Exitf("failed to create certificate: %s\n", err)
 				}
@@ -77,7 +86,7 @@ Remotely create a pair of certificate and private key with commonName edge:
 				}
 
 				var csrDER []byte
-				keyDER, csrDER, err = certutil.NewCertRequest(certOptions.AsRequest(commonName))
+				keyDER, csrDER, err = newCertRequest(certOptions.AsRequest(commonName), keyOptions)
 				if err != nil {
 					util.Exitf("failed to create certificate request: %s\n", err)
 				}
@@ -103,6 +112,7 @@ Remotely create a pair of certificate and private key with commonName edge:
 	fs := cmd.Flags()
 	commonOptions.AddFlags(fs)
 	certOptions.AddFlags(fs)
+	keyOptions.AddFlags(fs)
 	saveOptions.AddFlags(fs)
 
 	cmd.AddCommand(newCACmd(clientGetter))
@@ -111,6 +121,7 @@ Remotely create a pair of certificate and private key with commonName edge:
 
 func newCACmd(clientGetter types.ClientGetter) *cobra.Command {
 	var certOptions CertOptions
+	var keyOptions KeyOptions
 	var secretName string
 
 	cmd := &cobra.Command{
@@ -135,6 +146,14 @@ Create a self-signed certificate and a private key, save data to secret ca-tls i
 					util.Exitf("Invalid IP: %s\n", v)
 				}
 			}
+
+			if err := keyOptions.Validate(); err != nil {
+				util.Exitf("%s\n", err)
+			}
+
+			if err := keyOptions.ValidateCA(secretName); err != nil {
+				util.Exitf("%s\n", err)
+			}
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			cli := newClient(clientGetter)
@@ -145,7 +164,7 @@ Create a self-signed certificate and a private key, save data to secret ca-tls i
 			}
 
 			cfg := certOptions.AsConfig(commonName, true, nil)
-			certDER, keyDER, err := certutil.NewSelfSignedCA(cfg)
+			certDER, keyDER, err := newSelfSignedCA(cfg, keyOptions)
 			if err != nil {
 				util.Exitf("failed to create certificate: %s\n", err)
 			}
@@ -156,6 +175,7 @@ Create a self-signed certificate and a private key, save data to secret ca-tls i
 
 	fs := cmd.Flags()
 	certOptions.AddFlags(fs)
+	keyOptions.AddFlags(fs)
 	fs.StringVar(&secretName, "secret-name", "fabedge-ca", "The name of the secret to store certificate and private key")
 
 	return cmd
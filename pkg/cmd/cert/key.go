@@ -0,0 +1,309 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	certutil "github.com/fabedge/fabedge/pkg/util/cert"
+	flag "github.com/spf13/pflag"
+)
+
+const (
+	KeyAlgorithmRSA       = "rsa"
+	KeyAlgorithmECDSAP256 = "ecdsa-p256"
+	KeyAlgorithmECDSAP384 = "ecdsa-p384"
+	KeyAlgorithmEd25519   = "ed25519"
+
+	minRSAKeySize = 2048
+
+	// OperatorCASecret is the secret from which fabedge-operator loads CA, the operator only supports RSA keys in PKCS1 form
+	OperatorCASecret = "fabedge-ca"
+)
+
+type KeyOptions struct {
+	Algorithm string
+	Size      int
+}
+
+func (opts *KeyOptions) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&opts.Algorithm, "key-algorithm", KeyAlgorithmRSA, "The algorithm of private key, one of: rsa, ecdsa-p256, ecdsa-p384, ed25519. The CA in secret fabedge-ca must use rsa, fabedge-operator doesn't support other keys")
+	fs.IntVar(&opts.Size, "key-size", 0, "The size of RSA private key in bits, 2048 is used if not provided")
+}
+
+func (opts KeyOptions) Validate() error {
+	switch opts.Algorithm {
+	case KeyAlgorithmRSA:
+		if opts.Size != 0 && opts.Size < minRSAKeySize {
+			return fmt.Errorf("RSA key size should not be less than %d", minRSAKeySize)
+		}
+	case KeyAlgorithmECDSAP256, KeyAlgorithmECDSAP384, KeyAlgorithmEd25519:
+		if opts.Size != 0 {
+			return fmt.Errorf("key size is only supported by RSA, the key size of %s is decided by its curve", opts.Algorithm)
+		}
+	default:
+		return fmt.Errorf("unknown key algorithm: %s", opts.Algorithm)
+	}
+
+	return nil
+}
+
+// ValidateCA returns an error if the key of a CA which is saved to secretName can't be used by fabedge-operator
+func (opts KeyOptions) ValidateCA(secretName string) error {
+	if secretName == OperatorCASecret && opts.Algorithm != KeyAlgorithmRSA {
+		return fmt.Errorf("fabedge-operator only supports RSA keys, %s key can't be saved to secret %s", opts.Algorithm, secretName)
+	}
+
+	return nil
+}
+
+// isDefault returns true if the key is a RSA key of default size, which is generated by certutil
+func (opts KeyOptions) isDefault() bool {
+	return (opts.Algorithm == KeyAlgorithmRSA || opts.Algorithm == "") && opts.Size == 0
+}
+
+// generateKey returns a private key and its DER data, RSA keys are marshaled in PKCS1 form,
+// others are marshaled in PKCS8 form
+func (opts KeyOptions) generateKey() (crypto.Signer, []byte, error) {
+	var (
+		key crypto.Signer
+		der []byte
+		err error
+	)
+
+	switch opts.Algorithm {
+	case KeyAlgorithmRSA, "":
+		size := opts.Size
+		if size == 0 {
+			size = minRSAKeySize
+		}
+
+		var rsaKey *rsa.PrivateKey
+		if rsaKey, err = rsa.GenerateKey(rand.Reader, size); err != nil {
+			return nil, nil, err
+		}
+		return rsaKey, x509.MarshalPKCS1PrivateKey(rsaKey), nil
+	case KeyAlgorithmECDSAP256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyAlgorithmECDSAP384:
+		key, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case KeyAlgorithmEd25519:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		err = fmt.Errorf("unknown key algorithm: %s", opts.Algorithm)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if der, err = x509.MarshalPKCS8PrivateKey(key); err != nil {
+		return nil, nil, err
+	}
+
+	return key, der, nil
+}
+
+// keyOptionsOf returns key options which generate keys of the same type and size of pub,
+// RSA keys of default size are still generated by certutil
+func keyOptionsOf(pub interface{}) (KeyOptions, error) {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		size := key.N.BitLen()
+		if size == minRSAKeySize {
+			size = 0
+		}
+		return KeyOptions{Algorithm: KeyAlgorithmRSA, Size: size}, nil
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return KeyOptions{Algorithm: KeyAlgorithmECDSAP256}, nil
+		case elliptic.P384():
+			return KeyOptions{Algorithm: KeyAlgorithmECDSAP384}, nil
+		default:
+			return KeyOptions{}, fmt.Errorf("unsupported curve: %s", key.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+		return KeyOptions{Algorithm: KeyAlgorithmEd25519}, nil
+	default:
+		return KeyOptions{}, fmt.Errorf("unsupported public key type: %T", pub)
+	}
+}
+
+// publicKeyInfo returns the size in bits and the curve name of a public key,
+// curve name is empty for RSA keys
+func publicKeyInfo(pub interface{}) (int, string) {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return key.N.BitLen(), ""
+	case *ecdsa.PublicKey:
+		return key.Curve.Params().BitSize, key.Curve.Params().Name
+	case ed25519.PublicKey:
+		return len(key) * 8, "Ed25519"
+	default:
+		return 0, ""
+	}
+}
+
+// encodePrivateKeyPEM encodes PKCS1 key as "RSA PRIVATE KEY" like certutil does, other keys as "PRIVATE KEY"
+func encodePrivateKeyPEM(keyDER []byte) []byte {
+	if isPKCS1PrivateKey(keyDER) {
+		return certutil.EncodePrivateKeyPEM(keyDER)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+}
+
+func isPKCS1PrivateKey(keyDER []byte) bool {
+	_, err := x509.ParsePKCS1PrivateKey(keyDER)
+	return err == nil
+}
+
+func parsePrivateKey(der []byte) (crypto.Signer, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %s", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type: %T", key)
+	}
+
+	return signer, nil
+}
+
+// newSelfSignedCA creates a self-signed CA certificate, certutil is used if the key is a RSA key of default size
+func newSelfSignedCA(cfg certutil.Config, keyOptions KeyOptions) (certDER []byte, keyDER []byte, err error) {
+	if keyOptions.isDefault() {
+		return certutil.NewSelfSignedCA(cfg)
+	}
+
+	key, keyDER, err := keyOptions.generateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template, err := newTemplate(cfg, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certDER, err = x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return certDER, keyDER, nil
+}
+
+// newCertFromCA creates a certificate signed by CA, certutil is used if both the key
+// and the CA key are RSA keys and the key is of default size
+func newCertFromCA(caDER, caKeyDER []byte, cfg certutil.Config, keyOptions KeyOptions) (certDER []byte, keyDER []byte, err error) {
+	if keyOptions.isDefault() && isPKCS1PrivateKey(caKeyDER) {
+		return certutil.NewCertFromCA2(caDER, caKeyDER, cfg)
+	}
+
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	caKey, err := parsePrivateKey(caKeyDER)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, keyDER, err := keyOptions.generateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template, err := newTemplate(cfg, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certDER, err = x509.CreateCertificate(rand.Reader, template, caCert, key.Public(), caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return certDER, keyDER, nil
+}
+
+// newCertRequest creates a certificate request, certutil is used if the key is a RSA key of default size
+func newCertRequest(req certutil.Request, keyOptions KeyOptions) (keyDER []byte, csrDER []byte, err error) {
+	if keyOptions.isDefault() {
+		return certutil.NewCertRequest(req)
+	}
+
+	key, keyDER, err := keyOptions.generateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	csrDER, err = x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:   req.CommonName,
+			Organization: req.Organization,
+		},
+		IPAddresses: req.IPs,
+		DNSNames:    req.DNSNames,
+	}, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return keyDER, csrDER, nil
+}
+
+func newTemplate(cfg certutil.Config, key crypto.Signer) (*x509.Certificate, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	// only RSA keys can be used to encipher keys
+	keyUsage := x509.KeyUsageDigitalSignature
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	if cfg.IsCA {
+		keyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+
+	now := time.Now().UTC()
+	return &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			CommonName:   cfg.CommonName,
+			Organization: cfg.Organization,
+		},
+		IPAddresses:           cfg.IPs,
+		DNSNames:              cfg.DNSNames,
+		NotBefore:             now,
+		NotAfter:              now.Add(cfg.ValidityPeriod),
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           cfg.Usages,
+		BasicConstraintsValid: true,
+		IsCA:                  cfg.IsCA,
+	}, nil
+}
@@ -20,6 +20,7 @@ func (opts *CommonOptions) AddFlags(fs *flag.FlagSet) {
 	fs.StringVar(&opts.CASecret, "ca-secret", "fabedge-ca", "The name of ca secret, by default CLI read CA cert/key from secret")
 
 	fs.StringVar(&opts.APIServerAddress, "api-server-address", "", "The address of host cluster's API server, when this option is set, generate or verify certificate remotely")
+	// --token shadows the global one of kubeconfig in cert commands, it is kept for existing scripts
 	fs.StringVar(&opts.Token, "token", "", "Authentication token, not necessary when verifying certificate")
 }
 
@@ -0,0 +1,587 @@
+package cert
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"time"
+
+	fclient "github.com/fabedge/fabedge/pkg/operator/client"
+	certutil "github.com/fabedge/fabedge/pkg/util/cert"
+	secretutil "github.com/fabedge/fabedge/pkg/util/secret"
+	timeutil "github.com/fabedge/fabedge/pkg/util/time"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fabedge/fabctl/pkg/printer"
+	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
+)
+
+const (
+	SignerCA     = "ca"
+	SignerRemote = "remote"
+	SignerSelf   = "self"
+
+	// AnnotationBackupOf is put on backup secrets, the value is the name of the renewed secret
+	AnnotationBackupOf = "fabedge.io/backup-of"
+	// LabelBackupOf is put on backup secrets too, so they can be listed by selector
+	LabelBackupOf = "fabedge.io/backup-of"
+)
+
+// Renewal is the result of renewing the certificate in a secret
+type Renewal struct {
+	Secret    string    `json:"secret"`
+	Subject   string    `json:"subject,omitempty"`
+	Signer    string    `json:"signer,omitempty"`
+	NotAfter  time.Time `json:"notAfter"`
+	Backup    string    `json:"backup,omitempty"`
+	Restarted []string  `json:"restarted,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type RenewalList []Renewal
+
+func newRenewCmd(clientGetter types.ClientGetter) *cobra.Command {
+	var (
+		commonOptions  CommonOptions
+		selector       string
+		validityPeriod int64
+		restart        bool
+		restartTimeout time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "renew [secretNames] [flags]",
+		Short: "Renew certificates in TLS secrets and CA secret",
+		Long: `Renew certificates in TLS secrets and CA secret. The new certificate has the same subject, IPs, DNS names and usages of the current one,
+it is signed by the CA secret or by host cluster if API server address is provided. A self-signed CA certificate is renewed with its current key,
+so certificates signed by it are still valid. Before a secret is updated, its data is saved to a backup secret.`,
+		Example: `Renew certificates of specified TLS secrets:
+
+	fabctl cert renew fabedge-agent-tls-edge1 connector-tls
+
+Renew certificates of TLS secrets by selectors and restart agent and connector pods which use them:
+
+	fabctl cert renew -l fabedge.io/created-by=fabedge-operator --restart
+
+Renew CA certificate with a validity period of 10 years:
+
+	fabctl cert renew fabedge-ca --validity-period=3650
+
+Renew certificates using host cluster's API server:
+
+	fabctl cert renew fabedge-agent-tls-edge1 --api-server-address=https://host-cluster:30303 --token=xxx`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 && selector == "" {
+				util.Exitf("secretNames or selector is required\n")
+			}
+
+			if validityPeriod < 0 {
+				util.Exitf("validity period should not be negative\n")
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			p, err := clientGetter.GetPrinter()
+			util.CheckError(err)
+
+			cli := newClient(clientGetter)
+			secrets, err := cli.findSecrets(args, selector)
+			util.CheckError(err)
+
+			r := &renewer{
+				cli:            cli,
+				options:        commonOptions,
+				validityPeriod: timeutil.Days(validityPeriod),
+				now:            time.Now(),
+			}
+
+			renewals := make(RenewalList, 0, len(secrets))
+			for _, secret := range secrets {
+				renewals = append(renewals, r.renew(secret))
+			}
+
+			if restart {
+				restartPods(cli, renewals, restartTimeout)
+			}
+
+			util.CheckError(p.Print(renewals))
+
+			failed := 0
+			for _, renewal := range renewals {
+				if renewal.Error != "" {
+					failed++
+				}
+			}
+
+			if failed > 0 {
+				util.Exitf("failed to renew %d of %d secrets\n", failed, len(renewals))
+			}
+		},
+	}
+
+	fs := cmd.Flags()
+	commonOptions.AddFlags(fs)
+	fs.StringVarP(&selector, "selector", "l", "", "Selector (label query) to filter TLS secrets. Selectors will be ignored if you provide a secretName.")
+	fs.Int64Var(&validityPeriod, "validity-period", 0, "Validity period of new certificates, unit: day. By default the validity period of the current certificate is used")
+	fs.BoolVar(&restart, "restart", false, "Restart agent and connector pods which use renewed secrets one by one")
+	fs.DurationVar(&restartTimeout, "restart-timeout", 5*time.Minute, "Time to wait for a restarted pod to be ready")
+
+	return cmd
+}
+
+type renewer struct {
+	cli            secretClient
+	options        CommonOptions
+	validityPeriod time.Duration
+	now            time.Time
+
+	// CA certificate and key used to sign certificates, loaded at the first use
+	caDER    []byte
+	caKeyDER []byte
+	caPool   *x509.CertPool
+
+	// trustedCA is saved as ca.crt of renewed secrets if provided, otherwise the CA certificate is saved
+	trustedCA []byte
+}
+
+func (r *renewer) renew(secret corev1.Secret) Renewal {
+	renewal := Renewal{Secret: secret.Name}
+
+	cert, err := parseCertificateFromSecret(secret)
+	if err != nil {
+		renewal.Error = err.Error()
+		return renewal
+	}
+	renewal.Subject = cert.Subject.String()
+
+	var data map[string][]byte
+	if cert.IsCA {
+		renewal.Signer = SignerSelf
+		data, err = r.renewCA(secret, cert)
+	} else {
+		renewal.Signer = SignerCA
+		if r.options.Remote() {
+			renewal.Signer = SignerRemote
+		}
+		data, err = r.renewCert(cert)
+	}
+
+	if err != nil {
+		renewal.Error = err.Error()
+		return renewal
+	}
+
+	newCert, err := parseCertificateFromSecret(corev1.Secret{ObjectMeta: secret.ObjectMeta, Data: data})
+	if err != nil {
+		renewal.Error = err.Error()
+		return renewal
+	}
+	renewal.NotAfter = newCert.NotAfter
+
+	renewal.Backup, err = r.backup(secret)
+	if err != nil {
+		renewal.Error = fmt.Sprintf("failed to backup secret: %s", err)
+		return renewal
+	}
+
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	for key, value := range data {
+		secret.Data[key] = value
+	}
+
+	if err = r.cli.Update(context.TODO(), &secret); err != nil {
+		renewal.Error = fmt.Sprintf("failed to update secret: %s", err)
+	}
+
+	return renewal
+}
+
+// renewCert creates a certificate with the same subject, IPs, DNS names and usages of cert and
+// a new private key of the same type
+func (r *renewer) renewCert(cert *x509.Certificate) (map[string][]byte, error) {
+	if err := r.loadCA(); err != nil {
+		return nil, err
+	}
+
+	keyOptions, err := keyOptionsOf(cert.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var certDER, keyDER []byte
+	if r.options.Remote() {
+		var csrDER []byte
+		keyDER, csrDER, err = newCertRequest(certutil.Request{
+			CommonName:   cert.Subject.CommonName,
+			Organization: cert.Subject.Organization,
+			IPs:          cert.IPAddresses,
+			DNSNames:     cert.DNSNames,
+		}, keyOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create certificate request: %s", err)
+		}
+
+		signed, err := fclient.SignCertByToken(r.options.APIServerAddress, r.options.Token, csrDER, r.caPool)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create certificate: %s", err)
+		}
+		certDER = signed.DER
+	} else {
+		certDER, keyDER, err = newCertFromCA(r.caDER, r.caKeyDER, certutil.Config{
+			CommonName:     cert.Subject.CommonName,
+			Organization:   cert.Subject.Organization,
+			IPs:            cert.IPAddresses,
+			DNSNames:       cert.DNSNames,
+			ValidityPeriod: r.validityOf(cert),
+			Usages:         cert.ExtKeyUsage,
+		}, keyOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create certificate: %s", err)
+		}
+	}
+
+	caPEM := r.trustedCA
+	if caPEM == nil {
+		caPEM = certutil.EncodeCertPEM(r.caDER)
+	}
+
+	return map[string][]byte{
+		secretutil.KeyCACert:    caPEM,
+		corev1.TLSCertKey:       certutil.EncodeCertPEM(certDER),
+		corev1.TLSPrivateKeyKey: encodePrivateKeyPEM(keyDER),
+	}, nil
+}
+
+// renewCA creates a self-signed CA certificate with the current key of CA, so
+// certificates signed by the CA don't need to be renewed
+func (r *renewer) renewCA(secret corev1.Secret, cert *x509.Certificate) (map[string][]byte, error) {
+	if r.options.Remote() {
+		return nil, fmt.Errorf("CA certificate can't be renewed remotely")
+	}
+
+	if cert.Subject.String() != cert.Issuer.String() {
+		return nil, fmt.Errorf("CA certificate is not self-signed")
+	}
+
+	_, keyDER, err := parseCertAndKeyFromSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	if len(keyDER) == 0 {
+		return nil, fmt.Errorf("no private key found in secret %s", secret.Name)
+	}
+
+	signer, err := parsePrivateKey(keyDER)
+	if err != nil {
+		return nil, err
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               cert.Subject,
+		NotBefore:             r.now.UTC(),
+		NotAfter:              r.now.Add(r.validityOf(cert)).UTC(),
+		KeyUsage:              cert.KeyUsage,
+		ExtKeyUsage:           cert.ExtKeyUsage,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLen:            cert.MaxPathLen,
+		MaxPathLenZero:        cert.MaxPathLenZero,
+		SubjectKeyId:          cert.SubjectKeyId,
+		DNSNames:              cert.DNSNames,
+		IPAddresses:           cert.IPAddresses,
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, signer.Public(), signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate: %s", err)
+	}
+
+	certKey := corev1.TLSCertKey
+	if secret.Data[secretutil.KeyCAKey] != nil {
+		certKey = secretutil.KeyCACert
+	}
+
+	return map[string][]byte{
+		certKey: certutil.EncodeCertPEM(certDER),
+	}, nil
+}
+
+func (r *renewer) loadCA() error {
+	if r.caDER != nil {
+		return nil
+	}
+
+	if r.options.Remote() {
+		cacert, err := fclient.GetCertificate(r.options.APIServerAddress)
+		if err != nil {
+			return fmt.Errorf("failed to get CA certificate from host cluster: %s", err)
+		}
+
+		r.caPool = x509.NewCertPool()
+		r.caPool.AddCert(cacert.Raw)
+		r.caDER = cacert.DER
+		return nil
+	}
+
+	caDER, caKeyDER, err := r.cli.loadCertAndKey(r.options.CASecret)
+	if err != nil {
+		return err
+	}
+	if len(caKeyDER) == 0 {
+		return fmt.Errorf("no private key found in secret %s", r.options.CASecret)
+	}
+
+	r.caDER, r.caKeyDER = caDER, caKeyDER
+	return nil
+}
+
+// validityOf returns the validity period of renewed certificate of cert
+func (r *renewer) validityOf(cert *x509.Certificate) time.Duration {
+	if r.validityPeriod > 0 {
+		return r.validityPeriod
+	}
+
+	return cert.NotAfter.Sub(cert.NotBefore)
+}
+
+// backup saves data of secret to a new secret, the name of backup secret is returned. The name has
+// a random suffix, so a secret can be backed up more than once in a second. Backup secrets don't have
+// labels of the renewed secret, they can be listed by the label fabedge.io/backup-of
+func (r *renewer) backup(secret corev1.Secret) (string, error) {
+	backup := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-backup-%s-", secret.Name, r.now.Format("20060102150405")),
+			Namespace:    secret.Namespace,
+			Labels:       map[string]string{},
+			Annotations: map[string]string{
+				AnnotationBackupOf: secret.Name,
+			},
+		},
+		Type: secret.Type,
+		Data: make(map[string][]byte, len(secret.Data)),
+	}
+	for key, value := range secret.Data {
+		backup.Data[key] = value
+	}
+
+	// a label value is limited to 63 characters, the annotation always has the name
+	if len(validation.IsValidLabelValue(secret.Name)) == 0 {
+		backup.Labels[LabelBackupOf] = secret.Name
+	}
+
+	if err := r.cli.Create(context.TODO(), &backup); err != nil {
+		return "", err
+	}
+
+	return backup.Name, nil
+}
+
+// restartPods deletes agent and connector pods which mount renewed secrets one by one,
+// and waits for each of them to be replaced by a ready pod before deleting the next one.
+// It stops at the first pod which fails to restart.
+func restartPods(cli secretClient, renewals RenewalList, timeout time.Duration) {
+	ctx := context.TODO()
+
+	agentPods, err := cli.ListAgentPods(ctx)
+	util.CheckError(err)
+
+	connectorPods, err := cli.ListConnectorPods(ctx)
+	util.CheckError(err)
+
+	for _, pod := range append(agentPods, connectorPods...) {
+		var indexes []int
+		for i, renewal := range renewals {
+			if renewal.Error == "" && mountsSecret(pod, renewal.Secret) {
+				indexes = append(indexes, i)
+			}
+		}
+
+		if len(indexes) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(os.Stderr, "restarting pod %s/%s\n", pod.Namespace, pod.Name)
+		err = restartPod(ctx, cli.Client, pod, timeout)
+		for _, i := range indexes {
+			if err != nil {
+				renewals[i].Error = fmt.Sprintf("secret is renewed, but failed to restart pod %s: %s", pod.Name, err)
+			} else {
+				renewals[i].Restarted = append(renewals[i].Restarted, pod.Name)
+			}
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func mountsSecret(pod corev1.Pod, secretName string) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Secret != nil && volume.Secret.SecretName == secretName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// restartPod deletes pod and waits for a new pod with the same name or the same controller to be ready.
+// A pod without controller is created again by restartPod, e.g. agent pods when fabedge-operator is scaled down.
+func restartPod(ctx context.Context, cli *types.Client, pod corev1.Pod, timeout time.Duration) error {
+	var podList corev1.PodList
+	if err := cli.List(ctx, &podList, client.InNamespace(pod.Namespace)); err != nil {
+		return err
+	}
+
+	existing := sets.NewString()
+	for _, p := range podList.Items {
+		existing.Insert(string(p.UID))
+	}
+
+	if err := cli.Delete(ctx, &pod); err != nil {
+		return err
+	}
+
+	controller := metav1.GetControllerOf(&pod)
+	if controller == nil {
+		if err := recreatePod(ctx, cli, pod, timeout); err != nil {
+			return err
+		}
+	}
+
+	return wait.PollImmediate(2*time.Second, timeout, func() (bool, error) {
+		var podList corev1.PodList
+		if err := cli.List(ctx, &podList, client.InNamespace(pod.Namespace)); err != nil {
+			return false, err
+		}
+
+		for _, p := range podList.Items {
+			if existing.Has(string(p.UID)) {
+				continue
+			}
+
+			owner := metav1.GetControllerOf(&p)
+			replaced := p.Name == pod.Name || (controller != nil && owner != nil && owner.UID == controller.UID)
+			if replaced && isPodReady(p) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+}
+
+// recreatePod waits for deleted pod to be gone and creates it again with the same spec,
+// it's done if the pod is created by someone else in the meantime
+func recreatePod(ctx context.Context, cli *types.Client, pod corev1.Pod, timeout time.Duration) error {
+	err := wait.PollImmediate(2*time.Second, timeout, func() (bool, error) {
+		var p corev1.Pod
+		err := cli.Get(ctx, client.ObjectKey{Name: pod.Name, Namespace: pod.Namespace}, &p)
+		switch {
+		case errors.IsNotFound(err):
+			return true, nil
+		case err != nil:
+			return false, err
+		default:
+			return p.UID != pod.UID, nil
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	newPod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            pod.Name,
+			Namespace:       pod.Namespace,
+			Labels:          pod.Labels,
+			Annotations:     pod.Annotations,
+			OwnerReferences: pod.OwnerReferences,
+		},
+		Spec: pod.Spec,
+	}
+
+	if err = cli.Create(ctx, &newPod); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	return nil
+}
+
+func isPodReady(pod corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+func (r Renewal) formatNotAfter() string {
+	if r.NotAfter.IsZero() {
+		return ""
+	}
+
+	return r.NotAfter.Format(time.RFC3339)
+}
+
+func (list RenewalList) Describe(w io.Writer) {
+	for _, r := range list {
+		kvs := []printer.KeyValue{
+			{Key: "Secret", Value: r.Secret},
+			{Key: "Subject", Value: r.Subject},
+			{Key: "Signer", Value: r.Signer},
+			{Key: "Not After", Value: r.formatNotAfter()},
+			{Key: "Backup", Value: r.Backup},
+			{Key: "Restarted", Value: printer.Join(r.Restarted)},
+		}
+		if r.Error != "" {
+			kvs = append(kvs, printer.KeyValue{Key: "Error", Value: r.Error})
+		}
+
+		printer.Describe(w, kvs...)
+	}
+}
+
+func (list RenewalList) Header(wide bool) []string {
+	header := []string{"SECRET", "NOT-AFTER", "BACKUP", "RESTARTED", "ERROR"}
+	if wide {
+		header = append(header, "SUBJECT", "SIGNER")
+	}
+
+	return header
+}
+
+func (list RenewalList) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, r := range list {
+		row := []string{r.Secret, r.formatNotAfter(), r.Backup, printer.Join(r.Restarted), r.Error}
+		if wide {
+			row = append(row, r.Subject, r.Signer)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
@@ -0,0 +1,839 @@
+package cert
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fabedge/fabedge/pkg/common/constants"
+	certutil "github.com/fabedge/fabedge/pkg/util/cert"
+	secretutil "github.com/fabedge/fabedge/pkg/util/secret"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/fabedge/fabctl/pkg/printer"
+	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
+)
+
+const (
+	// AnnotationCARollover is put on CA secret, the value is the state of CA rollover in JSON
+	AnnotationCARollover = "fabedge.io/ca-rollover"
+
+	// KeyNextCACert is the key of the new CA certificate in TLS secrets during CA rollover. strongSwan loads
+	// only one certificate from a file, so the new CA certificate can't be appended to ca.crt
+	KeyNextCACert = "ca-next.crt"
+
+	// phases of CA rollover, a phase is recorded after it's finished
+	PhaseGenerated = "generated"
+	PhaseTrusted   = "trusted"
+	PhaseReissued  = "reissued"
+	PhaseCompleted = "completed"
+
+	SignedByOldCA = "old-ca"
+	SignedByNewCA = "new-ca"
+	SignedByOther = "other"
+)
+
+// RolloverState is the progress of CA rollover which is saved in the annotation of CA secret
+type RolloverState struct {
+	Phase       string    `json:"phase"`
+	NewCASecret string    `json:"newCASecret"`
+	Reissued    []string  `json:"reissued,omitempty"`
+	StartedAt   time.Time `json:"startedAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// SecretTrust shows which CA certificates a TLS secret trusts and which CA signs its certificate
+type SecretTrust struct {
+	Secret      string `json:"secret"`
+	TrustsOldCA bool   `json:"trustsOldCA"`
+	TrustsNewCA bool   `json:"trustsNewCA"`
+	SignedBy    string `json:"signedBy"`
+	Error       string `json:"error,omitempty"`
+}
+
+// RolloverStatus is the state of CA rollover and the trust of each TLS secret
+type RolloverStatus struct {
+	RolloverState `json:",inline"`
+	OldCA         string        `json:"oldCA"`
+	NewCA         string        `json:"newCA,omitempty"`
+	Secrets       []SecretTrust `json:"secrets"`
+}
+
+type rolloverOptions struct {
+	CASecret       string
+	Selector       string
+	BatchSize      int
+	Restart        bool
+	RestartTimeout time.Duration
+	New            bool
+}
+
+func (opts *rolloverOptions) addRestartFlags(cmd *cobra.Command) {
+	fs := cmd.Flags()
+	fs.BoolVar(&opts.Restart, "restart", false, "Restart agent and connector pods which use changed secrets one by one")
+	fs.DurationVar(&opts.RestartTimeout, "restart-timeout", 5*time.Minute, "Time to wait for a restarted pod to be ready")
+}
+
+// nextPhaseCommands maps the recorded phase of CA rollover to the command of the next phase
+var nextPhaseCommands = map[string]string{
+	"":             "generate",
+	PhaseCompleted: "generate",
+	PhaseGenerated: "trust",
+	PhaseTrusted:   "reissue",
+	PhaseReissued:  "finalize",
+}
+
+func newCARolloverCmd(clientGetter types.ClientGetter) *cobra.Command {
+	opts := &rolloverOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "ca-rollover [command] [flags]",
+		Short: "Replace CA with a new one without breaking tunnels",
+		Long: `Replace CA with a new one without breaking tunnels. CA rollover is done in phases, each phase is a command and has to be run in order:
+  generate: create a new CA and save it to secret <ca-secret>-next
+  trust:    save the new CA certificate to ca-next.crt of every TLS secret, ca.crt still holds the old one
+  reissue:  re-issue certificates of TLS secrets by the new CA one by one, use --batch-size to limit how many secrets are re-issued in one execution
+  finalize: replace CA secret with the new CA, save the new CA certificate to ca.crt of every TLS secret and remove ca-next.crt
+The progress is saved in the annotation fabedge.io/ca-rollover of CA secret, so an interrupted phase can be resumed by running it again.
+Pods don't reload certificates, use --restart to restart agent and connector pods after their secrets are changed.
+strongSwan loads one CA certificate per file, so pods have to mount ca-next.crt into the CA directory of strongSwan, e.g. an item
+{key: ca-next.crt, path: cacerts/ca-next.crt} in their secret volumes, trust refuses to run if a pod doesn't mount it.
+fabedge-operator loads CA at startup and regenerates certificates which are not signed by it, so it has to be scaled down
+to 0 replicas from trust through finalize, these phases refuse to run if it's running. Scale it up after finalize.
+Secrets created during rollover may be signed by the old CA, finalize refuses to run until they are re-issued.`,
+		Example: `Run the phases of CA rollover:
+
+	fabctl cert ca-rollover generate
+	fabctl cert ca-rollover trust --restart
+	fabctl cert ca-rollover reissue --restart --batch-size=5
+	fabctl cert ca-rollover reissue --restart
+	fabctl cert ca-rollover finalize --restart
+
+Show the progress of CA rollover:
+
+	fabctl cert ca-rollover status`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return nil
+			}
+
+			msg := fmt.Sprintf("unknown command %q for %q", args[0], cmd.CommandPath())
+			if suggestions := cmd.SuggestionsFor(args[0]); len(suggestions) > 0 {
+				msg += "\n\nDid you mean this?\n\t" + strings.Join(suggestions, "\n\t")
+			}
+
+			return fmt.Errorf("%s", msg)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			util.CheckError(cmd.Help())
+		},
+	}
+
+	fs := cmd.PersistentFlags()
+	fs.StringVar(&opts.CASecret, "ca-secret", "fabedge-ca", "The name of CA secret")
+	fs.StringVarP(&opts.Selector, "selector", "l", "fabedge.io/created-by=fabedge-operator", "Selector (label query) to filter TLS secrets")
+
+	cmd.AddCommand(newCARolloverGenerateCmd(clientGetter, opts))
+	cmd.AddCommand(newCARolloverTrustCmd(clientGetter, opts))
+	cmd.AddCommand(newCARolloverReissueCmd(clientGetter, opts))
+	cmd.AddCommand(newCARolloverFinalizeCmd(clientGetter, opts))
+	cmd.AddCommand(newCARolloverStatusCmd(clientGetter, opts))
+
+	return cmd
+}
+
+func newCARolloverGenerateCmd(clientGetter types.ClientGetter, opts *rolloverOptions) *cobra.Command {
+	var (
+		certOptions CertOptions
+		keyOptions  KeyOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "generate [CommonName] [flags]",
+		Short: "Create a new CA and save it to secret <ca-secret>-next",
+		Long: `Create a new CA and save it to secret <ca-secret>-next, the common name of the current CA is used if CommonName is not provided.
+If the secret exists, which means the phase was interrupted, the CA in it is used.`,
+		Args: cobra.MaximumNArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			for _, v := range certOptions.IPs {
+				if net.ParseIP(v) == nil {
+					util.Exitf("Invalid IP: %s\n", v)
+				}
+			}
+
+			if err := keyOptions.Validate(); err != nil {
+				util.Exitf("%s\n", err)
+			}
+
+			// the new CA replaces CA secret in finalize
+			if err := keyOptions.ValidateCA(opts.CASecret); err != nil {
+				util.Exitf("%s\n", err)
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			runPhase(clientGetter, opts, "generate", func(ro *rollover) (RenewalList, error) {
+				return nil, ro.generate(args, certOptions, keyOptions)
+			})
+		},
+	}
+
+	certOptions.AddFlags(cmd.Flags())
+	keyOptions.AddFlags(cmd.Flags())
+	cmd.Flags().BoolVar(&opts.New, "new", false, "Start a new CA rollover if the last one is completed")
+
+	return cmd
+}
+
+func newCARolloverTrustCmd(clientGetter types.ClientGetter, opts *rolloverOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trust [flags]",
+		Short: "Save the new CA certificate to ca-next.crt of every TLS secret",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runPhase(clientGetter, opts, "trust", (*rollover).trust)
+		},
+	}
+
+	opts.addRestartFlags(cmd)
+	return cmd
+}
+
+func newCARolloverReissueCmd(clientGetter types.ClientGetter, opts *rolloverOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reissue [flags]",
+		Short: "Re-issue certificates of TLS secrets by the new CA one by one",
+		Args:  cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if opts.BatchSize < 0 {
+				util.Exitf("batch size should not be negative\n")
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			runPhase(clientGetter, opts, "reissue", (*rollover).reissue)
+		},
+	}
+
+	opts.addRestartFlags(cmd)
+	cmd.Flags().IntVar(&opts.BatchSize, "batch-size", 0, "The max number of secrets to re-issue in one execution, 0 means no limit")
+
+	return cmd
+}
+
+func newCARolloverFinalizeCmd(clientGetter types.ClientGetter, opts *rolloverOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "finalize [flags]",
+		Short: "Replace CA secret with the new CA and stop trusting the old one",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runPhase(clientGetter, opts, "finalize", (*rollover).finalize)
+		},
+	}
+
+	opts.addRestartFlags(cmd)
+	return cmd
+}
+
+func newCARolloverStatusCmd(clientGetter types.ClientGetter, opts *rolloverOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status [flags]",
+		Short: "Show the progress of CA rollover",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			p, err := clientGetter.GetPrinter()
+			util.CheckError(err)
+
+			ro := &rollover{
+				cli:  newClient(clientGetter),
+				opts: *opts,
+			}
+			util.CheckError(ro.load())
+
+			status, err := ro.status()
+			util.CheckError(err)
+
+			util.CheckError(p.Print(status))
+		},
+	}
+
+	return cmd
+}
+
+// runPhase loads CA rollover, runs fn if command is the next phase, then prints the results and the current phase
+func runPhase(clientGetter types.ClientGetter, opts *rolloverOptions, command string, fn func(ro *rollover) (RenewalList, error)) {
+	p, err := clientGetter.GetPrinter()
+	util.CheckError(err)
+
+	ro := &rollover{
+		cli:  newClient(clientGetter),
+		opts: *opts,
+	}
+	util.CheckError(ro.load())
+
+	if next := nextPhaseCommands[ro.state.Phase]; next != command {
+		if next == "" {
+			util.Exitf("unknown phase of CA rollover: %s\n", ro.state.Phase)
+		}
+		util.Exitf("CA rollover is in phase %q, the next phase is 'fabctl cert ca-rollover %s'\n", ro.state.Phase, next)
+	}
+
+	if ro.state.Phase == PhaseCompleted && !opts.New {
+		util.Exitf("the last CA rollover is completed at %s, use --new to start a new one\n", ro.state.UpdatedAt.Format(time.RFC3339))
+	}
+
+	results, err := fn(ro)
+	if len(results) > 0 {
+		util.CheckError(p.Print(results))
+	}
+	util.CheckError(err)
+
+	if !p.IsStructured() {
+		fmt.Printf("\nCA rollover phase: %s\n", ro.state.Phase)
+	}
+}
+
+type rollover struct {
+	cli   secretClient
+	opts  rolloverOptions
+	state RolloverState
+
+	oldCADER    []byte
+	newCADER    []byte
+	newCAKeyDER []byte
+}
+
+// load reads the state of CA rollover and CA certificates
+func (ro *rollover) load() error {
+	secret, err := ro.cli.getSecret(ro.opts.CASecret)
+	if err != nil {
+		return err
+	}
+
+	if value := secret.Annotations[AnnotationCARollover]; value != "" {
+		if err = json.Unmarshal([]byte(value), &ro.state); err != nil {
+			return fmt.Errorf("failed to parse annotation %s of secret %s: %s", AnnotationCARollover, secret.Name, err)
+		}
+	}
+
+	if ro.oldCADER, _, err = parseCertAndKeyFromSecret(secret); err != nil {
+		return err
+	}
+
+	if ro.state.Phase == "" || ro.state.Phase == PhaseCompleted {
+		return nil
+	}
+
+	ro.newCADER, ro.newCAKeyDER, err = ro.cli.loadCertAndKey(ro.state.NewCASecret)
+	return err
+}
+
+func (ro *rollover) saveState(phase string) error {
+	secret, err := ro.cli.getSecret(ro.opts.CASecret)
+	if err != nil {
+		return err
+	}
+
+	ro.state.Phase = phase
+	ro.state.UpdatedAt = time.Now()
+	value, err := json.Marshal(ro.state)
+	if err != nil {
+		return err
+	}
+
+	if secret.Annotations == nil {
+		secret.Annotations = make(map[string]string)
+	}
+	secret.Annotations[AnnotationCARollover] = string(value)
+
+	return ro.cli.Update(context.TODO(), &secret)
+}
+
+// generate creates a new CA and saves it to secret <ca-secret>-next, if the secret
+// exists, which means the phase was interrupted, the CA in it is used
+func (ro *rollover) generate(args []string, certOptions CertOptions, keyOptions KeyOptions) error {
+	oldCA, err := x509.ParseCertificate(ro.oldCADER)
+	if err != nil {
+		return err
+	}
+
+	commonName := oldCA.Subject.CommonName
+	if len(args) > 0 {
+		commonName = args[0]
+	}
+
+	var (
+		name     = ro.opts.CASecret + "-next"
+		existing corev1.Secret
+		caDER    []byte
+		caKeyDER []byte
+	)
+
+	err = ro.cli.Get(context.TODO(), types.ObjectKey{Name: name, Namespace: ro.cli.GetNamespace()}, &existing)
+	switch {
+	case err == nil:
+		if caDER, caKeyDER, err = parseCertAndKeyFromSecret(existing); err != nil {
+			return err
+		}
+		if len(caKeyDER) == 0 {
+			return fmt.Errorf("no private key found in secret %s", name)
+		}
+		fmt.Printf("secret %s/%s exists, the CA in it is used\n", existing.Namespace, name)
+	case errors.IsNotFound(err):
+		caDER, caKeyDER, err = newSelfSignedCA(certOptions.AsConfig(commonName, true, nil), keyOptions)
+		if err != nil {
+			return fmt.Errorf("failed to create certificate: %s", err)
+		}
+
+		err = ro.cli.Create(context.TODO(), &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: ro.cli.GetNamespace(),
+				Labels: map[string]string{
+					constants.KeyCreatedBy: "fabctl",
+				},
+			},
+			Data: map[string][]byte{
+				secretutil.KeyCACert: certutil.EncodeCertPEM(caDER),
+				secretutil.KeyCAKey:  encodePrivateKeyPEM(caKeyDER),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save secret: %s", err)
+		}
+		fmt.Printf("secret %s/%s is saved\n", ro.cli.GetNamespace(), name)
+	default:
+		return err
+	}
+
+	ro.newCADER, ro.newCAKeyDER = caDER, caKeyDER
+	ro.state = RolloverState{
+		NewCASecret: name,
+		StartedAt:   time.Now(),
+	}
+
+	return ro.saveState(PhaseGenerated)
+}
+
+// trust saves the new CA certificate to ca-next.crt of every TLS secret, ca.crt is not changed
+func (ro *rollover) trust() (RenewalList, error) {
+	if err := ro.checkOperator(); err != nil {
+		return nil, err
+	}
+
+	secrets, err := ro.tlsSecrets()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = ro.checkMounts(secrets); err != nil {
+		return nil, err
+	}
+
+	caPEM := certutil.EncodeCertPEM(ro.newCADER)
+	r := &renewer{cli: ro.cli, now: time.Now()}
+
+	var results RenewalList
+	for _, secret := range secrets {
+		if bytes.Equal(secret.Data[KeyNextCACert], caPEM) {
+			continue
+		}
+
+		result := Renewal{Secret: secret.Name}
+		result.Backup, err = r.backup(secret)
+		if err == nil {
+			secret.Data[KeyNextCACert] = caPEM
+			err = ro.cli.Update(context.TODO(), &secret)
+		}
+
+		if err != nil {
+			result.Error = err.Error()
+			return append(results, result), err
+		}
+		results = append(results, result)
+	}
+
+	if err = ro.restart(results); err != nil {
+		return results, err
+	}
+
+	return results, ro.saveState(PhaseTrusted)
+}
+
+// reissue re-issues certificates of TLS secrets by the new CA one by one in the order
+// of secret names, each re-issued secret is recorded in the state. ca.crt keeps the old
+// CA certificate, so peers whose certificates are not re-issued yet are still trusted
+func (ro *rollover) reissue() (RenewalList, error) {
+	if err := ro.checkOperator(); err != nil {
+		return nil, err
+	}
+
+	secrets, err := ro.tlsSecrets()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &renewer{
+		cli:       ro.cli,
+		now:       time.Now(),
+		caDER:     ro.newCADER,
+		caKeyDER:  ro.newCAKeyDER,
+		trustedCA: certutil.EncodeCertPEM(ro.oldCADER),
+	}
+
+	reissued := sets.NewString(ro.state.Reissued...)
+	var results RenewalList
+	for _, secret := range secrets {
+		if reissued.Has(secret.Name) {
+			continue
+		}
+
+		if ro.opts.BatchSize > 0 && len(results) == ro.opts.BatchSize {
+			return results, nil
+		}
+
+		result := r.renew(secret)
+		results = append(results, result)
+		if result.Error != "" {
+			return results, fmt.Errorf("failed to re-issue certificate of secret %s", secret.Name)
+		}
+
+		if err = ro.restart(results[len(results)-1:]); err != nil {
+			return results, err
+		}
+
+		ro.state.Reissued = append(ro.state.Reissued, secret.Name)
+		if err = ro.saveState(PhaseTrusted); err != nil {
+			return results, err
+		}
+	}
+
+	return results, ro.saveState(PhaseReissued)
+}
+
+// finalize replaces CA secret with the new CA, saves the new CA certificate to ca.crt
+// of every TLS secret and removes ca-next.crt
+func (ro *rollover) finalize() (RenewalList, error) {
+	if err := ro.checkOperator(); err != nil {
+		return nil, err
+	}
+
+	secrets, err := ro.tlsSecrets()
+	if err != nil {
+		return nil, err
+	}
+
+	newCA, err := x509.ParseCertificate(ro.newCADER)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, secret := range secrets {
+		if signedBy(secret, newCA, nil) != SignedByNewCA {
+			return nil, fmt.Errorf("certificate of secret %s is not signed by the new CA, re-issue it by 'fabctl cert renew' first", secret.Name)
+		}
+	}
+
+	caSecret, err := ro.cli.getSecret(ro.opts.CASecret)
+	if err != nil {
+		return nil, err
+	}
+
+	// if finalize was interrupted after CA secret is updated, CA secret holds the new CA already,
+	// backing it up again would save the new CA as the old one
+	if currentDER, _, err := parseCertAndKeyFromSecret(caSecret); err != nil || !bytes.Equal(currentDER, ro.newCADER) {
+		r := &renewer{cli: ro.cli, now: time.Now()}
+		backup, err := r.backup(caSecret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to backup secret: %s", err)
+		}
+		fmt.Printf("old CA is saved to secret %s\n", backup)
+
+		certKey, keyKey := secretutil.KeyCACert, secretutil.KeyCAKey
+		if caSecret.Data[keyKey] == nil {
+			certKey, keyKey = corev1.TLSCertKey, corev1.TLSPrivateKeyKey
+		}
+		caSecret.Data[certKey] = certutil.EncodeCertPEM(ro.newCADER)
+		caSecret.Data[keyKey] = encodePrivateKeyPEM(ro.newCAKeyDER)
+		if err = ro.cli.Update(context.TODO(), &caSecret); err != nil {
+			return nil, fmt.Errorf("failed to update secret: %s", err)
+		}
+	}
+
+	caPEM := certutil.EncodeCertPEM(ro.newCADER)
+	var results RenewalList
+	for _, secret := range secrets {
+		if _, found := secret.Data[KeyNextCACert]; !found && bytes.Equal(secret.Data[secretutil.KeyCACert], caPEM) {
+			continue
+		}
+
+		result := Renewal{Secret: secret.Name}
+		secret.Data[secretutil.KeyCACert] = caPEM
+		delete(secret.Data, KeyNextCACert)
+		if err = ro.cli.Update(context.TODO(), &secret); err != nil {
+			result.Error = err.Error()
+			return append(results, result), err
+		}
+		results = append(results, result)
+	}
+
+	if err = ro.restart(results); err != nil {
+		return results, err
+	}
+
+	// the state is saved before <ca-secret>-next is deleted, otherwise the rollover
+	// can't be loaded again if it's interrupted between them
+	if err = ro.saveState(PhaseCompleted); err != nil {
+		return results, err
+	}
+
+	newCASecret := corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: ro.state.NewCASecret, Namespace: ro.cli.GetNamespace()}}
+	if err = ro.cli.Delete(context.TODO(), &newCASecret); err != nil && !errors.IsNotFound(err) {
+		return results, fmt.Errorf("rollover is completed, but failed to delete secret %s: %s", newCASecret.Name, err)
+	}
+
+	fmt.Printf("CA rollover is completed, scale up fabedge-operator to load the new CA\n")
+	return results, nil
+}
+
+func (ro *rollover) restart(results RenewalList) error {
+	if !ro.opts.Restart || len(results) == 0 {
+		return nil
+	}
+
+	restartPods(ro.cli, results, ro.opts.RestartTimeout)
+	for _, result := range results {
+		if result.Error != "" {
+			return fmt.Errorf("%s", result.Error)
+		}
+	}
+
+	return nil
+}
+
+// tlsSecrets returns TLS secrets matched by selector in the order of names, CA secrets are excluded
+func (ro *rollover) tlsSecrets() ([]corev1.Secret, error) {
+	found, err := ro.cli.findSecrets(nil, ro.opts.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var secrets []corev1.Secret
+	for _, secret := range found {
+		if secret.Namespace != ro.cli.GetNamespace() ||
+			secret.Name == ro.opts.CASecret ||
+			secret.Name == ro.state.NewCASecret ||
+			secret.Data[corev1.TLSCertKey] == nil {
+			continue
+		}
+		secrets = append(secrets, secret)
+	}
+
+	sort.Slice(secrets, func(i, j int) bool {
+		return secrets[i].Name < secrets[j].Name
+	})
+
+	return secrets, nil
+}
+
+// checkOperator returns an error if fabedge-operator is running. The operator loads CA at startup and
+// regenerates certificates which are not signed by it, it would replace certificates re-issued by the new CA
+func (ro *rollover) checkOperator() error {
+	deploy, err := ro.cli.GetDeployment(context.TODO(), "fabedge-operator")
+	switch {
+	case errors.IsNotFound(err):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if deploy.Spec.Replicas == nil || *deploy.Spec.Replicas > 0 || deploy.Status.Replicas > 0 {
+		return fmt.Errorf("fabedge-operator is running, scale it down by 'kubectl -n %s scale deployment fabedge-operator --replicas=0' first and scale it up after finalize",
+			ro.cli.GetNamespace())
+	}
+
+	return nil
+}
+
+// checkMounts returns an error if an agent or connector pod mounts one of secrets without ca-next.crt,
+// a secret volume with items only projects the listed keys
+func (ro *rollover) checkMounts(secrets []corev1.Secret) error {
+	ctx := context.TODO()
+
+	agentPods, err := ro.cli.ListAgentPods(ctx)
+	if err != nil {
+		return err
+	}
+
+	connectorPods, err := ro.cli.ListConnectorPods(ctx)
+	if err != nil {
+		return err
+	}
+
+	names := sets.NewString()
+	for _, secret := range secrets {
+		names.Insert(secret.Name)
+	}
+
+	var missing []string
+	for _, pod := range append(agentPods, connectorPods...) {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.Secret == nil || !names.Has(volume.Secret.SecretName) || !missesKey(volume.Secret.Items, KeyNextCACert) {
+				continue
+			}
+			missing = append(missing, fmt.Sprintf("%s(volume %s)", pod.Name, volume.Name))
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%s is not mounted by pods: %s, add an item {key: %s, path: cacerts/%s} to their secret volumes first",
+			KeyNextCACert, printer.Join(missing), KeyNextCACert, KeyNextCACert)
+	}
+
+	return nil
+}
+
+func missesKey(items []corev1.KeyToPath, key string) bool {
+	if len(items) == 0 {
+		return false
+	}
+
+	for _, item := range items {
+		if item.Key == key {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (ro *rollover) status() (RolloverStatus, error) {
+	status := RolloverStatus{RolloverState: ro.state}
+
+	oldCA, err := x509.ParseCertificate(ro.oldCADER)
+	if err != nil {
+		return status, err
+	}
+	status.OldCA = oldCA.Subject.String()
+
+	var newCA *x509.Certificate
+	if ro.newCADER != nil {
+		if newCA, err = x509.ParseCertificate(ro.newCADER); err != nil {
+			return status, err
+		}
+		status.NewCA = newCA.Subject.String()
+	}
+
+	secrets, err := ro.tlsSecrets()
+	if err != nil {
+		return status, err
+	}
+
+	status.Secrets = []SecretTrust{}
+	for _, secret := range secrets {
+		t := SecretTrust{Secret: secret.Name}
+
+		var trusted []*x509.Certificate
+		for _, key := range []string{secretutil.KeyCACert, KeyNextCACert} {
+			if key == KeyNextCACert && secret.Data[key] == nil {
+				continue
+			}
+
+			certs, err := parseCertificates(secret.Data[key])
+			if err != nil {
+				t.Error = fmt.Sprintf("failed to decode %s: %s", key, err)
+			}
+			trusted = append(trusted, certs...)
+		}
+		for _, c := range trusted {
+			t.TrustsOldCA = t.TrustsOldCA || bytes.Equal(c.Raw, ro.oldCADER)
+			t.TrustsNewCA = t.TrustsNewCA || (newCA != nil && bytes.Equal(c.Raw, ro.newCADER))
+		}
+
+		t.SignedBy = signedBy(secret, newCA, oldCA)
+		status.Secrets = append(status.Secrets, t)
+	}
+
+	return status, nil
+}
+
+// signedBy checks if the certificate of secret is signed by newCA or oldCA, nil CA is skipped
+func signedBy(secret corev1.Secret, newCA, oldCA *x509.Certificate) string {
+	cert, err := parseCertificateFromSecret(secret)
+	if err != nil {
+		return SignedByOther
+	}
+
+	switch {
+	case newCA != nil && cert.CheckSignatureFrom(newCA) == nil:
+		return SignedByNewCA
+	case oldCA != nil && cert.CheckSignatureFrom(oldCA) == nil:
+		return SignedByOldCA
+	default:
+		return SignedByOther
+	}
+}
+
+func (s RolloverStatus) Describe(w io.Writer) {
+	phase := s.Phase
+	if phase == "" {
+		phase = "not started"
+	}
+
+	printer.Describe(w,
+		printer.KeyValue{Key: "Phase", Value: phase},
+		printer.KeyValue{Key: "Old CA", Value: s.OldCA},
+		printer.KeyValue{Key: "New CA", Value: s.NewCA},
+		printer.KeyValue{Key: "New CA Secret", Value: s.NewCASecret},
+		printer.KeyValue{Key: "Re-issued", Value: fmt.Sprintf("%d/%d", s.countReissued(), len(s.Secrets))},
+		printer.KeyValue{Key: "Started At", Value: formatTime(s.StartedAt)},
+		printer.KeyValue{Key: "Updated At", Value: formatTime(s.UpdatedAt)},
+	)
+
+	if len(s.Secrets) > 0 {
+		fmt.Fprintln(w)
+		printer.PrintTable(w, s, false)
+	}
+}
+
+func (s RolloverStatus) countReissued() int {
+	count := 0
+	for _, t := range s.Secrets {
+		if t.SignedBy == SignedByNewCA {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (s RolloverStatus) Header(wide bool) []string {
+	return []string{"SECRET", "TRUSTS-OLD-CA", "TRUSTS-NEW-CA", "SIGNED-BY", "ERROR"}
+}
+
+func (s RolloverStatus) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, t := range s.Secrets {
+		rows = append(rows, []string{t.Secret, fmt.Sprint(t.TrustsOldCA), fmt.Sprint(t.TrustsNewCA), t.SignedBy, t.Error})
+	}
+
+	return rows
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
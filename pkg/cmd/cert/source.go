@@ -0,0 +1,164 @@
+package cert
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/fabedge/fabctl/pkg/types"
+)
+
+const defaultCertsPath = "/etc/ipsec.d/certs"
+
+// SourceOptions provides sources of certificates other than secrets, they are used
+// when an edge is offline from API server or to check certificates loaded by strongswan
+type SourceOptions struct {
+	File    string
+	Stdin   bool
+	FromPod string
+	Path    string
+}
+
+func (opts *SourceOptions) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&opts.File, "file", "", "Read certificates from a PEM or DER file instead of secrets")
+	fs.BoolVar(&opts.Stdin, "stdin", false, "Read certificates in PEM or DER format from stdin instead of secrets")
+	fs.StringVar(&opts.FromPod, "from-pod", "", "Read certificates from strongswan container of the agent or connector pod on specified node, a pod name is accepted too")
+	fs.StringVar(&opts.Path, "path", defaultCertsPath, "The file or directory of certificates in strongswan container, used with --from-pod")
+}
+
+// Enabled returns true if any source is provided
+func (opts SourceOptions) Enabled() bool {
+	return opts.File != "" || opts.Stdin || opts.FromPod != ""
+}
+
+func (opts SourceOptions) Validate(args []string) error {
+	count := 0
+	for _, provided := range []bool{opts.File != "", opts.Stdin, opts.FromPod != ""} {
+		if provided {
+			count++
+		}
+	}
+
+	switch {
+	case count > 1:
+		return fmt.Errorf("only one of --file, --stdin and --from-pod is allowed")
+	case count == 1 && len(args) > 0:
+		return fmt.Errorf("secret names are not allowed when --file, --stdin or --from-pod is provided")
+	}
+
+	return nil
+}
+
+// sourcedCertificate is a certificate with where it's read from
+type sourcedCertificate struct {
+	source string
+	cert   *x509.Certificate
+}
+
+// Load reads certificates from the provided source, every certificate in bundles is returned
+func (opts SourceOptions) Load(clientGetter types.ClientGetter) ([]sourcedCertificate, error) {
+	switch {
+	case opts.File != "":
+		data, err := ioutil.ReadFile(opts.File)
+		if err != nil {
+			return nil, err
+		}
+		return decodeCertificates(opts.File, data)
+	case opts.Stdin:
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		return decodeCertificates("stdin", data)
+	default:
+		cli, err := clientGetter.GetClient()
+		if err != nil {
+			return nil, err
+		}
+		return loadFromPod(cli, opts.FromPod, opts.Path)
+	}
+}
+
+// loadFromPod reads certificate files under path in strongswan container of the pod on node
+func loadFromPod(cli *types.Client, node, path string) ([]sourcedCertificate, error) {
+	ctx := context.Background()
+
+	podName, err := strongswanPodOf(ctx, cli, node)
+	if err != nil {
+		return nil, err
+	}
+
+	result := cli.ExecCapture(ctx, podName, "strongswan", []string{"find", path, "-type", "f"})
+	if err = result.AsError(); err != nil {
+		return nil, fmt.Errorf("failed to list %s in pod %s: %s", path, podName, err)
+	}
+
+	var certs []sourcedCertificate
+	for _, file := range strings.Fields(result.Stdout) {
+		result = cli.ExecCapture(ctx, podName, "strongswan", []string{"cat", file})
+		if err = result.AsError(); err != nil {
+			return nil, fmt.Errorf("failed to read %s in pod %s: %s", file, podName, err)
+		}
+
+		found, err := decodeCertificates(fmt.Sprintf("%s:%s", podName, file), []byte(result.Stdout))
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, found...)
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found in %s of pod %s", path, podName)
+	}
+
+	return certs, nil
+}
+
+// strongswanPodOf returns the name of agent pod or connector pod running on node,
+// if node is a name of agent pod or connector pod, it's returned directly
+func strongswanPodOf(ctx context.Context, cli *types.Client, node string) (string, error) {
+	if strings.HasPrefix(node, "fabedge-connector") || strings.HasPrefix(node, "fabedge-agent") {
+		return node, nil
+	}
+
+	agents, err := cli.ListAgentPods(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	connectors, err := cli.ListConnectorPods(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	for _, pod := range append(agents, connectors...) {
+		if pod.Spec.NodeName == node && pod.Status.Phase == corev1.PodRunning {
+			return pod.Name, nil
+		}
+	}
+
+	return "", fmt.Errorf("no running agent or connector pod on node %s", node)
+}
+
+// decodeCertificates decodes certificates in PEM data, if no PEM block found, data is decoded as DER
+func decodeCertificates(source string, data []byte) ([]sourcedCertificate, error) {
+	certs, err := parseCertificates(data)
+	if err != nil {
+		if certs, err = x509.ParseCertificates(data); err != nil || len(certs) == 0 {
+			return nil, fmt.Errorf("no certificate found in %s", source)
+		}
+	}
+
+	var found []sourcedCertificate
+	for _, cert := range certs {
+		found = append(found, sourcedCertificate{source: source, cert: cert})
+	}
+
+	return found, nil
+}
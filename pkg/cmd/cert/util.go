@@ -14,6 +14,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type secretClient struct {
@@ -38,7 +40,7 @@ func (cli secretClient) saveCAToSecret(name string, caDER, keyDER []byte) {
 		},
 		Data: map[string][]byte{
 			secretutil.KeyCACert: certutil.EncodeCertPEM(caDER),
-			secretutil.KeyCAKey:  certutil.EncodePrivateKeyPEM(keyDER),
+			secretutil.KeyCAKey:  encodePrivateKeyPEM(keyDER),
 		},
 	})
 }
@@ -49,9 +51,9 @@ func (cli secretClient) saveCertAndKey(name string, caCertDER, certDER, keyDER [
 		Namespace(cli.GetNamespace()).
 		EncodeCACert(caCertDER).
 		EncodeCert(certDER).
-		EncodeKey(keyDER).
 		Label(constants.KeyCreatedBy, "fabctl").
 		Build()
+	secret.Data[corev1.TLSPrivateKeyKey] = encodePrivateKeyPEM(keyDER)
 
 	cli.createOrUpdateSecret(&secret)
 }
@@ -87,39 +89,91 @@ func (cli secretClient) createSecretIfNotExist(secret *corev1.Secret) {
 }
 
 func (cli secretClient) getCertAndKeyAsDER(secretName string) (certDER []byte, keyDER []byte) {
-	secret := cli.getSecret(secretName)
-	return cli.getCertAndKeyFromSecret(secret)
+	certDER, keyDER, err := cli.loadCertAndKey(secretName)
+	if err != nil {
+		util.Exitf("%s\n", err)
+	}
+
+	return certDER, keyDER
+}
+
+func (cli secretClient) loadCertAndKey(secretName string) (certDER []byte, keyDER []byte, err error) {
+	secret, err := cli.getSecret(secretName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return parseCertAndKeyFromSecret(secret)
 }
 
 func (cli secretClient) getCertAndKeyFromSecret(secret corev1.Secret) (certDER []byte, keyDER []byte) {
+	certDER, keyDER, err := parseCertAndKeyFromSecret(secret)
+	if err != nil {
+		util.Exitf("%s\n", err)
+	}
+
+	return certDER, keyDER
+}
+
+func parseCertAndKeyFromSecret(secret corev1.Secret) (certDER []byte, keyDER []byte, err error) {
 	// CA TLS secret created by fabedge-cert CLI has ca.crt/ca.key fields, so
 	// here we try to get data by keys  ca.crt and ca.key first
 	certName, keyName := secretutil.KeyCACert, secretutil.KeyCAKey
-	if secret.Data[certName] != nil && secret.Data[keyName] != nil {
-		return decodePEM(secret.Data[certName]), decodePEM(secret.Data[keyName])
+	if secret.Data[certName] == nil || secret.Data[keyName] == nil {
+		certName, keyName = corev1.TLSCertKey, corev1.TLSPrivateKeyKey
+	}
+
+	if certDER, err = parsePEM(secret.Data[certName]); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode %s of secret %s: %s", certName, secret.Name, err)
 	}
 
-	return decodePEM(secret.Data[corev1.TLSCertKey]), decodePEM(secret.Data[corev1.TLSPrivateKeyKey])
+	// private key is not necessary when verifying certificates
+	keyDER, _ = parsePEM(secret.Data[keyName])
+
+	return certDER, keyDER, nil
 }
 
-func (cli secretClient) getCertificate(secretName string) *x509.Certificate {
-	secret := cli.getSecret(secretName)
+// getCertificates returns all certificates in tls.crt of a secret, which may be a bundle
+func (cli secretClient) getCertificates(secretName string) []*x509.Certificate {
+	secret, err := cli.getSecret(secretName)
+	if err != nil {
+		util.Exitf("%s\n", err)
+	}
 
-	pemBytes := secret.Data[corev1.TLSCertKey]
-	if len(pemBytes) == 0 {
-		pemBytes = secret.Data[secretutil.KeyCACert]
+	certs, err := parseCertificates(certPEMOfSecret(secret))
+	if err != nil {
+		util.Exitf("failed to decode certificate of secret %s: %s\n", secret.Name, err)
+	}
+
+	return certs
+}
+
+// parseCertificateFromSecret returns the first certificate in tls.crt of secret
+func parseCertificateFromSecret(secret corev1.Secret) (*x509.Certificate, error) {
+	der, err := parsePEM(certPEMOfSecret(secret))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode certificate of secret %s: %s", secret.Name, err)
 	}
 
-	cert, err := x509.ParseCertificate(decodePEM(pemBytes))
+	cert, err := x509.ParseCertificate(der)
 	if err != nil {
-		util.Exitf("failed to decode certificate: %s\n", err)
+		return nil, fmt.Errorf("failed to decode certificate of secret %s: %s", secret.Name, err)
 	}
 
-	return cert
+	return cert, nil
+}
+
+// certPEMOfSecret returns tls.crt of secret, for CA secret which has no tls.crt, ca.crt is returned
+func certPEMOfSecret(secret corev1.Secret) []byte {
+	pemBytes := secret.Data[corev1.TLSCertKey]
+	if len(pemBytes) == 0 {
+		pemBytes = secret.Data[secretutil.KeyCACert]
+	}
 
+	return pemBytes
 }
 
-func (cli secretClient) getSecret(name string) corev1.Secret {
+func (cli secretClient) getSecret(name string) (corev1.Secret, error) {
 	var (
 		secret corev1.Secret
 		key    = types.ObjectKey{Name: name, Namespace: cli.GetNamespace()}
@@ -127,17 +181,91 @@ func (cli secretClient) getSecret(name string) corev1.Secret {
 
 	err := cli.Get(context.TODO(), key, &secret)
 	if err != nil {
-		util.Exitf("failed to get secret: %s\n", err)
+		return secret, fmt.Errorf("failed to get secret: %s", err)
+	}
+
+	return secret, nil
+}
+
+// findSecrets gets secrets by names, if no names provided, secrets are listed by selector and backup secrets are skipped
+func (cli secretClient) findSecrets(secretNames []string, selector string) ([]corev1.Secret, error) {
+	if len(secretNames) > 0 {
+		var secrets []corev1.Secret
+		for _, secretName := range secretNames {
+			secret, err := cli.getSecret(secretName)
+			if err != nil {
+				return nil, err
+			}
+			secrets = append(secrets, secret)
+		}
+
+		return secrets, nil
+	}
+
+	l, err := labels.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var secretList corev1.SecretList
+	err = cli.List(context.Background(), &secretList, client.MatchingLabelsSelector{Selector: l})
+	if err != nil {
+		return nil, err
+	}
+
+	// backup secrets have the same type and data of renewed secrets, they are not the secrets in use
+	var secrets []corev1.Secret
+	for _, secret := range secretList.Items {
+		if _, found := secret.Annotations[AnnotationBackupOf]; !found {
+			secrets = append(secrets, secret)
+		}
 	}
 
-	return secret
+	return secrets, nil
 }
 
 func decodePEM(data []byte) []byte {
+	der, err := parsePEM(data)
+	if err != nil {
+		util.Exitf("%s\n", err)
+	}
+
+	return der
+}
+
+// parseCertificates decodes all certificates in PEM data, blocks of other types are ignored
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found in pem data")
+	}
+
+	return certs, nil
+}
+
+func parsePEM(data []byte) ([]byte, error) {
 	block, _ := pem.Decode(data)
 	if block == nil {
-		util.Exitf("failed to decode pem data\n")
+		return nil, fmt.Errorf("failed to decode pem data")
 	}
 
-	return block.Bytes
+	return block.Bytes, nil
 }
@@ -1,28 +1,28 @@
 package cert
 
 import (
-	"context"
 	"crypto/x509"
 	"fmt"
-	"os"
+	"io"
+	"io/ioutil"
 
 	"github.com/fabedge/fabctl/pkg/types"
 	"github.com/fabedge/fabctl/pkg/util"
 	fclient "github.com/fabedge/fabedge/pkg/operator/client"
 	certutil "github.com/fabedge/fabedge/pkg/util/cert"
 	"github.com/spf13/cobra"
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/labels"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func newVerifyCmd(clientGetter types.ClientGetter) *cobra.Command {
 	var commonOptions CommonOptions
+	var sourceOptions SourceOptions
 	var selector string
+	var caFile string
 
 	cmd := &cobra.Command{
-		Use:   "verify [secretNames]",
-		Short: "Verity your TLS secrets with specified CA",
+		Use:         "verify [secretNames]",
+		Short:       "Verity your TLS secrets with specified CA",
+		Annotations: types.MultiContexts(),
 		Example: `Verify specified TLS secrets:
 
 	fabctl cert verify edge-tls edge2-tls
@@ -37,58 +37,225 @@ Verify TLS secrets using specified CA secret:
 
 Verify TSL secrets using host cluster's API server:
 
-	fabctl cert verify --remote --api-server-address=http://host-cluster/`,
+	fabctl cert verify --remote --api-server-address=http://host-cluster/
+
+Verify certificates in a local file with a local CA file when API server is not reachable:
+
+	fabctl cert verify --file=/etc/ipsec.d/certs/tls.crt --ca-file=/etc/ipsec.d/cacerts/ca.crt
+
+Verify certificates loaded by strongswan on node edge1:
+
+	fabctl cert verify --from-pod=edge1`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if err := sourceOptions.Validate(args); err != nil {
+				util.Exitf("%s\n", err)
+			}
+
+			if caFile != "" && !sourceOptions.Enabled() {
+				util.Exitf("--ca-file is only used with --file, --stdin or --from-pod\n")
+			}
+		},
 		Run: func(cmd *cobra.Command, args []string) {
-			cli := newClient(clientGetter)
+			p, err := clientGetter.GetPrinter()
+			util.CheckError(err)
 
-			var caDER []byte
+			if sourceOptions.Enabled() {
+				roots, err := loadRoots(clientGetter, commonOptions, caFile)
+				util.CheckError(err)
+
+				certs, err := sourceOptions.Load(clientGetter)
+				util.CheckError(err)
+
+				util.CheckError(p.Print(verifyCertificates(certs, roots)))
+				return
+			}
+
+			var remoteCADER []byte
 			if commonOptions.Remote() {
 				cacert, err := fclient.GetCertificate(commonOptions.APIServerAddress)
 				if err != nil {
 					util.Exitf("failed to get CA certificate: %s\n", err)
 				}
-				caDER = cacert.DER
-			} else {
-				caDER, _ = cli.getCertAndKeyAsDER(commonOptions.CASecret)
+				remoteCADER = cacert.DER
 			}
 
-			verify := func(secret corev1.Secret) {
-				certDER, _ := cli.getCertAndKeyFromSecret(secret)
-				usages := []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}
-
-				if err := certutil.VerifyCert(caDER, certDER, usages); err != nil {
-					fmt.Fprintf(os.Stderr, "%s is invalid: %s\n", secret.Name, err)
-				} else {
-					fmt.Fprintf(os.Stdin, "%s is valid\n", secret.Name)
+			err = types.PrintInContexts(clientGetter, p, func(getter types.ClientGetter) (interface{}, error) {
+				c, err := getter.GetClient()
+				if err != nil {
+					return nil, err
 				}
-			}
+				cli := secretClient{c}
 
-			if len(args) > 0 {
-				for _, secretName := range args {
-					secret := cli.getSecret(secretName)
-					verify(secret)
+				caDER := remoteCADER
+				if caDER == nil {
+					caDER, _, err = cli.loadCertAndKey(commonOptions.CASecret)
+					if err != nil {
+						return nil, err
+					}
 				}
 
-				return
-			}
-
-			var secretList corev1.SecretList
-			l, err := labels.Parse(selector)
-			util.CheckError(err)
-
-			err = cli.List(context.Background(), &secretList, client.MatchingLabelsSelector{Selector: l})
+				return verifySecrets(cli, caDER, args, selector)
+			})
 			util.CheckError(err)
-
-			for _, secret := range secretList.Items {
-				verify(secret)
-			}
 		},
 	}
 
 	fs := cmd.Flags()
 	commonOptions.AddFlags(fs)
+	sourceOptions.AddFlags(fs)
+	fs.StringVar(&caFile, "ca-file", "", "Read CA certificates from a PEM or DER file instead of CA secret, used with --file, --stdin or --from-pod")
 
 	usage := "Selector (label query) to filter on, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2). Selectors will be ignored if you provide a secretName."
 	fs.StringVarP(&selector, "selector", "l", "fabedge.io/created-by=fabedge-operator", usage)
 	return cmd
 }
+
+type Verification struct {
+	Secret  string `json:"secret,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Valid   bool   `json:"valid"`
+	Error   string `json:"error,omitempty"`
+}
+
+type VerificationList []Verification
+
+func verifySecrets(cli secretClient, caDER []byte, secretNames []string, selector string) (VerificationList, error) {
+	secrets, err := cli.findSecrets(secretNames, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	usages := []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}
+	verifications := make(VerificationList, 0, len(secrets))
+	for _, secret := range secrets {
+		v := Verification{Secret: secret.Name}
+
+		certDER, _, err := parseCertAndKeyFromSecret(secret)
+		if err == nil {
+			err = certutil.VerifyCert(caDER, certDER, usages)
+		}
+
+		if err != nil {
+			v.Error = err.Error()
+		} else {
+			v.Valid = true
+		}
+		verifications = append(verifications, v)
+	}
+
+	return verifications, nil
+}
+
+// loadRoots returns CA certificates from caFile, host cluster or CA secret
+func loadRoots(clientGetter types.ClientGetter, commonOptions CommonOptions, caFile string) (*x509.CertPool, error) {
+	roots := x509.NewCertPool()
+
+	switch {
+	case caFile != "":
+		data, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		certs, err := decodeCertificates(caFile, data)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range certs {
+			roots.AddCert(c.cert)
+		}
+	case commonOptions.Remote():
+		cacert, err := fclient.GetCertificate(commonOptions.APIServerAddress)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get CA certificate: %s", err)
+		}
+		roots.AddCert(cacert.Raw)
+	default:
+		cli := newClient(clientGetter)
+		caDER, _, err := cli.loadCertAndKey(commonOptions.CASecret)
+		if err != nil {
+			return nil, err
+		}
+
+		cacert, err := x509.ParseCertificate(caDER)
+		if err != nil {
+			return nil, err
+		}
+		roots.AddCert(cacert)
+	}
+
+	return roots, nil
+}
+
+// verifyCertificates verifies certificates with roots, CA certificates in certs are used as
+// intermediates to verify leaf certificates. If there are only CA certificates, all of them are verified.
+func verifyCertificates(certs []sourcedCertificate, roots *x509.CertPool) VerificationList {
+	intermediates := x509.NewCertPool()
+	var leaves []sourcedCertificate
+	for _, c := range certs {
+		if c.cert.IsCA {
+			intermediates.AddCert(c.cert)
+		} else {
+			leaves = append(leaves, c)
+		}
+	}
+
+	usages := []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}
+	if len(leaves) == 0 {
+		leaves = certs
+		usages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+	}
+
+	verifications := make(VerificationList, 0, len(leaves))
+	for _, c := range leaves {
+		v := Verification{Source: c.source, Subject: c.cert.Subject.String()}
+
+		_, err := c.cert.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			KeyUsages:     usages,
+		})
+		if err != nil {
+			v.Error = err.Error()
+		} else {
+			v.Valid = true
+		}
+		verifications = append(verifications, v)
+	}
+
+	return verifications
+}
+
+// name returns secret name, or source and subject if the certificate is not from a secret
+func (v Verification) name() string {
+	if v.Secret != "" {
+		return v.Secret
+	}
+
+	return fmt.Sprintf("%s(%s)", v.Source, v.Subject)
+}
+
+func (list VerificationList) Describe(w io.Writer) {
+	for _, v := range list {
+		if v.Valid {
+			fmt.Fprintf(w, "%s is valid\n", v.name())
+		} else {
+			fmt.Fprintf(w, "%s is invalid: %s\n", v.name(), v.Error)
+		}
+	}
+}
+
+func (list VerificationList) Header(wide bool) []string {
+	return []string{"NAME", "VALID", "ERROR"}
+}
+
+func (list VerificationList) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, v := range list {
+		rows = append(rows, []string{v.name(), fmt.Sprint(v.Valid), v.Error})
+	}
+
+	return rows
+}
@@ -1,50 +1,220 @@
 package cert
 
 import (
-	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"fmt"
+	"io"
 	"net"
 	"net/url"
 	"strings"
+	"time"
 
-	"github.com/fabedge/fabctl/pkg/types"
 	"github.com/spf13/cobra"
+
+	"github.com/fabedge/fabctl/pkg/printer"
+	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
 )
 
+type Certificate struct {
+	Source             string    `json:"source,omitempty"`
+	Version            int       `json:"version"`
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	IsCA               bool      `json:"isCA"`
+	SignatureAlgorithm string    `json:"signatureAlgorithm"`
+	PublicKeyAlgorithm string    `json:"publicKeyAlgorithm"`
+	NotBefore          time.Time `json:"notBefore"`
+	NotAfter           time.Time `json:"notAfter"`
+	KeyLength          int       `json:"keyLength"`
+	Curve              string    `json:"curve,omitempty"`
+	KeyUsage           []string  `json:"keyUsage"`
+	ExtKeyUsage        []string  `json:"extKeyUsage"`
+	DNSNames           []string  `json:"dnsNames"`
+	IPAddresses        []string  `json:"ipAddresses"`
+	EmailAddresses     []string  `json:"emailAddresses"`
+	URIs               []string  `json:"uris"`
+	SHA256Fingerprint  string    `json:"sha256Fingerprint"`
+	SubjectKeyID       string    `json:"subjectKeyID,omitempty"`
+	AuthorityKeyID     string    `json:"authorityKeyID,omitempty"`
+}
+
+// CertificateList is printed when a secret or a file contains more than one certificate
+type CertificateList []Certificate
+
+func newCertificate(cert *x509.Certificate) Certificate {
+	keyLength, curve := publicKeyInfo(cert.PublicKey)
+	fingerprint := sha256.Sum256(cert.Raw)
+
+	return Certificate{
+		Version:            cert.Version,
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		IsCA:               cert.IsCA,
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		PublicKeyAlgorithm: cert.PublicKeyAlgorithm.String(),
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		KeyLength:          keyLength,
+		Curve:              curve,
+		KeyUsage:           keyUsageNames(cert.KeyUsage),
+		ExtKeyUsage:        extKeyUsageNames(cert.ExtKeyUsage),
+		DNSNames:           cert.DNSNames,
+		IPAddresses:        ipStrings(cert.IPAddresses),
+		EmailAddresses:     cert.EmailAddresses,
+		URIs:               uriStrings(cert.URIs),
+		SHA256Fingerprint:  formatHex(fingerprint[:]),
+		SubjectKeyID:       formatHex(cert.SubjectKeyId),
+		AuthorityKeyID:     formatHex(cert.AuthorityKeyId),
+	}
+}
+
+func (c Certificate) Describe(w io.Writer) {
+	if c.Source != "" {
+		fmt.Fprintf(w, "Source: %s\n", c.Source)
+	}
+	fmt.Fprintf(w, "Version: %d\n", c.Version)
+	fmt.Fprintf(w, "Subject: %s\n", c.Subject)
+	fmt.Fprintf(w, "Issuer: %s\n", c.Issuer)
+	fmt.Fprintf(w, "IsCA: %t\n", c.IsCA)
+	fmt.Fprintf(w, "Signature Algorithm: %s\n", c.SignatureAlgorithm)
+	fmt.Fprintf(w, "Publickey Algorithm: %s\n", c.PublicKeyAlgorithm)
+	fmt.Fprintf(w, "Validity: \n")
+	fmt.Fprintf(w, "      Not Before: %s\n", c.NotBefore)
+	fmt.Fprintf(w, "      Not After: %s\n", c.NotAfter)
+	fmt.Fprintf(w, "Key length: %d\n", c.KeyLength)
+	if c.Curve != "" {
+		fmt.Fprintf(w, "Curve: %s\n", c.Curve)
+	}
+	fmt.Fprintf(w, "Key Usage: %s\n", strings.Join(c.KeyUsage, " "))
+	fmt.Fprintf(w, "Ext Key Usage: %s\n", strings.Join(c.ExtKeyUsage, " "))
+	fmt.Fprintf(w, "DNS Names: %s\n", strings.Join(c.DNSNames, " "))
+	fmt.Fprintf(w, "IP Addresses: %s\n", strings.Join(c.IPAddresses, " "))
+	fmt.Fprintf(w, "Email Addresses: %s\n", strings.Join(c.EmailAddresses, " "))
+	fmt.Fprintf(w, "URIs: %s\n", strings.Join(c.URIs, " "))
+	fmt.Fprintf(w, "SHA256 Fingerprint: %s\n", c.SHA256Fingerprint)
+	fmt.Fprintf(w, "Subject Key ID: %s\n", c.SubjectKeyID)
+	fmt.Fprintf(w, "Authority Key ID: %s\n", c.AuthorityKeyID)
+}
+
+func (c Certificate) Header(wide bool) []string {
+	header := []string{"SUBJECT", "ISSUER", "IS-CA", "NOT-AFTER"}
+	if wide {
+		header = append(header, "NOT-BEFORE", "PUBLIC-KEY-ALGORITHM", "KEY-LENGTH", "DNS-NAMES", "IP-ADDRESSES", "SHA256-FINGERPRINT")
+	}
+
+	return header
+}
+
+func (c Certificate) Rows(wide bool) [][]string {
+	row := []string{c.Subject, c.Issuer, fmt.Sprint(c.IsCA), c.NotAfter.Format(time.RFC3339)}
+	if wide {
+		row = append(row,
+			c.NotBefore.Format(time.RFC3339),
+			c.PublicKeyAlgorithm,
+			fmt.Sprint(c.KeyLength),
+			printer.Join(c.DNSNames),
+			printer.Join(c.IPAddresses),
+			c.SHA256Fingerprint,
+		)
+	}
+
+	return [][]string{row}
+}
+
+func (list CertificateList) Describe(w io.Writer) {
+	for i, c := range list {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Certificate %d of %d\n", i+1, len(list))
+		c.Describe(w)
+	}
+}
+
+func (list CertificateList) Header(wide bool) []string {
+	return append([]string{"SOURCE"}, Certificate{}.Header(wide)...)
+}
+
+func (list CertificateList) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, c := range list {
+		for _, row := range c.Rows(wide) {
+			rows = append(rows, append([]string{c.Source}, row...))
+		}
+	}
+
+	return rows
+}
+
 func newViewCmd(clientGetter types.ClientGetter) *cobra.Command {
+	var sourceOptions SourceOptions
+
 	cmd := &cobra.Command{
-		Use:     "view secretName",
-		Short:   "View the certificate of a TLS secret",
-		Example: "fabctl cert view secretName",
-		Args:    cobra.ExactArgs(1),
+		Use:   "view [secretName]",
+		Short: "View the certificate of a TLS secret",
+		Long:  "View the certificate of a TLS secret, a PEM file, stdin or strongswan container. If a bundle is provided, all certificates in it are printed in order.",
+		Example: `View the certificate of a TLS secret:
+
+	fabctl cert view secretName
+
+View certificates in a local PEM file:
+
+	fabctl cert view --file=/etc/ipsec.d/certs/tls.crt
+
+View certificates loaded by strongswan on node edge1:
+
+	fabctl cert view --from-pod=edge1
+
+View certificates from stdin:
+
+	kubectl get secret secretName -o jsonpath='{.data.ca\.crt}' | base64 -d | fabctl cert view --stdin`,
+		Args: cobra.MaximumNArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if err := sourceOptions.Validate(args); err != nil {
+				util.Exitf("%s\n", err)
+			}
+
+			if len(args) == 0 && !sourceOptions.Enabled() {
+				util.Exitf("secretName is required\n")
+			}
+		},
 		Run: func(cmd *cobra.Command, args []string) {
-			cli := newClient(clientGetter)
-			cert := cli.getCertificate(args[0])
-
-			fmt.Printf("Version: %d\n", cert.Version)
-			fmt.Printf("Subject: %s\n", cert.Subject)
-			fmt.Printf("Issuer: %s\n", cert.Issuer)
-			fmt.Printf("IsCA: %t\n", cert.IsCA)
-			fmt.Printf("Signature Algorithm: %s\n", cert.SignatureAlgorithm)
-			fmt.Printf("Publickey Algorithm: %s\n", cert.PublicKeyAlgorithm)
-			fmt.Printf("Validity: \n")
-			fmt.Printf("      Not Before: %s\n", cert.NotBefore)
-			fmt.Printf("      Not After: %s\n", cert.NotAfter)
-			fmt.Printf("Key length: %d\n", cert.PublicKey.(*rsa.PublicKey).Size()*8)
-			fmt.Printf("Key Usage: %s\n", formatKeyUsage(cert.KeyUsage))
-			fmt.Printf("Ext Key Usage: %s\n", formatExtUsages(cert.ExtKeyUsage))
-			fmt.Printf("DNS Names: %s\n", strings.Join(cert.DNSNames, " "))
-			fmt.Printf("IP Addresses: %s\n", formatIPs(cert.IPAddresses))
-			fmt.Printf("Email Addresses: %s\n", strings.Join(cert.EmailAddresses, " "))
-			fmt.Printf("URIs: %s\n", formatURIs(cert.URIs))
+			p, err := clientGetter.GetPrinter()
+			util.CheckError(err)
+
+			var list CertificateList
+			if sourceOptions.Enabled() {
+				certs, err := sourceOptions.Load(clientGetter)
+				util.CheckError(err)
+
+				for _, c := range certs {
+					cert := newCertificate(c.cert)
+					cert.Source = c.source
+					list = append(list, cert)
+				}
+			} else {
+				cli := newClient(clientGetter)
+				for _, cert := range cli.getCertificates(args[0]) {
+					list = append(list, newCertificate(cert))
+				}
+			}
+
+			// a single certificate is printed as before to keep output of secrets compatible
+			if len(list) == 1 {
+				util.CheckError(p.Print(list[0]))
+			} else {
+				util.CheckError(p.Print(list))
+			}
 		},
 	}
 
+	sourceOptions.AddFlags(cmd.Flags())
 	return cmd
 }
 
-func formatKeyUsage(keyUsage x509.KeyUsage) string {
+func keyUsageNames(keyUsage x509.KeyUsage) []string {
 	var usages []string
 	isUsed := func(expected x509.KeyUsage) bool {
 		return keyUsage&expected == expected
@@ -86,10 +256,10 @@ func formatKeyUsage(keyUsage x509.KeyUsage) string {
 		usages = append(usages, "DecipherOnly")
 	}
 
-	return strings.Join(usages, " ")
+	return usages
 }
 
-func formatExtUsages(extKeyUsages []x509.ExtKeyUsage) string {
+func extKeyUsageNames(extKeyUsages []x509.ExtKeyUsage) []string {
 	var usages []string
 
 	for _, eku := range extKeyUsages {
@@ -130,23 +300,33 @@ func formatExtUsages(extKeyUsages []x509.ExtKeyUsage) string {
 		}
 	}
 
-	return strings.Join(usages, " ")
+	return usages
+}
+
+// formatHex formats bytes as colon separated hex like openssl does
+func formatHex(data []byte) string {
+	values := make([]string, 0, len(data))
+	for _, b := range data {
+		values = append(values, fmt.Sprintf("%02X", b))
+	}
+
+	return strings.Join(values, ":")
 }
 
-func formatIPs(ips []net.IP) string {
+func ipStrings(ips []net.IP) []string {
 	var values []string
 	for _, ip := range ips {
 		values = append(values, ip.String())
 	}
 
-	return strings.Join(values, " ")
+	return values
 }
 
-func formatURIs(urls []*url.URL) string {
+func uriStrings(urls []*url.URL) []string {
 	var values []string
 	for _, url := range urls {
 		values = append(values, url.String())
 	}
 
-	return strings.Join(values, " ")
+	return values
 }
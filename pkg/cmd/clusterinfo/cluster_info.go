@@ -3,63 +3,67 @@ package clusterinfo
 import (
 	"context"
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/api/errors"
 
+	"github.com/fabedge/fabctl/pkg/printer"
 	"github.com/fabedge/fabctl/pkg/types"
 	"github.com/fabedge/fabctl/pkg/util"
 )
 
 type Cluster struct {
 	client *types.Client
-	Name   string
-	Role   string
+	Name   string `json:"name"`
+	Role   string `json:"role"`
 
-	CNIType                string
-	EdgePodCIDR            string
-	ConnectorPublicAddress string
-	ConnectorSubnets       string
+	CNIType                  string   `json:"cniType"`
+	EdgePodCIDR              string   `json:"edgePodCIDR"`
+	ConnectorPublicAddresses []string `json:"connectorPublicAddresses"`
+	ConnectorSubnets         []string `json:"connectorSubnets"`
 
-	Zone   string
-	Region string
+	Zone   string `json:"zone"`
+	Region string `json:"region"`
 }
 
 func New(clientGetter types.ClientGetter) *cobra.Command {
 	return &cobra.Command{
-		Use:   "cluster-info",
-		Short: "Show information related to FabEdge of a cluster",
+		Use:         "cluster-info",
+		Short:       "Show information related to FabEdge of a cluster",
+		Annotations: types.MultiContexts(),
 		Run: func(cmd *cobra.Command, args []string) {
-			cli, err := clientGetter.GetClient()
+			p, err := clientGetter.GetPrinter()
 			util.CheckError(err)
 
-			cluster := Cluster{client: cli}
-			cluster.extractValuesFromOperator()
-			cluster.extractTopology()
-
-			fmt.Printf(`
-Name:                       %s
-Role:                       %s
-Region:                     %s
-Zone:                       %s
-CNI Type:                   %s
-EdgePodCIDR:                %s
-Connector Public Addresses: %s
-Connector Subnets:          %s
-`,
-				cluster.Name, cluster.Role,
-				cluster.Region, cluster.Zone,
-				cluster.CNIType, cluster.EdgePodCIDR,
-				cluster.ConnectorPublicAddress, cluster.ConnectorSubnets,
-			)
+			err = types.PrintInContexts(clientGetter, p, func(getter types.ClientGetter) (interface{}, error) {
+				cli, err := getter.GetClient()
+				if err != nil {
+					return nil, err
+				}
+
+				return getCluster(cli)
+			})
+			util.CheckError(err)
 		},
 	}
 }
 
-func (c *Cluster) extractValuesFromOperator() {
+func getCluster(cli *types.Client) (Cluster, error) {
+	cluster := Cluster{client: cli}
+	if err := cluster.extractValuesFromOperator(); err != nil {
+		return cluster, err
+	}
+
+	err := cluster.extractTopology()
+	return cluster, err
+}
+
+func (c *Cluster) extractValuesFromOperator() error {
 	operator, err := c.client.GetDeployment(context.Background(), "fabedge-operator")
 	if err != nil {
-		util.Exitf("failed to get fabedge-operator deployment: %s\n", err)
+		return fmt.Errorf("failed to get fabedge-operator deployment: %s", err)
 	}
 
 	args := types.NewArgs(operator.Spec.Template.Spec.Containers[0].Args)
@@ -67,20 +71,66 @@ func (c *Cluster) extractValuesFromOperator() {
 	c.Role = args.GetValue("cluster-role")
 	c.CNIType = args.GetValue("cni-type")
 	c.EdgePodCIDR = args.GetValue("edge-pod-cidr")
-	c.ConnectorPublicAddress = args.GetValue("connector-public-addresses")
-	c.ConnectorSubnets = args.GetValue("connector-subnets")
+	c.ConnectorPublicAddresses = splitValues(args.GetValue("connector-public-addresses"))
+	c.ConnectorSubnets = splitValues(args.GetValue("connector-subnets"))
+
+	return nil
 }
 
-func (c *Cluster) extractTopology() {
+func (c *Cluster) extractTopology() error {
 	serviceHub, err := c.client.GetDeployment(context.Background(), "service-hub")
 	switch {
 	case err == nil:
 		args := types.NewArgs(serviceHub.Spec.Template.Spec.Containers[0].Args)
 		c.Region = args.GetValue("region")
 		c.Zone = args.GetValue("zone")
+		return nil
 	case errors.IsNotFound(err):
-		util.Exitf("service-hub deployment is not found\n")
+		return fmt.Errorf("service-hub deployment is not found")
 	default:
-		util.Exitf("failed to get service-hub deployment: %s\n", err)
+		return fmt.Errorf("failed to get service-hub deployment: %s", err)
 	}
 }
+
+func (c Cluster) Describe(w io.Writer) {
+	printer.Describe(w,
+		printer.KeyValue{Key: "Name", Value: c.Name},
+		printer.KeyValue{Key: "Role", Value: c.Role},
+		printer.KeyValue{Key: "Region", Value: c.Region},
+		printer.KeyValue{Key: "Zone", Value: c.Zone},
+		printer.KeyValue{Key: "CNI Type", Value: c.CNIType},
+		printer.KeyValue{Key: "EdgePodCIDR", Value: c.EdgePodCIDR},
+		printer.KeyValue{Key: "Connector Public Addresses", Value: printer.Join(c.ConnectorPublicAddresses)},
+		printer.KeyValue{Key: "Connector Subnets", Value: printer.Join(c.ConnectorSubnets)},
+	)
+}
+
+func (c Cluster) Header(wide bool) []string {
+	header := []string{"NAME", "ROLE", "CNI-TYPE", "CONNECTOR-PUBLIC-ADDRESSES"}
+	if wide {
+		header = append(header, "REGION", "ZONE", "EDGE-POD-CIDR", "CONNECTOR-SUBNETS")
+	}
+
+	return header
+}
+
+func (c Cluster) Rows(wide bool) [][]string {
+	row := []string{c.Name, c.Role, c.CNIType, printer.Join(c.ConnectorPublicAddresses)}
+	if wide {
+		row = append(row, c.Region, c.Zone, c.EdgePodCIDR, printer.Join(c.ConnectorSubnets))
+	}
+
+	return [][]string{row}
+}
+
+// splitValues splits comma separated values and drops empty ones
+func splitValues(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,408 @@
+package clusters
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	apisv1 "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/fabedge/fabctl/pkg/iproute"
+	"github.com/fabedge/fabctl/pkg/printer"
+	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
+)
+
+const (
+	KindSubnets     = "subnets"
+	KindNodeSubnets = "nodeSubnets"
+)
+
+// Cluster is a FabEdge cluster registered by a Cluster resource
+type Cluster struct {
+	Name               string            `json:"name"`
+	Role               string            `json:"role"`
+	ConnectorAddresses []string          `json:"connectorAddresses"`
+	Subnets            []string          `json:"subnets"`
+	TokenExpiry        *time.Time        `json:"tokenExpiry,omitempty"`
+	LastUpdate         time.Time         `json:"lastUpdate"`
+	Endpoints          []apisv1.Endpoint `json:"endpoints"`
+	Overlaps           []Overlap         `json:"overlaps,omitempty"`
+}
+
+// Overlap is a subnet of an endpoint in a cluster which overlaps with a subnet of an endpoint in another cluster.
+// Overlapped node subnets may be fine if nodes are behind NAT, but overlapped pod subnets break traffic silently.
+type Overlap struct {
+	Kind          string `json:"kind"`
+	Endpoint      string `json:"endpoint"`
+	Subnet        string `json:"subnet"`
+	OtherCluster  string `json:"otherCluster"`
+	OtherEndpoint string `json:"otherEndpoint"`
+	OtherSubnet   string `json:"otherSubnet"`
+}
+
+type ClusterList []Cluster
+
+func New(clientGetter types.ClientGetter) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clusters [flags]",
+		Short: "List FabEdge clusters registered by Cluster resources",
+		Long: `List FabEdge clusters registered by Cluster resources. Subnets of endpoints in different clusters are checked,
+if any pod subnets overlap, clusters exits with non-zero code.`,
+		Example: `
+fabctl clusters
+fabctl clusters -o wide
+fabctl clusters describe beijing
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			p, err := clientGetter.GetPrinter()
+			util.CheckError(err)
+
+			cli, err := clientGetter.GetClient()
+			util.CheckError(err)
+
+			clusters, err := listClusters(cli)
+			util.CheckError(err)
+
+			util.CheckError(p.Print(clusters))
+			exitOnOverlaps(clusters)
+		},
+	}
+
+	cmd.AddCommand(newDescribeCmd(clientGetter))
+	return cmd
+}
+
+func newDescribeCmd(clientGetter types.ClientGetter) *cobra.Command {
+	return &cobra.Command{
+		Use:   "describe name [name]...",
+		Short: "Show endpoints and subnet overlaps of specified clusters",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			p, err := clientGetter.GetPrinter()
+			util.CheckError(err)
+
+			cli, err := clientGetter.GetClient()
+			util.CheckError(err)
+
+			clusters, err := listClusters(cli)
+			util.CheckError(err)
+
+			var selected ClusterDetails
+			for _, name := range args {
+				found := false
+				for _, c := range clusters {
+					if c.Name == name {
+						selected, found = append(selected, c), true
+						break
+					}
+				}
+
+				if !found {
+					util.Exitf("cluster %s is not found\n", name)
+				}
+			}
+
+			util.CheckError(p.Print(selected))
+			exitOnOverlaps(ClusterList(selected))
+		},
+	}
+}
+
+func listClusters(cli *types.Client) (ClusterList, error) {
+	local := types.NewCluster(cli)
+	if err := local.ExtractArgumentsFromFabEdge(); err != nil {
+		return nil, err
+	}
+
+	crs, err := cli.ListClusters(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(crs, func(i, j int) bool {
+		return crs[i].Name < crs[j].Name
+	})
+
+	clusters := make(ClusterList, 0, len(crs))
+	for _, cr := range crs {
+		clusters = append(clusters, newCluster(cr, local))
+	}
+
+	findOverlaps(clusters)
+
+	return clusters, nil
+}
+
+func newCluster(cr apisv1.Cluster, local *types.Cluster) Cluster {
+	c := Cluster{
+		Name:       cr.Name,
+		Role:       roleOf(cr.Name, local),
+		Endpoints:  cr.Spec.EndPoints,
+		LastUpdate: cr.CreationTimestamp.Time,
+	}
+
+	subnets := sets.NewString()
+	for _, ep := range cr.Spec.EndPoints {
+		if ep.Type == apisv1.Connector {
+			c.ConnectorAddresses = append(c.ConnectorAddresses, ep.PublicAddresses...)
+		}
+		subnets.Insert(ep.Subnets...)
+	}
+	c.Subnets = subnets.List()
+
+	for _, field := range cr.ManagedFields {
+		if field.Time != nil && field.Time.After(c.LastUpdate) {
+			c.LastUpdate = field.Time.Time
+		}
+	}
+
+	if expiry, ok := tokenExpiry(cr.Spec.Token); ok {
+		c.TokenExpiry = &expiry
+	}
+
+	return c
+}
+
+// roleOf returns role of a cluster, only the role of local cluster is known, other
+// clusters are members if local cluster is host
+func roleOf(name string, local *types.Cluster) string {
+	switch {
+	case name == local.Name:
+		return local.Role
+	case local.Role == "host":
+		return "member"
+	default:
+		return ""
+	}
+}
+
+// tokenExpiry returns the exp claim of a JWT token, the signature is not verified
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(claims.Exp, 0), true
+}
+
+// findOverlaps checks subnets and node subnets of endpoints in different clusters
+func findOverlaps(clusters ClusterList) {
+	for i := range clusters {
+		for j := range clusters {
+			if i == j {
+				continue
+			}
+
+			for _, ep := range clusters[i].Endpoints {
+				for _, other := range clusters[j].Endpoints {
+					clusters[i].Overlaps = append(clusters[i].Overlaps, overlapsOf(KindSubnets, ep, ep.Subnets, clusters[j].Name, other, other.Subnets)...)
+					clusters[i].Overlaps = append(clusters[i].Overlaps, overlapsOf(KindNodeSubnets, ep, ep.NodeSubnets, clusters[j].Name, other, other.NodeSubnets)...)
+				}
+			}
+		}
+	}
+}
+
+func overlapsOf(kind string, ep apisv1.Endpoint, subnets []string, otherCluster string, other apisv1.Endpoint, otherSubnets []string) []Overlap {
+	var overlaps []Overlap
+	for _, subnet := range subnets {
+		for _, otherSubnet := range otherSubnets {
+			if iproute.Covers(subnet, otherSubnet) || iproute.Covers(otherSubnet, subnet) {
+				overlaps = append(overlaps, Overlap{
+					Kind:          kind,
+					Endpoint:      ep.Name,
+					Subnet:        subnet,
+					OtherCluster:  otherCluster,
+					OtherEndpoint: other.Name,
+					OtherSubnet:   otherSubnet,
+				})
+			}
+		}
+	}
+
+	return overlaps
+}
+
+func exitOnOverlaps(clusters ClusterList) {
+	if count := countPodSubnetOverlaps(clusters); count > 0 {
+		util.Exitf("%d pairs of pod subnets overlap between clusters\n", count)
+	}
+}
+
+// countPodSubnetOverlaps counts overlapped pod subnets, an overlap is recorded in both clusters,
+// so it's counted once by the unordered pair of subnets
+func countPodSubnetOverlaps(clusters ClusterList) int {
+	pairs := sets.NewString()
+	for _, c := range clusters {
+		for _, o := range c.Overlaps {
+			if o.Kind != KindSubnets {
+				continue
+			}
+
+			a := fmt.Sprintf("%s/%s/%s", c.Name, o.Endpoint, o.Subnet)
+			b := fmt.Sprintf("%s/%s/%s", o.OtherCluster, o.OtherEndpoint, o.OtherSubnet)
+			if a > b {
+				a, b = b, a
+			}
+			pairs.Insert(a + " " + b)
+		}
+	}
+
+	return pairs.Len()
+}
+
+// overlappedClusters returns names of clusters which have overlapped subnets with c
+func (c Cluster) overlappedClusters() []string {
+	names := sets.NewString()
+	for _, o := range c.Overlaps {
+		names.Insert(o.OtherCluster)
+	}
+
+	return names.List()
+}
+
+func (c Cluster) formatTokenExpiry() string {
+	if c.TokenExpiry == nil {
+		return ""
+	}
+
+	return c.TokenExpiry.Format(time.RFC3339)
+}
+
+func (clusters ClusterList) Describe(w io.Writer) {
+	for _, c := range clusters {
+		printer.Describe(w,
+			printer.KeyValue{Key: "Name", Value: c.Name},
+			printer.KeyValue{Key: "Role", Value: c.Role},
+			printer.KeyValue{Key: "Connector Addresses", Value: printer.Join(c.ConnectorAddresses)},
+			printer.KeyValue{Key: "Endpoints", Value: fmt.Sprint(len(c.Endpoints))},
+			printer.KeyValue{Key: "Subnets", Value: printer.Join(c.Subnets)},
+			printer.KeyValue{Key: "Token Expiry", Value: c.formatTokenExpiry()},
+			printer.KeyValue{Key: "Last Update", Value: c.LastUpdate.Format(time.RFC3339)},
+			printer.KeyValue{Key: "Overlaps", Value: printer.Join(c.overlappedClusters())},
+		)
+	}
+}
+
+func (clusters ClusterList) Header(wide bool) []string {
+	header := []string{"NAME", "ROLE", "CONNECTOR-ADDRESSES", "ENDPOINTS", "TOKEN-EXPIRY", "LAST-UPDATE", "OVERLAPS"}
+	if wide {
+		header = append(header, "SUBNETS")
+	}
+
+	return header
+}
+
+func (clusters ClusterList) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, c := range clusters {
+		row := []string{
+			c.Name,
+			c.Role,
+			printer.Join(c.ConnectorAddresses),
+			fmt.Sprint(len(c.Endpoints)),
+			c.formatTokenExpiry(),
+			c.LastUpdate.Format(time.RFC3339),
+			printer.Join(c.overlappedClusters()),
+		}
+		if wide {
+			row = append(row, printer.Join(c.Subnets))
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// ClusterDetails is printed by describe, endpoints and overlaps are printed as tables
+type ClusterDetails []Cluster
+
+func (clusters ClusterDetails) Describe(w io.Writer) {
+	for _, c := range clusters {
+		ClusterList{c}.Describe(w)
+
+		fmt.Fprintln(w)
+		printer.PrintTable(w, endpointTable(c.Endpoints), false)
+
+		if len(c.Overlaps) > 0 {
+			fmt.Fprintln(w)
+			printer.PrintTable(w, overlapTable(c.Overlaps), false)
+		}
+	}
+}
+
+func (clusters ClusterDetails) Header(wide bool) []string {
+	return append([]string{"CLUSTER"}, endpointTable(nil).Header(wide)...)
+}
+
+func (clusters ClusterDetails) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, c := range clusters {
+		for _, row := range endpointTable(c.Endpoints).Rows(wide) {
+			rows = append(rows, append([]string{c.Name}, row...))
+		}
+	}
+
+	return rows
+}
+
+type endpointTable []apisv1.Endpoint
+
+func (endpoints endpointTable) Header(wide bool) []string {
+	header := []string{"ENDPOINT", "TYPE", "PUBLIC-ADDRESSES", "SUBNETS"}
+	if wide {
+		header = append(header, "NODE-SUBNETS")
+	}
+
+	return header
+}
+
+func (endpoints endpointTable) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, ep := range endpoints {
+		row := []string{ep.Name, string(ep.Type), printer.Join(ep.PublicAddresses), printer.Join(ep.Subnets)}
+		if wide {
+			row = append(row, printer.Join(ep.NodeSubnets))
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+type overlapTable []Overlap
+
+func (overlaps overlapTable) Header(wide bool) []string {
+	return []string{"KIND", "ENDPOINT", "SUBNET", "OVERLAPS-WITH", "OTHER-SUBNET"}
+}
+
+func (overlaps overlapTable) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, o := range overlaps {
+		rows = append(rows, []string{o.Kind, o.Endpoint, o.Subnet, fmt.Sprintf("%s/%s", o.OtherCluster, o.OtherEndpoint), o.OtherSubnet})
+	}
+
+	return rows
+}
@@ -0,0 +1,126 @@
+package clusters
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	apisv1 "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+)
+
+func newToken(payload string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + encode([]byte(payload)) + ".c2lnbmF0dXJl"
+}
+
+func TestTokenExpiry(t *testing.T) {
+	testCases := []struct {
+		name     string
+		token    string
+		expected time.Time
+		ok       bool
+	}{
+		{
+			name:     "token with exp",
+			token:    newToken(`{"sub":"beijing","exp":1700000000}`),
+			expected: time.Unix(1700000000, 0),
+			ok:       true,
+		},
+		{
+			name:     "payload with padding",
+			token:    "eyJhbGciOiJSUzI1NiJ9." + base64.URLEncoding.EncodeToString([]byte(`{"exp": 1700000000}`)) + ".c2lnbmF0dXJl",
+			expected: time.Unix(1700000000, 0),
+			ok:       true,
+		},
+		{
+			name:  "token without exp",
+			token: newToken(`{"sub":"beijing"}`),
+		},
+		{
+			name:  "payload is not json",
+			token: newToken(`beijing`),
+		},
+		{
+			name:  "payload is not base64",
+			token: "header.!!!.signature",
+		},
+		{
+			name:  "not a JWT token",
+			token: "5f4dcc3b5aa765d61d8327deb882cf99",
+		},
+		{
+			name:  "empty token",
+			token: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expiry, ok := tokenExpiry(tc.token)
+			if ok != tc.ok {
+				t.Fatalf("expected ok %t, got %t", tc.ok, ok)
+			}
+
+			if !expiry.Equal(tc.expected) {
+				t.Errorf("expected expiry %s, got %s", tc.expected, expiry)
+			}
+		})
+	}
+}
+
+func TestCountPodSubnetOverlaps(t *testing.T) {
+	beijing := Cluster{
+		Name: "beijing",
+		Endpoints: []apisv1.Endpoint{
+			{Name: "beijing.connector", Subnets: []string{"10.233.64.0/18"}, NodeSubnets: []string{"10.22.46.47"}},
+			{Name: "beijing.edge1", Subnets: []string{"10.234.1.0/24"}, NodeSubnets: []string{"192.168.1.10"}},
+		},
+	}
+	shanghai := Cluster{
+		Name: "shanghai",
+		Endpoints: []apisv1.Endpoint{
+			{Name: "shanghai.connector", Subnets: []string{"10.233.0.0/16"}, NodeSubnets: []string{"10.40.20.181"}},
+			{Name: "shanghai.edge1", Subnets: []string{"10.234.2.0/24"}, NodeSubnets: []string{"192.168.1.10"}},
+		},
+	}
+	hangzhou := Cluster{
+		Name: "hangzhou",
+		Endpoints: []apisv1.Endpoint{
+			{Name: "hangzhou.connector", Subnets: []string{"10.234.0.0/16"}, NodeSubnets: []string{"10.50.20.181"}},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		clusters ClusterList
+		expected int
+	}{
+		{
+			name:     "no overlaps",
+			clusters: ClusterList{beijing, {Name: "guangzhou", Endpoints: []apisv1.Endpoint{{Name: "guangzhou.connector", Subnets: []string{"10.100.0.0/16"}}}}},
+			expected: 0,
+		},
+		{
+			name:     "overlaps are counted once",
+			clusters: ClusterList{beijing, shanghai},
+			expected: 1,
+		},
+		{
+			name:     "overlaps among three clusters",
+			clusters: ClusterList{beijing, shanghai, hangzhou},
+			expected: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clusters := make(ClusterList, len(tc.clusters))
+			copy(clusters, tc.clusters)
+			findOverlaps(clusters)
+
+			if got := countPodSubnetOverlaps(clusters); got != tc.expected {
+				t.Errorf("expected %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,412 @@
+package collect
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	apisv1 "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+
+	"github.com/fabedge/fabctl/pkg/about"
+	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
+)
+
+// Manifest describes the content of a support bundle
+type Manifest struct {
+	CreatedAt       time.Time `json:"createdAt"`
+	FabctlVersion   string    `json:"fabctlVersion"`
+	Namespace       string    `json:"namespace"`
+	Cluster         string    `json:"cluster,omitempty"`
+	LogsSince       string    `json:"logsSince,omitempty"`
+	IncludeCertInfo bool      `json:"includeCertInfo"`
+	Files           []string  `json:"files"`
+	Errors          []string  `json:"errors,omitempty"`
+}
+
+// CertificateInfo is the metadata of a certificate, private keys are never collected
+type CertificateInfo struct {
+	Key          string    `json:"key"`
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	SerialNumber string    `json:"serialNumber"`
+	IsCA         bool      `json:"isCA"`
+	NotBefore    time.Time `json:"notBefore"`
+	NotAfter     time.Time `json:"notAfter"`
+	DNSNames     []string  `json:"dnsNames,omitempty"`
+	IPAddresses  []string  `json:"ipAddresses,omitempty"`
+}
+
+func New(clientGetter types.ClientGetter) *cobra.Command {
+	var (
+		since           time.Duration
+		includeCertInfo bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "collect [filename] [flags]",
+		Short: "Collect diagnostic data of FabEdge into a tar.gz archive",
+		Long: `Collect diagnostic data of FabEdge into a tar.gz archive, including FabEdge workloads, communities,
+clusters, edge nodes, container logs and swanctl outputs. Data of secrets are redacted, you can use --include-cert-info
+to collect metadata of certificates, private keys are never collected.`,
+		Example: `
+fabctl collect
+fabctl collect fabedge.tar.gz --since 30m
+fabctl collect --include-cert-info
+`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			cli, err := clientGetter.GetClient()
+			util.CheckError(err)
+
+			now := time.Now()
+			filename := fmt.Sprintf("fabedge-diagnosis-%s.tar.gz", now.Format("20060102-150405"))
+			if len(args) > 0 {
+				filename = args[0]
+			}
+
+			file, err := os.Create(filename)
+			util.CheckError(err)
+			defer file.Close()
+
+			b := newBundle(file, strings.TrimSuffix(path.Base(filename), ".tar.gz"), now)
+			b.manifest.Namespace = cli.GetNamespace()
+			b.manifest.IncludeCertInfo = includeCertInfo
+			if since > 0 {
+				b.manifest.LogsSince = since.String()
+			}
+
+			c := collector{client: cli, bundle: b, since: since, includeCertInfo: includeCertInfo}
+			c.collect()
+
+			util.CheckError(b.close())
+
+			if len(b.manifest.Errors) > 0 {
+				fmt.Fprintf(os.Stderr, "%d errors occurred during collecting, check manifest.json for details\n", len(b.manifest.Errors))
+			}
+			fmt.Printf("Diagnostic data is written to %s\n", filename)
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.DurationVar(&since, "since", 0, "Only collect logs newer than a relative duration like 5s, 2m, or 3h. Defaults to all logs")
+	fs.BoolVar(&includeCertInfo, "include-cert-info", false, "Include metadata of certificates in secrets, private keys are never collected")
+
+	return cmd
+}
+
+const execTimeout = 30 * time.Second
+
+type collector struct {
+	client          *types.Client
+	bundle          *bundle
+	since           time.Duration
+	includeCertInfo bool
+}
+
+func (c collector) collect() {
+	ctx := context.Background()
+
+	c.collectWorkloads(ctx)
+	c.collectCustomResources(ctx)
+	c.collectEdgeNodes(ctx)
+	c.collectSecrets(ctx)
+	c.collectLogs(ctx)
+	c.collectSwanctl(ctx)
+}
+
+func (c collector) collectWorkloads(ctx context.Context) {
+	var deployments appsv1.DeploymentList
+	if err := c.client.List(ctx, &deployments, client.InNamespace(c.client.GetNamespace())); err != nil {
+		c.bundle.recordError("list deployments", err)
+	}
+	for i := range deployments.Items {
+		deploy := &deployments.Items[i]
+		c.bundle.addObject(path.Join("workloads", "deployments", deploy.Name+".yaml"), deploy)
+	}
+
+	var daemonSets appsv1.DaemonSetList
+	if err := c.client.List(ctx, &daemonSets, client.InNamespace(c.client.GetNamespace())); err != nil {
+		c.bundle.recordError("list daemonsets", err)
+	}
+	for i := range daemonSets.Items {
+		ds := &daemonSets.Items[i]
+		c.bundle.addObject(path.Join("workloads", "daemonsets", ds.Name+".yaml"), ds)
+	}
+}
+
+func (c collector) collectCustomResources(ctx context.Context) {
+	var communities apisv1.CommunityList
+	if err := c.client.List(ctx, &communities); err != nil {
+		c.bundle.recordError("list communities", err)
+	}
+	for i := range communities.Items {
+		community := &communities.Items[i]
+		c.bundle.addObject(path.Join("communities", community.Name+".yaml"), community)
+	}
+
+	var clusters apisv1.ClusterList
+	if err := c.client.List(ctx, &clusters); err != nil {
+		c.bundle.recordError("list clusters", err)
+	}
+	for i := range clusters.Items {
+		cluster := &clusters.Items[i]
+		// token of cluster is a credential, so it's removed
+		cluster.Spec.Token = redact([]byte(cluster.Spec.Token))
+		c.bundle.addObject(path.Join("clusters", cluster.Name+".yaml"), cluster)
+	}
+}
+
+func (c collector) collectEdgeNodes(ctx context.Context) {
+	cluster := types.NewCluster(c.client)
+	if err := cluster.ExtractArgumentsFromFabEdge(); err != nil {
+		c.bundle.recordError("get arguments of fabedge-operator", err)
+		return
+	}
+	c.bundle.manifest.Cluster = cluster.Name
+
+	nodes, err := c.client.ListNodes(ctx, cluster.EdgeLabels)
+	if err != nil {
+		c.bundle.recordError("list edge nodes", err)
+		return
+	}
+
+	for i := range nodes {
+		node := &nodes[i]
+		c.bundle.addObject(path.Join("nodes", node.Name+".yaml"), node)
+	}
+}
+
+func (c collector) collectSecrets(ctx context.Context) {
+	var secrets corev1.SecretList
+	if err := c.client.List(ctx, &secrets, client.InNamespace(c.client.GetNamespace())); err != nil {
+		c.bundle.recordError("list secrets", err)
+		return
+	}
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+
+		var certs []CertificateInfo
+		if c.includeCertInfo {
+			certs = extractCertificates(*secret)
+		}
+
+		// last-applied-configuration may contain data of secret
+		delete(secret.Annotations, corev1.LastAppliedConfigAnnotation)
+		for key, value := range secret.Data {
+			secret.Data[key] = []byte(redact(value))
+		}
+
+		c.bundle.addObject(path.Join("secrets", secret.Name+".yaml"), secret)
+		if len(certs) > 0 {
+			c.bundle.addJSON(path.Join("secrets", secret.Name+".certs.json"), certs)
+		}
+	}
+}
+
+func (c collector) collectLogs(ctx context.Context) {
+	var pods corev1.PodList
+	if err := c.client.List(ctx, &pods, client.InNamespace(c.client.GetNamespace())); err != nil {
+		c.bundle.recordError("list pods", err)
+		return
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		c.bundle.addObject(path.Join("pods", pod.Name+".yaml"), pod)
+
+		var containers []corev1.Container
+		containers = append(containers, pod.Spec.InitContainers...)
+		containers = append(containers, pod.Spec.Containers...)
+		for _, container := range containers {
+			logs, err := c.client.GetLogs(ctx, pod.Name, container.Name, c.since)
+			if err != nil {
+				c.bundle.recordError(fmt.Sprintf("get logs of %s/%s", pod.Name, container.Name), err)
+				continue
+			}
+			c.bundle.addFile(path.Join("logs", pod.Name, container.Name+".log"), logs)
+		}
+	}
+}
+
+func (c collector) collectSwanctl(ctx context.Context) {
+	agents, err := c.client.ListAgentPods(ctx)
+	if err != nil {
+		c.bundle.recordError("list agent pods", err)
+	}
+
+	connectors, err := c.client.ListConnectorPods(ctx)
+	if err != nil {
+		c.bundle.recordError("list connector pods", err)
+	}
+
+	for _, pod := range append(agents, connectors...) {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+
+		for _, flag := range []string{"--list-sa", "--list-conns", "--stats"} {
+			execCtx, cancel := context.WithTimeout(ctx, execTimeout)
+			result := c.client.ExecCapture(execCtx, pod.Name, "strongswan", []string{"swanctl", flag})
+			cancel()
+
+			if err = result.AsError(); err != nil {
+				c.bundle.recordError(fmt.Sprintf("execute swanctl %s in %s", flag, pod.Name), err)
+				continue
+			}
+			c.bundle.addFile(path.Join("swanctl", pod.Name, strings.TrimPrefix(flag, "--")+".txt"), []byte(result.Stdout))
+		}
+	}
+}
+
+func extractCertificates(secret corev1.Secret) []CertificateInfo {
+	var certs []CertificateInfo
+	for _, key := range []string{corev1.TLSCertKey, "ca.crt"} {
+		rest := secret.Data[key]
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				continue
+			}
+
+			var ips []string
+			for _, ip := range cert.IPAddresses {
+				ips = append(ips, ip.String())
+			}
+
+			certs = append(certs, CertificateInfo{
+				Key:          key,
+				Subject:      cert.Subject.String(),
+				Issuer:       cert.Issuer.String(),
+				SerialNumber: cert.SerialNumber.String(),
+				IsCA:         cert.IsCA,
+				NotBefore:    cert.NotBefore,
+				NotAfter:     cert.NotAfter,
+				DNSNames:     cert.DNSNames,
+				IPAddresses:  ips,
+			})
+		}
+	}
+
+	return certs
+}
+
+func redact(value []byte) string {
+	if len(value) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("REDACTED (%d bytes)", len(value))
+}
+
+// bundle writes files into a tar.gz archive under a root directory
+type bundle struct {
+	gw       *gzip.Writer
+	tw       *tar.Writer
+	root     string
+	modTime  time.Time
+	manifest Manifest
+}
+
+func newBundle(file io.Writer, root string, now time.Time) *bundle {
+	gw := gzip.NewWriter(file)
+
+	return &bundle{
+		gw:      gw,
+		tw:      tar.NewWriter(gw),
+		root:    root,
+		modTime: now,
+		manifest: Manifest{
+			CreatedAt:     now,
+			FabctlVersion: about.Version(),
+		},
+	}
+}
+
+func (b *bundle) addFile(name string, data []byte) {
+	err := b.tw.WriteHeader(&tar.Header{
+		Name:    path.Join(b.root, name),
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: b.modTime,
+	})
+	if err == nil {
+		_, err = b.tw.Write(data)
+	}
+
+	if err != nil {
+		b.recordError("write "+name, err)
+		return
+	}
+
+	b.manifest.Files = append(b.manifest.Files, name)
+}
+
+func (b *bundle) addObject(name string, obj client.Object) {
+	// objects fetched by client have no apiVersion and kind, set them for readability
+	if gvk, err := apiutil.GVKForObject(obj, scheme.Scheme); err == nil {
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+	}
+	obj.SetManagedFields(nil)
+
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		b.recordError("marshal "+name, err)
+		return
+	}
+
+	b.addFile(name, data)
+}
+
+func (b *bundle) addJSON(name string, obj interface{}) {
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		b.recordError("marshal "+name, err)
+		return
+	}
+
+	b.addFile(name, data)
+}
+
+func (b *bundle) recordError(action string, err error) {
+	msg := fmt.Sprintf("failed to %s: %s", action, err)
+	fmt.Fprintln(os.Stderr, msg)
+	b.manifest.Errors = append(b.manifest.Errors, msg)
+}
+
+func (b *bundle) close() error {
+	b.addJSON("manifest.json", b.manifest)
+
+	if err := b.tw.Close(); err != nil {
+		return err
+	}
+
+	return b.gw.Close()
+}
@@ -0,0 +1,107 @@
+package community
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/fabedge/fabctl/pkg/types"
+)
+
+func New(clientGetter types.ClientGetter) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "community",
+		Short: "Manage and validate Community resources",
+	}
+
+	cmd.AddCommand(newListCmd(clientGetter))
+	cmd.AddCommand(newGetCmd(clientGetter))
+	cmd.AddCommand(newCreateCmd(clientGetter))
+	cmd.AddCommand(newDeleteCmd(clientGetter))
+	cmd.AddCommand(newAddMemberCmd(clientGetter))
+	cmd.AddCommand(newRemoveMemberCmd(clientGetter))
+	cmd.AddCommand(newValidateCmd(clientGetter))
+	return cmd
+}
+
+// environment holds data to resolve and validate members of communities
+type environment struct {
+	client  *types.Client
+	cluster *types.Cluster
+
+	// names of endpoints in Cluster resources, local edge nodes and local connector
+	endpoints sets.String
+	// endpoint names of local edge nodes, sorted
+	edgeEndpoints []string
+	nodes         map[string]corev1.Node
+}
+
+func newEnvironment(cli *types.Client) (*environment, error) {
+	cluster := types.NewCluster(cli)
+	if err := cluster.ExtractArgumentsFromFabEdge(); err != nil {
+		return nil, err
+	}
+
+	if err := cluster.LoadCommunities(); err != nil {
+		return nil, err
+	}
+
+	var nodeList corev1.NodeList
+	if err := cli.List(context.Background(), &nodeList); err != nil {
+		return nil, err
+	}
+
+	clusters, err := cli.ListClusters(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	env := &environment{
+		client:    cli,
+		cluster:   cluster,
+		endpoints: sets.NewString(cluster.ConnectorName()),
+		nodes:     make(map[string]corev1.Node),
+	}
+
+	for _, c := range clusters {
+		for _, ep := range c.Spec.EndPoints {
+			env.endpoints.Insert(ep.Name)
+		}
+	}
+
+	for _, node := range nodeList.Items {
+		env.nodes[node.Name] = node
+		if cluster.IsEdgeNode(node) {
+			name := cluster.NewEndpoint(node).Name
+			env.endpoints.Insert(name)
+			env.edgeEndpoints = append(env.edgeEndpoints, name)
+		}
+	}
+	sort.Strings(env.edgeEndpoints)
+
+	return env, nil
+}
+
+// endpointName converts a member argument to an endpoint name, names of local nodes
+// and names without dots are prefixed with name of current cluster
+func (env *environment) endpointName(member string) string {
+	if _, ok := env.nodes[member]; ok || !strings.Contains(member, ".") {
+		return env.cluster.Name + "." + member
+	}
+
+	return member
+}
+
+// communityNames returns names of loaded communities in order
+func (env *environment) communityNames() []string {
+	names := sets.NewString()
+	for name := range env.cluster.Communities {
+		names.Insert(name)
+	}
+
+	return names.List()
+}
@@ -0,0 +1,105 @@
+package community
+
+import (
+	"io"
+
+	apisv1 "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/spf13/cobra"
+
+	"github.com/fabedge/fabctl/pkg/printer"
+	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
+)
+
+// Community is a Community resource with members which are not found in endpoints
+type Community struct {
+	Name           string   `json:"name"`
+	Members        []string `json:"members"`
+	UnknownMembers []string `json:"unknownMembers,omitempty"`
+}
+
+type Communities []Community
+
+func newListCmd(clientGetter types.ClientGetter) *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List communities",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			p, env := prepare(clientGetter)
+
+			communities := Communities{}
+			for _, name := range env.communityNames() {
+				communities = append(communities, env.newCommunity(env.cluster.Communities[name]))
+			}
+
+			util.CheckError(p.Print(communities))
+		},
+	}
+}
+
+func newGetCmd(clientGetter types.ClientGetter) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get name [name]...",
+		Short: "Show members of specified communities",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			p, env := prepare(clientGetter)
+
+			var communities Communities
+			for _, name := range args {
+				communities = append(communities, env.newCommunity(env.getCommunity(name)))
+			}
+
+			util.CheckError(p.Print(communities))
+		},
+	}
+}
+
+func (env *environment) newCommunity(community apisv1.Community) Community {
+	c := Community{
+		Name:    community.Name,
+		Members: community.Spec.Members,
+	}
+
+	for _, member := range community.Spec.Members {
+		if !env.endpoints.Has(member) {
+			c.UnknownMembers = append(c.UnknownMembers, member)
+		}
+	}
+
+	return c
+}
+
+func (communities Communities) Describe(w io.Writer) {
+	for _, c := range communities {
+		printer.Describe(w,
+			printer.KeyValue{Key: "Name", Value: c.Name},
+			printer.KeyValue{Key: "Members", Value: printer.Join(c.Members)},
+			printer.KeyValue{Key: "Unknown Members", Value: printer.Join(c.UnknownMembers)},
+		)
+	}
+}
+
+func (communities Communities) Header(wide bool) []string {
+	header := []string{"NAME", "MEMBERS"}
+	if wide {
+		header = append(header, "UNKNOWN-MEMBERS")
+	}
+
+	return header
+}
+
+func (communities Communities) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, c := range communities {
+		row := []string{c.Name, printer.Join(c.Members)}
+		if wide {
+			row = append(row, printer.Join(c.UnknownMembers))
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
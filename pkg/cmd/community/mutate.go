@@ -0,0 +1,334 @@
+package community
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	apisv1 "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/fabedge/fabctl/pkg/printer"
+	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
+)
+
+const (
+	ActionCreate       = "create"
+	ActionDelete       = "delete"
+	ActionAddMember    = "add-member"
+	ActionRemoveMember = "remove-member"
+)
+
+// PeerChange is the change of community peers of an endpoint caused by a mutation
+type PeerChange struct {
+	Endpoint string   `json:"endpoint"`
+	Added    []string `json:"added,omitempty"`
+	Removed  []string `json:"removed,omitempty"`
+}
+
+// Mutation is a change of a community, Members are members after the change
+type Mutation struct {
+	Action    string       `json:"action"`
+	Community string       `json:"community"`
+	Members   []string     `json:"members"`
+	Changes   []PeerChange `json:"changes"`
+	DryRun    bool         `json:"dryRun"`
+}
+
+func newCreateCmd(clientGetter types.ClientGetter) *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "create name member [member]... [flags]",
+		Short: "Create a community",
+		Long: `Create a community. Members can be endpoint names like clusterName.nodeName or names of nodes in current cluster,
+they must be local edge nodes or endpoints in Cluster resources. Peer changes of each endpoint are printed before applying.`,
+		Example: `
+fabctl community create beijing-edges edge1 edge2
+fabctl community create all-connectors beijing.connector shanghai.connector --dry-run
+`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			p, env := prepare(clientGetter)
+
+			name := args[0]
+			if _, ok := env.cluster.Communities[name]; ok {
+				util.Exitf("community %s already exists\n", name)
+			}
+
+			members, err := env.resolveMembers(nil, args[1:])
+			util.CheckError(err)
+
+			community := apisv1.Community{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec:       apisv1.CommunitySpec{Members: members},
+			}
+
+			run(p, env, ActionCreate, community, dryRun, func() error {
+				return env.client.Create(context.Background(), &community)
+			})
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only preview the changes")
+	return cmd
+}
+
+func newDeleteCmd(clientGetter types.ClientGetter) *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "delete name [flags]",
+		Short: "Delete a community",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			p, env := prepare(clientGetter)
+
+			community := env.getCommunity(args[0])
+			deleted := community
+			deleted.Spec.Members = nil
+
+			run(p, env, ActionDelete, deleted, dryRun, func() error {
+				return env.client.Delete(context.Background(), &community)
+			})
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only preview the changes")
+	return cmd
+}
+
+func newAddMemberCmd(clientGetter types.ClientGetter) *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "add-member name member [member]... [flags]",
+		Short: "Add members to a community",
+		Long: `Add members to a community. Members can be endpoint names like clusterName.nodeName or names of nodes in current cluster,
+they must be local edge nodes or endpoints in Cluster resources. Peer changes of each endpoint are printed before applying.`,
+		Example: `
+fabctl community add-member beijing-edges edge3
+fabctl community add-member beijing-edges shanghai.edge1 --dry-run
+`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			p, env := prepare(clientGetter)
+
+			community := env.getCommunity(args[0])
+			members, err := env.resolveMembers(community.Spec.Members, args[1:])
+			util.CheckError(err)
+
+			community.Spec.Members = append(community.Spec.Members, members...)
+			run(p, env, ActionAddMember, community, dryRun, func() error {
+				return env.client.Update(context.Background(), &community)
+			})
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only preview the changes")
+	return cmd
+}
+
+func newRemoveMemberCmd(clientGetter types.ClientGetter) *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "remove-member name member [member]... [flags]",
+		Short: "Remove members from a community",
+		Example: `
+fabctl community remove-member beijing-edges edge3
+fabctl community remove-member beijing-edges shanghai.edge1 --dry-run
+`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			p, env := prepare(clientGetter)
+
+			community := env.getCommunity(args[0])
+			current := sets.NewString(community.Spec.Members...)
+
+			removed := sets.NewString()
+			for _, arg := range args[1:] {
+				// members which are not found should be removable, so exact names are checked first
+				member := arg
+				if !current.Has(member) {
+					member = env.endpointName(arg)
+				}
+
+				if !current.Has(member) {
+					util.Exitf("%s is not a member of community %s\n", arg, community.Name)
+				}
+				removed.Insert(member)
+			}
+
+			var members []string
+			for _, member := range community.Spec.Members {
+				if !removed.Has(member) {
+					members = append(members, member)
+				}
+			}
+
+			community.Spec.Members = members
+			run(p, env, ActionRemoveMember, community, dryRun, func() error {
+				return env.client.Update(context.Background(), &community)
+			})
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only preview the changes")
+	return cmd
+}
+
+func prepare(clientGetter types.ClientGetter) (printer.Printer, *environment) {
+	p, err := clientGetter.GetPrinter()
+	util.CheckError(err)
+
+	cli, err := clientGetter.GetClient()
+	util.CheckError(err)
+
+	env, err := newEnvironment(cli)
+	util.CheckError(err)
+
+	return p, env
+}
+
+// run prints the mutation with peer changes, then applies it if it's not a dry run
+func run(p printer.Printer, env *environment, action string, community apisv1.Community, dryRun bool, apply func() error) {
+	mutation := Mutation{
+		Action:    action,
+		Community: community.Name,
+		Members:   community.Spec.Members,
+		Changes:   env.peerChanges(community),
+		DryRun:    dryRun,
+	}
+	util.CheckError(p.Print(mutation))
+
+	if dryRun {
+		return
+	}
+
+	util.CheckError(apply())
+	if !p.IsStructured() {
+		fmt.Printf("\ncommunity %s is changed by %s\n", community.Name, action)
+	}
+}
+
+func (env *environment) getCommunity(name string) apisv1.Community {
+	community, ok := env.cluster.Communities[name]
+	if !ok {
+		util.Exitf("community %s is not found\n", name)
+	}
+
+	return community
+}
+
+// resolveMembers converts args to endpoint names and checks if they exist and are not in current members
+func (env *environment) resolveMembers(current []string, args []string) ([]string, error) {
+	existing := sets.NewString(current...)
+
+	var members []string
+	for _, arg := range args {
+		member := env.endpointName(arg)
+
+		switch {
+		case !env.endpoints.Has(member):
+			return nil, fmt.Errorf("endpoint %s is not found, it should be a local edge node or an endpoint in Cluster resources", member)
+		case existing.Has(member):
+			return nil, fmt.Errorf("%s is already a member", member)
+		}
+
+		existing.Insert(member)
+		members = append(members, member)
+	}
+
+	return members, nil
+}
+
+// peerChanges compares community peers of each endpoint before and after community is changed
+func (env *environment) peerChanges(community apisv1.Community) []PeerChange {
+	before := make(map[string][]string)
+	after := make(map[string][]string)
+	for name, c := range env.cluster.Communities {
+		before[name] = c.Spec.Members
+		after[name] = c.Spec.Members
+	}
+
+	if len(community.Spec.Members) == 0 {
+		delete(after, community.Name)
+	} else {
+		after[community.Name] = community.Spec.Members
+	}
+
+	peersBefore, peersAfter := peersOf(before), peersOf(after)
+
+	endpoints := sets.NewString()
+	for name := range peersBefore {
+		endpoints.Insert(name)
+	}
+	for name := range peersAfter {
+		endpoints.Insert(name)
+	}
+
+	changes := []PeerChange{}
+	for _, name := range endpoints.List() {
+		change := PeerChange{
+			Endpoint: name,
+			Added:    peersAfter[name].Difference(peersBefore[name]).List(),
+			Removed:  peersBefore[name].Difference(peersAfter[name]).List(),
+		}
+
+		if len(change.Added) > 0 || len(change.Removed) > 0 {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes
+}
+
+// peersOf returns peers of each endpoint in communities
+func peersOf(communities map[string][]string) map[string]sets.String {
+	peers := make(map[string]sets.String)
+	for _, members := range communities {
+		for _, member := range members {
+			if peers[member] == nil {
+				peers[member] = sets.NewString()
+			}
+			peers[member].Insert(members...)
+			peers[member].Delete(member)
+		}
+	}
+
+	return peers
+}
+
+func (m Mutation) Describe(w io.Writer) {
+	printer.Describe(w,
+		printer.KeyValue{Key: "Action", Value: m.Action},
+		printer.KeyValue{Key: "Community", Value: m.Community},
+		printer.KeyValue{Key: "Members", Value: printer.Join(m.Members)},
+		printer.KeyValue{Key: "Dry Run", Value: fmt.Sprint(m.DryRun)},
+	)
+
+	fmt.Fprintln(w)
+	if len(m.Changes) == 0 {
+		fmt.Fprintln(w, "No peer changes")
+		return
+	}
+
+	printer.PrintTable(w, m, false)
+}
+
+func (m Mutation) Header(wide bool) []string {
+	return []string{"ENDPOINT", "ADDED-PEERS", "REMOVED-PEERS"}
+}
+
+func (m Mutation) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, change := range m.Changes {
+		rows = append(rows, []string{change.Endpoint, printer.Join(change.Added), printer.Join(change.Removed)})
+	}
+
+	return rows
+}
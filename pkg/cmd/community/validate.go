@@ -0,0 +1,256 @@
+package community
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/fabedge/fabctl/pkg/printer"
+	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+
+	CheckMalformedName   = "malformed-name"
+	CheckUnknownMember   = "unknown-member"
+	CheckNotEdge         = "not-edge"
+	CheckDuplicateMember = "duplicate-member"
+	CheckDuplicate       = "duplicate-community"
+	CheckNoCommunity     = "no-community"
+)
+
+// Issue is a problem found in Community resources, Target is a member, a node or names of communities
+type Issue struct {
+	Check     string `json:"check"`
+	Severity  string `json:"severity"`
+	Community string `json:"community,omitempty"`
+	Target    string `json:"target"`
+	Message   string `json:"message"`
+}
+
+type Issues []Issue
+
+// Count returns the number of issues with specified severity
+func (issues Issues) Count(severity string) int {
+	count := 0
+	for _, issue := range issues {
+		if issue.Severity == severity {
+			count++
+		}
+	}
+
+	return count
+}
+
+func newValidateCmd(clientGetter types.ClientGetter) *cobra.Command {
+	var strict bool
+
+	cmd := &cobra.Command{
+		Use:   "validate [flags]",
+		Short: "Validate Community resources against edge nodes and endpoints in Cluster resources",
+		Long: `Validate Community resources against edge nodes and endpoints in Cluster resources. It reports:
+  malformed member names which are not in format: clusterName.nodeName
+  members which don't resolve to any local edge node or any endpoint in Cluster resources
+  members whose nodes lack edge labels
+  members listed more than once in a community
+  communities with identical members
+  edge nodes which are in no community
+If any error is found, validate exits with non-zero code, use --strict to fail on warnings too.`,
+		Example: `
+fabctl community validate
+fabctl community validate -o json --strict
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			p, err := clientGetter.GetPrinter()
+			util.CheckError(err)
+
+			cli, err := clientGetter.GetClient()
+			util.CheckError(err)
+
+			issues, err := validate(cli)
+			util.CheckError(err)
+
+			util.CheckError(p.Print(issues))
+
+			failed := issues.Count(SeverityError)
+			if strict {
+				failed += issues.Count(SeverityWarning)
+			}
+
+			if failed > 0 {
+				util.Exitf("%d issues found\n", failed)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&strict, "strict", false, "Exit with non-zero code if any warning is found")
+	return cmd
+}
+
+func validate(cli *types.Client) (Issues, error) {
+	env, err := newEnvironment(cli)
+	if err != nil {
+		return nil, err
+	}
+	cluster := env.cluster
+
+	issues := Issues{}
+	names := env.communityNames()
+	for _, name := range names {
+		members := sets.NewString()
+		for _, member := range cluster.Communities[name].Spec.Members {
+			if members.Has(member) {
+				issues = append(issues, Issue{
+					Check:     CheckDuplicateMember,
+					Severity:  SeverityWarning,
+					Community: name,
+					Target:    member,
+					Message:   "member is listed more than once",
+				})
+				continue
+			}
+			members.Insert(member)
+
+			clusterName, nodeName, ok := parseEndpointName(member)
+			switch {
+			case !ok:
+				issues = append(issues, Issue{
+					Check:     CheckMalformedName,
+					Severity:  SeverityError,
+					Community: name,
+					Target:    member,
+					Message:   "member name should be in format: clusterName.nodeName",
+				})
+			case env.endpoints.Has(member):
+			case clusterName == cluster.Name && env.nodes[nodeName].Name != "":
+				issues = append(issues, Issue{
+					Check:     CheckNotEdge,
+					Severity:  SeverityError,
+					Community: name,
+					Target:    member,
+					Message:   fmt.Sprintf("node %s has no edge labels: %s", nodeName, formatLabels(cluster.EdgeLabels)),
+				})
+			default:
+				issues = append(issues, Issue{
+					Check:     CheckUnknownMember,
+					Severity:  SeverityError,
+					Community: name,
+					Target:    member,
+					Message:   "member is neither a local edge node nor an endpoint in Cluster resources",
+				})
+			}
+		}
+	}
+
+	issues = append(issues, checkDuplicateCommunities(cluster, names)...)
+
+	for _, name := range env.edgeEndpoints {
+		if len(cluster.EdgeToCommunities[name]) == 0 {
+			issues = append(issues, Issue{
+				Check:    CheckNoCommunity,
+				Severity: SeverityWarning,
+				Target:   name,
+				Message:  "edge node is in no community, it can only communicate with connector",
+			})
+		}
+	}
+
+	return issues, nil
+}
+
+// checkDuplicateCommunities finds communities with identical members, the order of members doesn't matter
+func checkDuplicateCommunities(cluster *types.Cluster, names []string) Issues {
+	groups := make(map[string][]string)
+	var keys []string
+	for _, name := range names {
+		key := strings.Join(sets.NewString(cluster.Communities[name].Spec.Members...).List(), ",")
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], name)
+	}
+
+	var issues Issues
+	for _, key := range keys {
+		if len(groups[key]) < 2 {
+			continue
+		}
+
+		issues = append(issues, Issue{
+			Check:    CheckDuplicate,
+			Severity: SeverityWarning,
+			Target:   strings.Join(groups[key], ","),
+			Message:  "communities have identical members",
+		})
+	}
+
+	return issues
+}
+
+// parseEndpointName splits an endpoint name into cluster name and node name, node name may contain dots
+func parseEndpointName(name string) (string, string, bool) {
+	index := strings.Index(name, ".")
+	if index <= 0 || index == len(name)-1 {
+		return "", "", false
+	}
+
+	return name[:index], name[index+1:], true
+}
+
+func formatLabels(labels map[string]string) string {
+	var pairs []string
+	for key, value := range labels {
+		if value == "" {
+			pairs = append(pairs, key)
+		} else {
+			pairs = append(pairs, key+"="+value)
+		}
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+func (issues Issues) Describe(w io.Writer) {
+	if len(issues) == 0 {
+		fmt.Fprintln(w, "No issues found")
+		return
+	}
+
+	for _, issue := range issues {
+		kvs := []printer.KeyValue{
+			{Key: "Check", Value: issue.Check},
+			{Key: "Severity", Value: issue.Severity},
+		}
+		if issue.Community != "" {
+			kvs = append(kvs, printer.KeyValue{Key: "Community", Value: issue.Community})
+		}
+		kvs = append(kvs,
+			printer.KeyValue{Key: "Target", Value: issue.Target},
+			printer.KeyValue{Key: "Message", Value: issue.Message},
+		)
+
+		printer.Describe(w, kvs...)
+	}
+}
+
+func (issues Issues) Header(wide bool) []string {
+	return []string{"CHECK", "SEVERITY", "COMMUNITY", "TARGET", "MESSAGE"}
+}
+
+func (issues Issues) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, issue := range issues {
+		rows = append(rows, []string{issue.Check, issue.Severity, issue.Community, issue.Target, issue.Message})
+	}
+
+	return rows
+}
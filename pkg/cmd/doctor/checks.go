@@ -0,0 +1,360 @@
+package doctor
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	certutil "github.com/fabedge/fabedge/pkg/util/cert"
+	secretutil "github.com/fabedge/fabedge/pkg/util/secret"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fabedge/fabctl/pkg/strongswan"
+	"github.com/fabedge/fabctl/pkg/types"
+)
+
+const execTimeout = 30 * time.Second
+
+func init() {
+	Register("workloads", "FabEdge workloads are deployed and ready", checkWorkloads)
+	Register("agents", "every edge node has a running fabedge-agent pod", checkAgents)
+	Register("certificates", "TLS secrets are signed by the CA", checkCertificates)
+	Register("tunnels", "IKE SAs are established between expected peers", checkTunnels)
+	Register("communities", "community members reference existing endpoints", checkCommunities)
+}
+
+func checkWorkloads(env *Environment) []Result {
+	ctx := context.Background()
+
+	var results []Result
+	for _, w := range []struct {
+		name     string
+		kind     string
+		required bool
+	}{
+		{"fabedge-operator", "deployment", true},
+		{"fabedge-connector", "deployment", true},
+		{"fabedge-cloud-agent", "daemonset", false},
+		{"service-hub", "deployment", false},
+		{"fabdns", "deployment", false},
+	} {
+		var (
+			desired, ready int32
+			err            error
+		)
+
+		switch w.kind {
+		case "deployment":
+			var deploy appsv1.Deployment
+			deploy, err = env.Client.GetDeployment(ctx, w.name)
+			desired, ready = 1, deploy.Status.ReadyReplicas
+			if deploy.Spec.Replicas != nil {
+				desired = *deploy.Spec.Replicas
+			}
+		case "daemonset":
+			var ds appsv1.DaemonSet
+			ds, err = env.Client.GetDaemonSet(ctx, w.name)
+			desired, ready = ds.Status.DesiredNumberScheduled, ds.Status.NumberReady
+		}
+
+		target := fmt.Sprintf("%s/%s", w.kind, w.name)
+		switch {
+		case errors.IsNotFound(err) && !w.required:
+			results = append(results, Result{
+				Target:      target,
+				Status:      StatusWarn,
+				Message:     "not found",
+				Remediation: fmt.Sprintf("ignore it if %s is not enabled, otherwise check your FabEdge installation", w.name),
+			})
+		case err != nil:
+			results = append(results, Result{
+				Target:      target,
+				Status:      StatusFail,
+				Message:     err.Error(),
+				Remediation: "check your FabEdge installation",
+			})
+		case ready < desired:
+			results = append(results, Result{
+				Target:      target,
+				Status:      StatusFail,
+				Message:     fmt.Sprintf("%d/%d ready", ready, desired),
+				Remediation: fmt.Sprintf("kubectl -n %s describe %s %s", env.Client.GetNamespace(), w.kind, w.name),
+			})
+		default:
+			results = append(results, Result{
+				Target:  target,
+				Status:  StatusPass,
+				Message: fmt.Sprintf("%d/%d ready", ready, desired),
+			})
+		}
+	}
+
+	return results
+}
+
+func checkAgents(env *Environment) []Result {
+	pods, err := listAgentPods(env.Client)
+	if err != nil {
+		return []Result{{Target: "fabedge-agent", Status: StatusFail, Message: err.Error()}}
+	}
+
+	var results []Result
+	for _, node := range env.EdgeNodes {
+		pod, ok := pods[node.Name]
+		switch {
+		case !ok:
+			results = append(results, Result{
+				Target:      node.Name,
+				Status:      StatusFail,
+				Message:     "no agent pod",
+				Remediation: "check logs of fabedge-operator, make sure the node is ready and has edge labels",
+			})
+		case pod.Status.Phase != corev1.PodRunning:
+			results = append(results, Result{
+				Target:      node.Name,
+				Status:      StatusFail,
+				Message:     fmt.Sprintf("agent pod %s is %s", pod.Name, pod.Status.Phase),
+				Remediation: fmt.Sprintf("kubectl -n %s describe pod %s", pod.Namespace, pod.Name),
+			})
+		default:
+			results = append(results, Result{
+				Target:  node.Name,
+				Status:  StatusPass,
+				Message: fmt.Sprintf("agent pod %s is running", pod.Name),
+			})
+		}
+	}
+
+	if len(results) == 0 {
+		results = append(results, Result{
+			Target:      "edge nodes",
+			Status:      StatusWarn,
+			Message:     "no edge nodes found",
+			Remediation: fmt.Sprintf("make sure edge nodes have labels: %s", labels.SelectorFromSet(env.Cluster.EdgeLabels)),
+		})
+	}
+
+	return results
+}
+
+func checkCertificates(env *Environment) []Result {
+	var caSecret corev1.Secret
+	err := env.Client.Get(context.Background(), types.ObjectKey{Name: env.CASecret, Namespace: env.Client.GetNamespace()}, &caSecret)
+	if err != nil {
+		return []Result{{
+			Target:      "secret/" + env.CASecret,
+			Status:      StatusFail,
+			Message:     err.Error(),
+			Remediation: "specify CA secret by --ca-secret",
+		}}
+	}
+
+	// CA secret may be created by fabctl with ca.crt field or by others with tls.crt field
+	caDER, err := decodeCertFromSecret(caSecret, secretutil.KeyCACert)
+	if err != nil {
+		caDER, err = decodeCertFromSecret(caSecret, corev1.TLSCertKey)
+	}
+	if err != nil {
+		return []Result{{Target: "secret/" + env.CASecret, Status: StatusFail, Message: err.Error()}}
+	}
+
+	selector, err := labels.Parse(env.CertSelector)
+	if err != nil {
+		return []Result{{Target: env.CertSelector, Status: StatusFail, Message: err.Error()}}
+	}
+
+	var secrets corev1.SecretList
+	err = env.Client.List(context.Background(), &secrets,
+		client.InNamespace(env.Client.GetNamespace()),
+		client.MatchingLabelsSelector{Selector: selector})
+	if err != nil {
+		return []Result{{Target: env.CertSelector, Status: StatusFail, Message: err.Error()}}
+	}
+
+	usages := []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}
+	var results []Result
+	for _, secret := range secrets.Items {
+		target := "secret/" + secret.Name
+
+		certDER, err := decodeCertFromSecret(secret, corev1.TLSCertKey)
+		if err == nil {
+			err = certutil.VerifyCert(caDER, certDER, usages)
+		}
+
+		if err != nil {
+			results = append(results, Result{
+				Target:      target,
+				Status:      StatusFail,
+				Message:     err.Error(),
+				Remediation: "delete the secret and restart fabedge-operator to recreate it, or renew it with fabctl cert gen",
+			})
+		} else {
+			results = append(results, Result{Target: target, Status: StatusPass, Message: "valid"})
+		}
+	}
+
+	return results
+}
+
+func decodeCertFromSecret(secret corev1.Secret, key string) ([]byte, error) {
+	block, _ := pem.Decode(secret.Data[key])
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode %s of secret %s", key, secret.Name)
+	}
+
+	return block.Bytes, nil
+}
+
+func checkTunnels(env *Environment) []Result {
+	cluster := env.Cluster
+
+	agentPods, err := listAgentPods(env.Client)
+	if err != nil {
+		return []Result{{Target: "fabedge-agent", Status: StatusFail, Message: err.Error()}}
+	}
+
+	var results []Result
+	for _, node := range env.EdgeNodes {
+		ep := cluster.NewEndpoint(node)
+
+		pod, ok := agentPods[node.Name]
+		if !ok || pod.Status.Phase != corev1.PodRunning {
+			// missing agents are reported by agents check
+			continue
+		}
+
+		results = append(results, checkPodTunnels(env, pod, cluster.EdgePeers(ep.Name))...)
+	}
+
+	connectorPeers := cluster.ConnectorPeers(env.EdgeNodes)
+	connectors, err := env.Client.ListConnectorPods(context.Background())
+	if err != nil {
+		return append(results, Result{Target: "fabedge-connector", Status: StatusFail, Message: err.Error()})
+	}
+
+	for _, pod := range connectors {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		results = append(results, checkPodTunnels(env, pod, connectorPeers)...)
+	}
+
+	return results
+}
+
+func checkPodTunnels(env *Environment, pod corev1.Pod, peers sets.String) []Result {
+	ctx, cancel := context.WithTimeout(context.Background(), execTimeout)
+	defer cancel()
+
+	result := env.Client.ExecCapture(ctx, pod.Name, "strongswan", []string{"swanctl", "--list-sa", "--pretty"})
+	if err := result.AsError(); err != nil {
+		return []Result{{
+			Target:      pod.Name,
+			Status:      StatusFail,
+			Message:     fmt.Sprintf("failed to list SAs: %s", err),
+			Remediation: fmt.Sprintf("kubectl -n %s logs %s -c strongswan", pod.Namespace, pod.Name),
+		}}
+	}
+
+	sas, err := strongswan.ParseSAs(result.Stdout)
+	if err != nil {
+		return []Result{{Target: pod.Name, Status: StatusFail, Message: fmt.Sprintf("failed to parse SAs: %s", err)}}
+	}
+
+	states := make(map[string]string)
+	for _, sa := range sas {
+		// keep ESTABLISHED state if there are duplicated SAs
+		if states[sa.Name] != strongswan.IKEStateEstablished {
+			states[sa.Name] = sa.State
+		}
+	}
+
+	var results []Result
+	for _, peer := range peers.List() {
+		target := fmt.Sprintf("%s -> %s", pod.Name, peer)
+		state, ok := states[peer]
+		switch {
+		case !ok:
+			results = append(results, Result{
+				Target:      target,
+				Status:      StatusFail,
+				Message:     "no IKE SA",
+				Remediation: fmt.Sprintf("fabctl swanctl list-conns %s; check logs of %s", pod.Name, pod.Name),
+			})
+		case state != strongswan.IKEStateEstablished:
+			results = append(results, Result{
+				Target:      target,
+				Status:      StatusWarn,
+				Message:     state,
+				Remediation: fmt.Sprintf("fabctl swanctl initiate %s --ike %s", pod.Name, peer),
+			})
+		default:
+			results = append(results, Result{Target: target, Status: StatusPass, Message: state})
+		}
+	}
+
+	return results
+}
+
+func checkCommunities(env *Environment) []Result {
+	var results []Result
+	for _, name := range sortedCommunityNames(env.Cluster) {
+		community := env.Cluster.Communities[name]
+
+		var missing []string
+		for _, member := range community.Spec.Members {
+			if _, ok := env.Endpoints[member]; !ok {
+				missing = append(missing, member)
+			}
+		}
+
+		if len(missing) > 0 {
+			results = append(results, Result{
+				Target:      "community/" + name,
+				Status:      StatusFail,
+				Message:     fmt.Sprintf("unknown members: %s", strings.Join(missing, ",")),
+				Remediation: "fix member names, they should be in format: clusterName.nodeName",
+			})
+		} else {
+			results = append(results, Result{
+				Target:  "community/" + name,
+				Status:  StatusPass,
+				Message: fmt.Sprintf("%d members", len(community.Spec.Members)),
+			})
+		}
+	}
+
+	return results
+}
+
+func sortedCommunityNames(cluster *types.Cluster) []string {
+	names := sets.NewString()
+	for name := range cluster.Communities {
+		names.Insert(name)
+	}
+
+	return names.List()
+}
+
+// listAgentPods returns agent pods indexed by node name
+func listAgentPods(cli *types.Client) (map[string]corev1.Pod, error) {
+	agents, err := cli.ListAgentPods(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	pods := make(map[string]corev1.Pod)
+	for _, pod := range agents {
+		pods[pod.Spec.NodeName] = pod
+	}
+
+	return pods, nil
+}
@@ -0,0 +1,246 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	apisv1 "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/fabedge/fabctl/pkg/printer"
+	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
+)
+
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// Result is the outcome of a check against a target, e.g. a workload, a node or a secret
+type Result struct {
+	Check       string `json:"check"`
+	Target      string `json:"target"`
+	Status      Status `json:"status"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// CheckFunc inspects the environment and returns results of each target it checks
+type CheckFunc func(env *Environment) []Result
+
+type check struct {
+	name        string
+	description string
+	fn          CheckFunc
+}
+
+var registry []check
+
+// Register adds a check to doctor, checks are executed in the order of registration
+func Register(name, description string, fn CheckFunc) {
+	for _, c := range registry {
+		if c.name == name {
+			panic(fmt.Sprintf("check %s is already registered", name))
+		}
+	}
+
+	registry = append(registry, check{name: name, description: description, fn: fn})
+}
+
+// Environment holds data shared by checks
+type Environment struct {
+	Client  *types.Client
+	Cluster *types.Cluster
+
+	EdgeNodes []corev1.Node
+	// Endpoints contains local edge endpoints and endpoints from Cluster resources, indexed by name
+	Endpoints map[string]apisv1.Endpoint
+
+	CASecret     string
+	CertSelector string
+}
+
+func New(clientGetter types.ClientGetter) *cobra.Command {
+	var (
+		checkNames   []string
+		caSecret     string
+		certSelector string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "doctor [flags]",
+		Short: "Diagnose the health of FabEdge deployment",
+		Long:  "Diagnose the health of FabEdge deployment. If any check fails, doctor exits with non-zero code.",
+		Example: `
+fabctl doctor
+fabctl doctor --checks workloads,agents
+fabctl doctor -o json
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			p, err := clientGetter.GetPrinter()
+			util.CheckError(err)
+
+			selected := sets.NewString(checkNames...)
+			for _, name := range selected.List() {
+				if !isRegistered(name) {
+					util.Exitf("unknown check: %s, possible values: %s\n", name, strings.Join(checkNamesOfRegistry(), ","))
+				}
+			}
+
+			cli, err := clientGetter.GetClient()
+			util.CheckError(err)
+
+			env, err := newEnvironment(cli, caSecret, certSelector)
+			util.CheckError(err)
+
+			var results Results
+			for _, c := range registry {
+				if selected.Len() > 0 && !selected.Has(c.name) {
+					continue
+				}
+
+				for _, r := range c.fn(env) {
+					r.Check = c.name
+					results = append(results, r)
+				}
+			}
+
+			util.CheckError(p.Print(results))
+
+			if failed := results.Count(StatusFail); failed > 0 {
+				util.Exitf("%d checks failed\n", failed)
+			}
+		},
+	}
+
+	var descriptions []string
+	for _, c := range registry {
+		descriptions = append(descriptions, fmt.Sprintf("  %s: %s", c.name, c.description))
+	}
+	cmd.Long = fmt.Sprintf("%s\n\nAvailable checks:\n%s", cmd.Long, strings.Join(descriptions, "\n"))
+
+	fs := cmd.Flags()
+	fs.StringSliceVar(&checkNames, "checks", nil, "The checks to run, all checks are run if not specified")
+	fs.StringVar(&caSecret, "ca-secret", "fabedge-ca", "The name of CA secret used to verify certificates")
+	fs.StringVarP(&certSelector, "selector", "l", "fabedge.io/created-by=fabedge-operator", "Selector (label query) to find TLS secrets to verify")
+
+	return cmd
+}
+
+func newEnvironment(cli *types.Client, caSecret, certSelector string) (*Environment, error) {
+	cluster := types.NewCluster(cli)
+	if err := cluster.ExtractArgumentsFromFabEdge(); err != nil {
+		return nil, err
+	}
+
+	if err := cluster.LoadCommunities(); err != nil {
+		return nil, err
+	}
+
+	edgeNodes, err := cli.ListNodes(context.Background(), cluster.EdgeLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters, err := cli.ListClusters(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make(map[string]apisv1.Endpoint)
+	for _, c := range clusters {
+		for _, ep := range c.Spec.EndPoints {
+			endpoints[ep.Name] = ep
+		}
+	}
+
+	for _, node := range edgeNodes {
+		ep := cluster.NewEndpoint(node)
+		endpoints[ep.Name] = ep
+	}
+
+	return &Environment{
+		Client:       cli,
+		Cluster:      cluster,
+		EdgeNodes:    edgeNodes,
+		Endpoints:    endpoints,
+		CASecret:     caSecret,
+		CertSelector: certSelector,
+	}, nil
+}
+
+func isRegistered(name string) bool {
+	for _, c := range registry {
+		if c.name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func checkNamesOfRegistry() []string {
+	var names []string
+	for _, c := range registry {
+		names = append(names, c.name)
+	}
+
+	return names
+}
+
+type Results []Result
+
+func (results Results) Count(status Status) int {
+	count := 0
+	for _, r := range results {
+		if r.Status == status {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (results Results) Describe(w io.Writer) {
+	printer.PrintTable(w, results, false)
+
+	fmt.Fprintln(w)
+	for _, r := range results {
+		if r.Status != StatusPass && r.Remediation != "" {
+			fmt.Fprintf(w, "[%s] %s %s: %s\n", r.Status, r.Check, r.Target, r.Remediation)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d passed, %d warnings, %d failed\n",
+		results.Count(StatusPass), results.Count(StatusWarn), results.Count(StatusFail))
+}
+
+func (results Results) Header(wide bool) []string {
+	header := []string{"CHECK", "TARGET", "STATUS", "MESSAGE"}
+	if wide {
+		header = append(header, "REMEDIATION")
+	}
+
+	return header
+}
+
+func (results Results) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, r := range results {
+		row := []string{r.Check, r.Target, strings.ToUpper(string(r.Status)), r.Message}
+		if wide {
+			row = append(row, r.Remediation)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
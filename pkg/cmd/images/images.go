@@ -3,10 +3,12 @@ package images
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/spf13/cobra"
 
+	"github.com/fabedge/fabctl/pkg/printer"
 	"github.com/fabedge/fabctl/pkg/types"
 	"github.com/fabedge/fabctl/pkg/util"
 )
@@ -14,41 +16,37 @@ import (
 type Images struct {
 	client *types.Client
 
-	Operator            string
-	Agent               string
-	AgentStrongSwan     string
-	Connector           string
-	ConnectorStrongSwan string
-	CloudAgent          string
-	ServiceHub          string
-	FabDNS              string
+	Operator            string `json:"operator"`
+	Agent               string `json:"agent"`
+	AgentStrongSwan     string `json:"agentStrongSwan"`
+	Connector           string `json:"connector"`
+	ConnectorStrongSwan string `json:"connectorStrongSwan"`
+	CloudAgent          string `json:"cloudAgent"`
+	ServiceHub          string `json:"serviceHub"`
+	FabDNS              string `json:"fabDNS"`
 }
 
 func New(clientGetter types.ClientGetter) *cobra.Command {
 	return &cobra.Command{
-		Use:   "images",
-		Short: "Show images of FabEdge and FabDNS",
+		Use:         "images",
+		Short:       "Show images of FabEdge and FabDNS",
+		Annotations: types.MultiContexts(),
 		Run: func(cmd *cobra.Command, args []string) {
-			cli, err := clientGetter.GetClient()
+			p, err := clientGetter.GetPrinter()
 			util.CheckError(err)
 
-			images := Images{client: cli}
-			images.extractImages()
-
-			fmt.Printf(`
-Operator:                 %s
-Agent:                    %s
-AgentStrongSwan:          %s
-Connector:                %s
-ConnectorStrongSwan:      %s
-CloudAgent:               %s
-ServiceHub:               %s
-FabDNS:                   %s
-`,
-				images.Operator, images.Agent, images.AgentStrongSwan,
-				images.Connector, images.ConnectorStrongSwan, images.CloudAgent,
-				images.ServiceHub, images.FabDNS,
-			)
+			err = types.PrintInContexts(clientGetter, p, func(getter types.ClientGetter) (interface{}, error) {
+				cli, err := getter.GetClient()
+				if err != nil {
+					return nil, err
+				}
+
+				images := Images{client: cli}
+				images.extractImages()
+
+				return images, nil
+			})
+			util.CheckError(err)
 		},
 	}
 }
@@ -86,6 +84,56 @@ func (images *Images) extractImages() {
 	})
 }
 
+func (images Images) Describe(w io.Writer) {
+	var kvs []printer.KeyValue
+	for _, c := range images.components() {
+		kvs = append(kvs, printer.KeyValue{Key: c.name, Value: c.image})
+	}
+
+	printer.Describe(w, kvs...)
+}
+
+func (images Images) Header(wide bool) []string {
+	if wide {
+		return []string{"COMPONENT", "IMAGE", "SOURCE"}
+	}
+
+	return []string{"COMPONENT", "IMAGE"}
+}
+
+func (images Images) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, c := range images.components() {
+		row := []string{c.name, c.image}
+		if wide {
+			row = append(row, c.source)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+type component struct {
+	name  string
+	image string
+	// where the image is read from
+	source string
+}
+
+func (images Images) components() []component {
+	return []component{
+		{"Operator", images.Operator, "deployment/fabedge-operator"},
+		{"Agent", images.Agent, "deployment/fabedge-operator --agent-image"},
+		{"AgentStrongSwan", images.AgentStrongSwan, "deployment/fabedge-operator --agent-strongswan-image"},
+		{"Connector", images.Connector, "deployment/fabedge-connector"},
+		{"ConnectorStrongSwan", images.ConnectorStrongSwan, "deployment/fabedge-connector"},
+		{"CloudAgent", images.CloudAgent, "daemonset/fabedge-cloud-agent"},
+		{"ServiceHub", images.ServiceHub, "deployment/service-hub"},
+		{"FabDNS", images.FabDNS, "deployment/fabdns"},
+	}
+}
+
 func doIfNoError(err error, fn func()) {
 	if err == nil {
 		fn()
@@ -0,0 +1,291 @@
+package mtu
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/fabedge/fabctl/pkg/cmd/nettool"
+	"github.com/fabedge/fabctl/pkg/printer"
+	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
+)
+
+const (
+	// sizes of IP header and ICMP header
+	ipv4Overhead = 20 + 8
+	ipv6Overhead = 40 + 8
+
+	// payload size used to check if destination is reachable at all
+	minPayloadSize = 56
+)
+
+var devRegexp = regexp.MustCompile(`\bdev (\S+)`)
+
+type options struct {
+	image          string
+	prepareTimeout time.Duration
+	keepPods       bool
+	hostNetwork    bool
+	count          uint
+	wait           uint
+}
+
+// Result is the path MTU from a net-tool pod to another
+type Result struct {
+	From         string `json:"from"`
+	FromIP       string `json:"fromIP"`
+	To           string `json:"to"`
+	ToIP         string `json:"toIP"`
+	Interface    string `json:"interface"`
+	InterfaceMTU int    `json:"interfaceMTU"`
+	PathMTU      int    `json:"pathMTU"`
+	Error        string `json:"error,omitempty"`
+}
+
+// Warning returns a message if path MTU is less than MTU of interface configured by CNI,
+// packets bigger than path MTU with DF bit will be dropped
+func (r Result) Warning() string {
+	if r.Error != "" || r.PathMTU == 0 || r.PathMTU >= r.InterfaceMTU {
+		return ""
+	}
+
+	return fmt.Sprintf("path MTU %d is less than MTU %d of %s, large packets may be dropped, consider setting MTU of CNI to %d or less",
+		r.PathMTU, r.InterfaceMTU, r.Interface, r.PathMTU)
+}
+
+type Results []Result
+
+func New(clientGetter types.ClientGetter) *cobra.Command {
+	opts := &options{}
+
+	cmd := &cobra.Command{
+		Use:   "mtu nodeName nodeName [flags]",
+		Short: "Probe path MTU between two nodes",
+		Long: `Probe path MTU between two nodes. Pings with DF bit set are sent between net-tool pods in both directions,
+the payload size is searched in binary to find the effective path MTU, which is compared with MTU of the interface in net-tool pod.`,
+		Example: `
+fabctl mtu edge1 edge2
+fabctl mtu edge1 edge2 --host
+`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			p, err := clientGetter.GetPrinter()
+			util.CheckError(err)
+
+			client, err := clientGetter.GetClient()
+			util.CheckError(err)
+
+			var pods []corev1.Pod
+			deletePods := func() {
+				if opts.keepPods {
+					return
+				}
+
+				ctx, cancel := context.WithTimeout(context.Background(), opts.prepareTimeout)
+				defer cancel()
+
+				for _, pod := range pods {
+					nettool.DeletePod(ctx, client, pod)
+				}
+			}
+
+			// prepare net tool pods, pods prepared before an error are deleted
+			err = func() error {
+				ctx, cancel := context.WithTimeout(context.Background(), opts.prepareTimeout)
+				defer cancel()
+
+				for _, nodeName := range args {
+					pod, err := nettool.GetOrCreatePod(ctx, client, nodeName, opts.image, opts.hostNetwork, opts.prepareTimeout)
+					if pod.Name != "" {
+						pods = append(pods, pod)
+					}
+					if err != nil {
+						return err
+					}
+				}
+
+				return nil
+			}()
+			if err != nil {
+				deletePods()
+				util.CheckError(err)
+			}
+
+			results := make(Results, 2)
+			util.Parallel(2, 2, func(i int) {
+				results[i] = probe(client, pods[i], pods[1-i], opts)
+				results[i].From, results[i].To = args[i], args[1-i]
+			})
+
+			deletePods()
+
+			util.CheckError(p.Print(results))
+
+			for _, r := range results {
+				if r.Error != "" {
+					util.Exitf("failed to probe path MTU from %s to %s\n", r.From, r.To)
+				}
+			}
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.StringVarP(&opts.image, "net-tool-image", "i", "praqma/network-multitool:minimal", "The image of net-tool pod")
+	fs.DurationVar(&opts.prepareTimeout, "prepare-timeout", 30*time.Second, "The length of time to prepare net-tool pods")
+	fs.BoolVarP(&opts.keepPods, "keep", "k", false, "keep pods after test if finished")
+	fs.BoolVar(&opts.hostNetwork, "host", false, "Use host-network net-tool pods to probe path MTU between nodes")
+	fs.UintVar(&opts.count, "count", 2, "The count of packets sent for each payload size")
+	fs.UintVar(&opts.wait, "wait", 1, "The seconds to wait for each reply")
+
+	return cmd
+}
+
+// probe finds the biggest packet which can be sent from pod1 to pod2 with DF bit set
+func probe(client *types.Client, pod1, pod2 corev1.Pod, opts *options) Result {
+	result := Result{FromIP: pod1.Status.PodIP, ToIP: pod2.Status.PodIP}
+
+	dev, mtu, err := getInterfaceMTU(client, pod1, result.ToIP)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Interface, result.InterfaceMTU = dev, mtu
+
+	overhead := ipv4Overhead
+	if ip := net.ParseIP(result.ToIP); ip != nil && ip.To4() == nil {
+		overhead = ipv6Overhead
+	}
+
+	ok, err := pingWithDF(client, pod1, result.ToIP, minPayloadSize, opts)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if !ok {
+		result.Error = fmt.Sprintf("%s is unreachable even with %d bytes payload", result.ToIP, minPayloadSize)
+		return result
+	}
+
+	// low is the biggest payload size known to pass and high is the smallest one known to fail
+	low, high := minPayloadSize, mtu-overhead+1
+	for low+1 < high {
+		size := (low + high) / 2
+		ok, err = pingWithDF(client, pod1, result.ToIP, size, opts)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+
+		if ok {
+			low = size
+		} else {
+			high = size
+		}
+	}
+	result.PathMTU = low + overhead
+
+	return result
+}
+
+// getInterfaceMTU returns name and MTU of the interface which is used to send packets to ip
+func getInterfaceMTU(client *types.Client, pod corev1.Pod, ip string) (string, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result := client.ExecCapture(ctx, pod.Name, nettool.ContainerName, []string{"ip", "-o", "route", "get", ip})
+	if err := result.AsError(); err != nil {
+		return "", 0, err
+	}
+
+	matches := devRegexp.FindStringSubmatch(result.Stdout)
+	if matches == nil {
+		return "", 0, fmt.Errorf("no device found in route: %s", strings.TrimSpace(result.Stdout))
+	}
+	dev := matches[1]
+
+	result = client.ExecCapture(ctx, pod.Name, nettool.ContainerName, []string{"cat", fmt.Sprintf("/sys/class/net/%s/mtu", dev)})
+	if err := result.AsError(); err != nil {
+		return dev, 0, err
+	}
+
+	mtu, err := strconv.Atoi(strings.TrimSpace(result.Stdout))
+	return dev, mtu, err
+}
+
+// pingWithDF returns true if any reply is received for pings with DF bit set.
+// Replies may be lost or ping fails with "message too long" if packet is bigger than path MTU.
+func pingWithDF(client *types.Client, pod corev1.Pod, ip string, size int, opts *options) (bool, error) {
+	cmd := []string{"ping", "-M", "do", "-c", fmt.Sprint(opts.count), "-W", fmt.Sprint(opts.wait), "-s", fmt.Sprint(size), ip}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(opts.count*opts.wait)*time.Second+10*time.Second)
+	defer cancel()
+
+	result := client.ExecCapture(ctx, pod.Name, nettool.ContainerName, cmd)
+	if result.Err != nil {
+		return false, result.Err
+	}
+
+	return result.ExitCode == 0, nil
+}
+
+func (results Results) Describe(w io.Writer) {
+	for _, r := range results {
+		kvs := []printer.KeyValue{
+			{Key: "From", Value: fmt.Sprintf("%s(%s)", r.From, r.FromIP)},
+			{Key: "To", Value: fmt.Sprintf("%s(%s)", r.To, r.ToIP)},
+			{Key: "Interface", Value: r.Interface},
+			{Key: "Interface MTU", Value: fmt.Sprint(r.InterfaceMTU)},
+		}
+
+		if r.Error != "" {
+			kvs = append(kvs, printer.KeyValue{Key: "Error", Value: r.Error})
+		} else {
+			kvs = append(kvs, printer.KeyValue{Key: "Path MTU", Value: fmt.Sprint(r.PathMTU)})
+		}
+
+		if warning := r.Warning(); warning != "" {
+			kvs = append(kvs, printer.KeyValue{Key: "Warning", Value: warning})
+		}
+
+		printer.Describe(w, kvs...)
+	}
+}
+
+func (results Results) Header(wide bool) []string {
+	header := []string{"FROM", "TO", "INTERFACE-MTU", "PATH-MTU", "WARNING"}
+	if wide {
+		header = append(header, "FROM-IP", "TO-IP", "INTERFACE", "ERROR")
+	}
+
+	return header
+}
+
+func (results Results) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, r := range results {
+		var pathMTU, warning string
+		if r.Error == "" {
+			pathMTU = fmt.Sprint(r.PathMTU)
+		}
+		if r.Warning() != "" {
+			warning = "path MTU is less than interface MTU"
+		}
+
+		row := []string{r.From, r.To, fmt.Sprint(r.InterfaceMTU), pathMTU, warning}
+		if wide {
+			row = append(row, r.FromIP, r.ToIP, r.Interface, r.Error)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
@@ -49,19 +49,15 @@ func New(clientGetter types.ClientGetter) *cobra.Command {
 	fs.StringVarP(&image, "image", "i", "fabedge/net-tool:v0.1.0", "The image of net-tool pod")
 	fs.StringVar(&podName, "podName", "", "The podName of generated pod, if this value is empty, fabctl will use podName derived from node podName")
 	fs.BoolVar(&useHostNetwork, "host", false, "Use host network or not")
-	fs.Int32Var(&httpPort, "http-port", 30080, "The default http port for net-tool pod")
-	fs.Int32Var(&httpsPort, "https-port", 30443, "The default https port for net-tool pod")
+	fs.Int32Var(&httpPort, "http-port", DefaultHTTPPort, "The default http port for net-tool pod")
+	fs.Int32Var(&httpsPort, "https-port", DefaultHTTPSPort, "The default https port for net-tool pod")
 
 	return cmd
 }
 
 func newNetToolPod(nodeName, podName, namespace, image string, useHostNetwork bool, httpPort, httpsPort int32) corev1.Pod {
 	if podName == "" {
-		if useHostNetwork {
-			podName = fmt.Sprintf("host-net-tool-%s", nodeName)
-		} else {
-			podName = fmt.Sprintf("net-tool-%s", nodeName)
-		}
+		podName = PodName(nodeName, useHostNetwork)
 	}
 
 	return corev1.Pod{
@@ -81,7 +77,7 @@ func newNetToolPod(nodeName, podName, namespace, image string, useHostNetwork bo
 			AutomountServiceAccountToken: new(bool),
 			Containers: []corev1.Container{
 				{
-					Name:            "net-tool",
+					Name:            ContainerName,
 					Image:           image,
 					ImagePullPolicy: corev1.PullIfNotPresent,
 					Ports: []corev1.ContainerPort{
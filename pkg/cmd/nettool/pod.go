@@ -0,0 +1,96 @@
+package nettool
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/fabedge/fabctl/pkg/types"
+)
+
+const (
+	ContainerName = "net-tool"
+
+	DefaultHTTPPort  int32 = 30080
+	DefaultHTTPSPort int32 = 30443
+)
+
+// PodName returns the default name of net-tool pod on specified node
+func PodName(nodeName string, useHostNetwork bool) string {
+	if useHostNetwork {
+		return fmt.Sprintf("host-net-tool-%s", nodeName)
+	}
+
+	return fmt.Sprintf("net-tool-%s", nodeName)
+}
+
+// PodOption changes the net-tool pod before it's created
+type PodOption func(pod *corev1.Pod)
+
+// WithPodName sets the name of net-tool pod, commands which need a pod different from
+// the default one should use their own pod name, so the pod won't be shared with others
+func WithPodName(name string) PodOption {
+	return func(pod *corev1.Pod) {
+		pod.Name = name
+	}
+}
+
+// WithCapabilities adds capabilities to net-tool container
+func WithCapabilities(capabilities ...corev1.Capability) PodOption {
+	return func(pod *corev1.Pod) {
+		pod.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{
+			Capabilities: &corev1.Capabilities{
+				Add: capabilities,
+			},
+		}
+	}
+}
+
+// GetOrCreatePod returns the net-tool pod on specified node, the pod is created if not found.
+// It waits until the pod is running or timeout.
+func GetOrCreatePod(ctx context.Context, cli *types.Client, nodeName, image string, useHostNetwork bool, timeout time.Duration, opts ...PodOption) (corev1.Pod, error) {
+	expected := newNetToolPod(nodeName, PodName(nodeName, useHostNetwork), cli.GetNamespace(), image, useHostNetwork, DefaultHTTPPort, DefaultHTTPSPort)
+	for _, opt := range opts {
+		opt(&expected)
+	}
+
+	var (
+		pod corev1.Pod
+		key = types.ObjectKey{Name: expected.Name, Namespace: expected.Namespace}
+	)
+
+	err := cli.Get(ctx, key, &pod)
+	switch {
+	case err == nil:
+	case !errors.IsNotFound(err):
+		return pod, err
+	default:
+		pod = expected
+		if err = cli.Create(ctx, &pod); err != nil {
+			return pod, err
+		}
+	}
+
+	err = wait.PollImmediate(time.Second, timeout, func() (bool, error) {
+		err = cli.Get(ctx, key, &pod)
+		if err != nil {
+			return false, err
+		}
+
+		return pod.Status.Phase == corev1.PodRunning, nil
+	})
+
+	return pod, err
+}
+
+func DeletePod(ctx context.Context, cli *types.Client, pod corev1.Pod) {
+	err := cli.Delete(ctx, &pod)
+	if err != nil && !errors.IsNotFound(err) {
+		fmt.Fprintf(os.Stderr, "failed to delete pod %s/%s: %s\n", pod.Namespace, pod.Name, err)
+	}
+}
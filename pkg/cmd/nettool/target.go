@@ -0,0 +1,130 @@
+package nettool
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/fabedge/fabctl/pkg/types"
+)
+
+const (
+	TargetNode     = "node"
+	TargetPod      = "pod"
+	TargetService  = "svc"
+	TargetIP       = "ip"
+	TargetEndpoint = "endpoint"
+)
+
+// Target is the destination of a network test, it's expressed as kind:name, a value without kind is a node name
+type Target struct {
+	Kind string
+	Name string
+}
+
+func ParseTarget(value string) (Target, error) {
+	index := strings.Index(value, ":")
+	if index == -1 {
+		return Target{Kind: TargetNode, Name: value}, nil
+	}
+
+	target := Target{Kind: value[:index], Name: value[index+1:]}
+	if target.Name == "" {
+		return target, fmt.Errorf("target name is empty: %s", value)
+	}
+
+	switch target.Kind {
+	case TargetNode, TargetPod, TargetService, TargetEndpoint:
+	case TargetIP:
+		if net.ParseIP(target.Name) == nil {
+			return target, fmt.Errorf("invalid IP: %s", target.Name)
+		}
+	default:
+		return target, fmt.Errorf("unknown target kind: %s, it should be one of node, pod, svc, ip and endpoint", target.Kind)
+	}
+
+	return target, nil
+}
+
+func (t Target) String() string {
+	return fmt.Sprintf("%s:%s", t.Kind, t.Name)
+}
+
+// ResolveIP returns the address of target, node targets are not supported because
+// net-tool pods have to be created for them
+func (t Target) ResolveIP(ctx context.Context, client *types.Client) (string, error) {
+	switch t.Kind {
+	case TargetIP:
+		return t.Name, nil
+	case TargetPod:
+		var pod corev1.Pod
+		if err := client.Get(ctx, NamespacedKey(t.Name), &pod); err != nil {
+			return "", err
+		}
+
+		if pod.Status.PodIP == "" {
+			return "", fmt.Errorf("pod %s has no IP", t.Name)
+		}
+
+		return pod.Status.PodIP, nil
+	case TargetService:
+		var svc corev1.Service
+		if err := client.Get(ctx, NamespacedKey(t.Name), &svc); err != nil {
+			return "", err
+		}
+
+		if svc.Spec.ClusterIP == "" || svc.Spec.ClusterIP == corev1.ClusterIPNone {
+			return "", fmt.Errorf("service %s has no cluster IP", t.Name)
+		}
+
+		return svc.Spec.ClusterIP, nil
+	case TargetEndpoint:
+		return resolveEndpointIP(ctx, client, t.Name)
+	default:
+		return "", fmt.Errorf("can't resolve IP of %s", t)
+	}
+}
+
+// resolveEndpointIP finds endpoint in Cluster resources and returns its first node address.
+// Cluster resources are only complete in host cluster.
+func resolveEndpointIP(ctx context.Context, client *types.Client, name string) (string, error) {
+	clusters, err := client.ListClusters(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	for _, cluster := range clusters {
+		for _, ep := range cluster.Spec.EndPoints {
+			if ep.Name != name {
+				continue
+			}
+
+			if len(ep.NodeSubnets) == 0 {
+				return "", fmt.Errorf("endpoint %s has no node subnets", name)
+			}
+
+			// node subnets of edge are node IPs in CIDR format, e.g. 10.22.46.18/32
+			subnet := ep.NodeSubnets[0]
+			if ip, _, err := net.ParseCIDR(subnet); err == nil {
+				return ip.String(), nil
+			}
+
+			return subnet, nil
+		}
+	}
+
+	return "", fmt.Errorf("endpoint %s is not found in clusters", name)
+}
+
+// NamespacedKey parses namespace/name, default namespace is used if namespace is omitted
+func NamespacedKey(value string) types.ObjectKey {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) == 1 {
+		return types.ObjectKey{Namespace: corev1.NamespaceDefault, Name: parts[0]}
+	}
+
+	return types.ObjectKey{Namespace: parts[0], Name: parts[1]}
+}
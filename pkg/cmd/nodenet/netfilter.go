@@ -0,0 +1,103 @@
+package nodenet
+
+import (
+	"sort"
+	"strings"
+)
+
+// IPSet is an ipset listed by "ipset list"
+type IPSet struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Members []string `json:"members"`
+}
+
+// Chain is an iptables chain listed by iptables-save, References is the number of rules which jump to it
+type Chain struct {
+	Table      string   `json:"table"`
+	Name       string   `json:"name"`
+	Rules      []string `json:"rules"`
+	References int      `json:"references"`
+}
+
+// parseIPSets parses output of "ipset list", members may be followed by options like timeout
+func parseIPSets(output string) []IPSet {
+	var (
+		sets      []IPSet
+		inMembers bool
+	)
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(line, "Name:"):
+			sets = append(sets, IPSet{Name: strings.TrimSpace(strings.TrimPrefix(line, "Name:"))})
+			inMembers = false
+		case len(sets) == 0:
+		case strings.HasPrefix(line, "Type:"):
+			sets[len(sets)-1].Type = strings.TrimSpace(strings.TrimPrefix(line, "Type:"))
+		case line == "Members:":
+			inMembers = true
+		case line == "":
+			inMembers = false
+		case inMembers:
+			set := &sets[len(sets)-1]
+			set.Members = append(set.Members, strings.Fields(line)[0])
+		}
+	}
+
+	return sets
+}
+
+// parseChains parses output of iptables-save and returns chains whose names start with prefix
+func parseChains(output string, prefix string) []Chain {
+	var (
+		table  string
+		chains = make(map[string]*Chain)
+		refs   = make(map[string]int)
+	)
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "*"):
+			table = line[1:]
+		case strings.HasPrefix(line, ":"):
+			name := fields[0][1:]
+			if strings.HasPrefix(name, prefix) {
+				chains[table+"/"+name] = &Chain{Table: table, Name: name}
+			}
+		case fields[0] == "-A" && len(fields) > 1:
+			if chain, ok := chains[table+"/"+fields[1]]; ok {
+				chain.Rules = append(chain.Rules, line)
+			}
+
+			for i := 2; i < len(fields)-1; i++ {
+				if fields[i] == "-j" || fields[i] == "-g" {
+					refs[table+"/"+fields[i+1]]++
+				}
+			}
+		}
+	}
+
+	var result []Chain
+	for key, chain := range chains {
+		chain.References = refs[key]
+		result = append(result, *chain)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Table != result[j].Table {
+			return result[i].Table < result[j].Table
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
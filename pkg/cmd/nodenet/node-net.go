@@ -0,0 +1,677 @@
+package nodenet
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	apisv1 "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/fabedge/fabctl/pkg/cmd/nettool"
+	"github.com/fabedge/fabctl/pkg/iproute"
+	"github.com/fabedge/fabctl/pkg/printer"
+	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+
+	agentContainer = "agent"
+	chainPrefix    = "FABEDGE"
+)
+
+type options struct {
+	image          string
+	prepareTimeout time.Duration
+	keepPods       bool
+	hostNetwork    bool
+}
+
+// Finding is a problem found by comparing network configuration of a node with its endpoint and peers
+type Finding struct {
+	Severity string `json:"severity"`
+	Category string `json:"category"`
+	Message  string `json:"message"`
+}
+
+// Result is the network configuration of an edge node, routes in local table are omitted
+type Result struct {
+	Node        string           `json:"node"`
+	Endpoint    string           `json:"endpoint"`
+	Pod         string           `json:"pod"`
+	Subnets     []string         `json:"subnets"`
+	NodeSubnets []string         `json:"nodeSubnets"`
+	Peers       []string         `json:"peers"`
+	States      []iproute.State  `json:"xfrmStates"`
+	Policies    []iproute.Policy `json:"xfrmPolicies"`
+	Routes      []iproute.Route  `json:"routes"`
+	Rules       []iproute.Rule   `json:"rules"`
+	IPSets      []IPSet          `json:"ipsets"`
+	Chains      []Chain          `json:"chains"`
+	Findings    []Finding        `json:"findings"`
+
+	peers []apisv1.Endpoint
+}
+
+type Results []Result
+
+func (r *Result) addFinding(severity, category, format string, args ...interface{}) {
+	r.Findings = append(r.Findings, Finding{Severity: severity, Category: category, Message: fmt.Sprintf(format, args...)})
+}
+
+// Count returns the number of findings with specified severity
+func (results Results) Count(severity string) int {
+	count := 0
+	for _, r := range results {
+		for _, f := range r.Findings {
+			if f.Severity == severity {
+				count++
+			}
+		}
+	}
+
+	return count
+}
+
+func New(clientGetter types.ClientGetter) *cobra.Command {
+	opts := &options{}
+
+	cmd := &cobra.Command{
+		Use:   "node-net nodeName [nodeName]... [flags]",
+		Short: "Inspect xfrm states and policies, routes, rules, ipsets and iptables chains of edge nodes",
+		Long: `Inspect xfrm states and policies, routes, rules, ipsets and iptables chains of edge nodes.
+Commands are executed in agent pod of the node, or in host-network net-tool pod if agent pod is not running or --host is set.
+The outputs are compared with subnets of the endpoint of node and its peers, missing routes or policies are reported as errors,
+stale policies and unexpected entries are reported as warnings.`,
+		Example: `
+fabctl node-net edge1
+fabctl node-net edge1 edge2 -o table
+fabctl node-net edge1 --host -o json
+`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			p, err := clientGetter.GetPrinter()
+			util.CheckError(err)
+
+			client, err := clientGetter.GetClient()
+			util.CheckError(err)
+
+			i, err := newInspector(client, opts)
+			util.CheckError(err)
+
+			results := make(Results, len(args))
+			errs := make([]error, len(args))
+			util.Parallel(len(args), len(args), func(n int) {
+				results[n], errs[n] = i.inspect(args[n])
+			})
+			i.close()
+
+			for _, err := range errs {
+				util.CheckError(err)
+			}
+
+			util.CheckError(p.Print(results))
+
+			if count := results.Count(SeverityError); count > 0 {
+				util.Exitf("%d errors found\n", count)
+			}
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.StringVarP(&opts.image, "net-tool-image", "i", "praqma/network-multitool:minimal", "The image of net-tool pod")
+	fs.DurationVar(&opts.prepareTimeout, "prepare-timeout", 30*time.Second, "The length of time to prepare net-tool pods")
+	fs.BoolVarP(&opts.keepPods, "keep", "k", false, "keep pods after test if finished")
+	fs.BoolVar(&opts.hostNetwork, "host", false, "Use host-network net-tool pods even if agent pod is running")
+
+	return cmd
+}
+
+type inspector struct {
+	client  *types.Client
+	opts    *options
+	cluster *types.Cluster
+
+	// endpoints from Cluster resources and local edges, indexed by name
+	endpoints map[string]apisv1.Endpoint
+	agents    map[string]corev1.Pod
+
+	mutex sync.Mutex
+	pods  []corev1.Pod
+}
+
+func newInspector(client *types.Client, opts *options) (*inspector, error) {
+	cluster := types.NewCluster(client)
+	if err := cluster.ExtractArgumentsFromFabEdge(); err != nil {
+		return nil, err
+	}
+
+	if err := cluster.LoadCommunities(); err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	clusters, err := client.ListClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make(map[string]apisv1.Endpoint)
+	for _, c := range clusters {
+		for _, ep := range c.Spec.EndPoints {
+			endpoints[ep.Name] = ep
+		}
+	}
+
+	edgeNodes, err := client.ListNodes(ctx, cluster.EdgeLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range edgeNodes {
+		ep := cluster.NewEndpoint(node)
+		endpoints[ep.Name] = ep
+	}
+
+	pods, err := client.ListAgentPods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	agents := make(map[string]corev1.Pod)
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodRunning {
+			agents[pod.Spec.NodeName] = pod
+		}
+	}
+
+	return &inspector{
+		client:    client,
+		opts:      opts,
+		cluster:   cluster,
+		endpoints: endpoints,
+		agents:    agents,
+	}, nil
+}
+
+func (i *inspector) inspect(nodeName string) (Result, error) {
+	node, err := i.client.GetNode(context.Background(), nodeName)
+	if err != nil {
+		return Result{Node: nodeName}, err
+	}
+
+	if !i.cluster.IsEdgeNode(node) {
+		return Result{Node: nodeName}, fmt.Errorf("%s is not an edge node", nodeName)
+	}
+
+	ep := i.cluster.NewEndpoint(node)
+	result := Result{
+		Node:        nodeName,
+		Endpoint:    ep.Name,
+		Subnets:     ep.Subnets,
+		NodeSubnets: ep.NodeSubnets,
+	}
+
+	peerNames := i.cluster.CommunityPeers(ep.Name)
+	peerNames.Insert(i.cluster.ConnectorName())
+	for _, name := range peerNames.List() {
+		result.Peers = append(result.Peers, name)
+
+		peer, ok := i.endpoints[name]
+		if !ok {
+			result.addFinding(SeverityWarning, "peer", "endpoint of peer %s is not found", name)
+			continue
+		}
+		result.peers = append(result.peers, peer)
+	}
+
+	pod, container, err := i.getPod(nodeName)
+	if err != nil {
+		return result, err
+	}
+	result.Pod = pod.Name
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	exec := func(category string, cmd ...string) (string, bool) {
+		r := i.client.ExecCapture(ctx, pod.Name, container, cmd)
+		if err := r.AsError(); err != nil {
+			result.addFinding(SeverityError, category, "failed to execute %s: %s", strings.Join(cmd, " "), err)
+			return "", false
+		}
+
+		return r.Stdout, true
+	}
+
+	statesOut, statesOK := exec("xfrm-state", "ip", "xfrm", "state")
+	if out, ok := exec("xfrm-policy", "ip", "xfrm", "policy"); ok {
+		result.Policies = iproute.ParsePolicies(out)
+		result.checkPolicies()
+	}
+
+	// states are checked after policies are parsed, tunnel addresses of policies are used to find peers of states
+	if statesOK {
+		result.States = iproute.ParseStates(statesOut)
+		result.checkStates()
+	}
+
+	routesOut, routesOK := exec("route", "ip", "route", "show", "table", "all")
+	rulesOut, rulesOK := exec("rule", "ip", "rule")
+	if routesOK && rulesOK {
+		for _, route := range iproute.ParseRoutes(routesOut) {
+			if route.Table != iproute.TableLocal {
+				result.Routes = append(result.Routes, route)
+			}
+		}
+		result.Rules = iproute.ParseRules(rulesOut)
+		result.checkRoutes()
+	}
+
+	if out, ok := exec("ipset", "ipset", "list"); ok {
+		for _, set := range parseIPSets(out) {
+			if strings.HasPrefix(set.Name, chainPrefix) {
+				result.IPSets = append(result.IPSets, set)
+			}
+		}
+		result.checkIPSets()
+	}
+
+	if out, ok := exec("iptables", "iptables-save"); ok {
+		result.Chains = parseChains(out, chainPrefix)
+		result.checkChains()
+	}
+
+	return result, nil
+}
+
+// getPod returns agent pod on the node and its agent container, if agent pod is not running or
+// host network is required, a host-network net-tool pod is used
+func (i *inspector) getPod(nodeName string) (corev1.Pod, string, error) {
+	if pod, ok := i.agents[nodeName]; ok && !i.opts.hostNetwork {
+		return pod, agentContainer, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), i.opts.prepareTimeout)
+	defer cancel()
+
+	// NET_ADMIN and NET_RAW are required to read xfrm states, iptables and ipsets of the node,
+	// the pod has its own name, so a host-network net-tool pod without them won't be used
+	pod, err := nettool.GetOrCreatePod(ctx, i.client, nodeName, i.opts.image, true, i.opts.prepareTimeout,
+		nettool.WithPodName(fmt.Sprintf("node-net-%s", nodeName)),
+		nettool.WithCapabilities("NET_ADMIN", "NET_RAW"),
+	)
+	if pod.Name != "" {
+		i.mutex.Lock()
+		i.pods = append(i.pods, pod)
+		i.mutex.Unlock()
+	}
+
+	return pod, nettool.ContainerName, err
+}
+
+func (i *inspector) close() {
+	if i.opts.keepPods {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), i.opts.prepareTimeout)
+	defer cancel()
+
+	for _, pod := range i.pods {
+		nettool.DeletePod(ctx, i.client, pod)
+	}
+}
+
+// localSubnets returns subnets used as local traffic selectors of tunnels
+func (r Result) localSubnets() []string {
+	return append(append([]string{}, r.Subnets...), r.NodeSubnets...)
+}
+
+func remoteSubnets(peer apisv1.Endpoint) []string {
+	return append(append([]string{}, peer.Subnets...), peer.NodeSubnets...)
+}
+
+// checkStates checks if there are SAs with each peer and no SAs with unknown hosts
+func (r *Result) checkStates() {
+	for _, peer := range r.peers {
+		addresses := r.tunnelAddresses(peer)
+		if len(addresses) == 0 {
+			continue
+		}
+
+		var in, out bool
+		for _, s := range r.States {
+			in = in || addresses[s.Src]
+			out = out || addresses[s.Dst]
+		}
+
+		if !in || !out {
+			r.addFinding(SeverityError, "xfrm-state", "no xfrm states in both directions with peer %s(%s)", peer.Name, printer.Join(peer.PublicAddresses))
+		}
+	}
+
+	for _, s := range r.States {
+		if r.peerOfAddress(s.Src) == "" && r.peerOfAddress(s.Dst) == "" {
+			r.addFinding(SeverityWarning, "xfrm-state", "unexpected xfrm state %s, it's not for any peer", s)
+		}
+	}
+}
+
+// checkPolicies checks if policies of every pair of local and remote subnets exist,
+// policies with tunnels which don't match any pair are stale.
+// Node to node traffic doesn't go through tunnels, so pairs of node subnets are skipped
+func (r *Result) checkPolicies() {
+	expected := make(map[string]bool)
+	localNodeSubnets := sets.NewString(r.NodeSubnets...)
+	for _, peer := range r.peers {
+		remoteNodeSubnets := sets.NewString(peer.NodeSubnets...)
+		for _, local := range r.localSubnets() {
+			for _, remote := range remoteSubnets(peer) {
+				if localNodeSubnets.Has(local) && remoteNodeSubnets.Has(remote) {
+					continue
+				}
+
+				pairs := []iproute.Policy{
+					{Src: local, Dst: remote, Dir: iproute.DirOut},
+					{Src: remote, Dst: local, Dir: iproute.DirIn},
+					{Src: remote, Dst: local, Dir: iproute.DirFwd},
+				}
+
+				for _, p := range pairs {
+					expected[policyKey(p)] = true
+					if !r.hasPolicy(p) {
+						r.addFinding(SeverityError, "xfrm-policy", "missing xfrm policy %s for peer %s", p, peer.Name)
+					}
+				}
+			}
+		}
+	}
+
+	for _, p := range r.Policies {
+		if p.TunnelDst == "" {
+			continue
+		}
+
+		if !expected[policyKey(p)] {
+			r.addFinding(SeverityWarning, "xfrm-policy", "stale xfrm policy %s", p)
+		}
+	}
+}
+
+func (r Result) hasPolicy(expected iproute.Policy) bool {
+	for _, p := range r.Policies {
+		if policyKey(p) == policyKey(expected) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func policyKey(p iproute.Policy) string {
+	return fmt.Sprintf("%s %s %s", p.Dir, iproute.Normalize(p.Src), iproute.Normalize(p.Dst))
+}
+
+// checkRoutes checks if there is a route to each remote subnet and the table of route is looked up by rules.
+// Routes in tables other than main to unknown subnets are unexpected.
+func (r *Result) checkRoutes() {
+	var remotes []string
+	for _, peer := range r.peers {
+		for _, remote := range remoteSubnets(peer) {
+			remotes = append(remotes, remote)
+
+			route, ok := r.routeTo(remote)
+			if !ok {
+				r.addFinding(SeverityError, "route", "missing route to %s of peer %s", remote, peer.Name)
+				continue
+			}
+
+			if route.Table != iproute.TableMain && !r.tableIsLookedUp(route.Table) {
+				r.addFinding(SeverityError, "rule", "route to %s is in table %s, but no rule looks up the table", remote, route.Table)
+			}
+		}
+	}
+
+	for _, route := range r.Routes {
+		if route.Table == iproute.TableMain || route.Dst == "0.0.0.0/0" {
+			continue
+		}
+
+		known := false
+		for _, remote := range remotes {
+			if iproute.Covers(remote, route.Dst) {
+				known = true
+				break
+			}
+		}
+
+		if !known {
+			r.addFinding(SeverityWarning, "route", "unexpected route to %s in table %s", route.Dst, route.Table)
+		}
+	}
+}
+
+// routeTo returns the most specific route which covers subnet, default routes are ignored
+func (r Result) routeTo(subnet string) (iproute.Route, bool) {
+	var (
+		found iproute.Route
+		ones  = -1
+	)
+
+	for _, route := range r.Routes {
+		if route.Dst == "0.0.0.0/0" || route.Type == "unreachable" || route.Type == "blackhole" || route.Type == "prohibit" {
+			continue
+		}
+
+		if !iproute.Covers(route.Dst, subnet) {
+			continue
+		}
+
+		if _, ipNet, err := net.ParseCIDR(route.Dst); err == nil {
+			if n, _ := ipNet.Mask.Size(); n > ones {
+				found, ones = route, n
+			}
+		}
+	}
+
+	return found, ones != -1
+}
+
+func (r Result) tableIsLookedUp(table string) bool {
+	for _, rule := range r.Rules {
+		if rule.Table == table {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkIPSets checks if remote subnets are in FabEdge ipsets, and members of ipsets are local or remote subnets
+func (r *Result) checkIPSets() {
+	if len(r.IPSets) == 0 {
+		r.addFinding(SeverityWarning, "ipset", "no FabEdge ipsets found")
+		return
+	}
+
+	known := r.localSubnets()
+	for _, peer := range r.peers {
+		for _, remote := range remoteSubnets(peer) {
+			known = append(known, remote)
+
+			if !r.inIPSets(remote) {
+				r.addFinding(SeverityWarning, "ipset", "%s of peer %s is not in any FabEdge ipset", remote, peer.Name)
+			}
+		}
+		known = append(known, peer.PublicAddresses...)
+	}
+
+	for _, set := range r.IPSets {
+		for _, member := range set.Members {
+			covered := false
+			for _, subnet := range known {
+				if iproute.Covers(subnet, member) {
+					covered = true
+					break
+				}
+			}
+
+			if !covered {
+				r.addFinding(SeverityWarning, "ipset", "unexpected entry %s in ipset %s", member, set.Name)
+			}
+		}
+	}
+}
+
+func (r Result) inIPSets(subnet string) bool {
+	for _, set := range r.IPSets {
+		for _, member := range set.Members {
+			if iproute.SameSubnet(member, subnet) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// checkChains checks if FabEdge chains exist and are referenced by other chains
+func (r *Result) checkChains() {
+	if len(r.Chains) == 0 {
+		r.addFinding(SeverityError, "iptables", "no FabEdge iptables chains found")
+		return
+	}
+
+	for _, chain := range r.Chains {
+		if chain.References == 0 {
+			r.addFinding(SeverityWarning, "iptables", "chain %s in table %s is not referenced by any rule", chain.Name, chain.Table)
+		}
+	}
+}
+
+func (r Result) peerOfAddress(address string) string {
+	for _, peer := range r.peers {
+		if r.tunnelAddresses(peer)[address] {
+			return peer.Name
+		}
+	}
+
+	return ""
+}
+
+// tunnelAddresses returns addresses of peer used by xfrm, they are public addresses of peer and tunnel
+// addresses in templates of policies for subnets of peer, which are different if peer is behind NAT
+func (r Result) tunnelAddresses(peer apisv1.Endpoint) map[string]bool {
+	addresses := ipAddresses(peer.PublicAddresses)
+
+	remotes := sets.NewString()
+	for _, subnet := range remoteSubnets(peer) {
+		remotes.Insert(iproute.Normalize(subnet))
+	}
+
+	for _, p := range r.Policies {
+		switch {
+		case p.TunnelDst == "":
+		case p.Dir == iproute.DirOut && remotes.Has(iproute.Normalize(p.Dst)):
+			addresses[p.TunnelDst] = true
+		case p.Dir != iproute.DirOut && remotes.Has(iproute.Normalize(p.Src)):
+			addresses[p.TunnelSrc] = true
+		}
+	}
+
+	return addresses
+}
+
+// ipAddresses returns addresses which are IPs, public addresses may be domain names
+func ipAddresses(addresses []string) map[string]bool {
+	result := make(map[string]bool)
+	for _, address := range addresses {
+		if ip := net.ParseIP(address); ip != nil {
+			result[ip.String()] = true
+		}
+	}
+
+	return result
+}
+
+func (results Results) Describe(w io.Writer) {
+	for _, r := range results {
+		printer.Describe(w,
+			printer.KeyValue{Key: "Node", Value: r.Node},
+			printer.KeyValue{Key: "Endpoint", Value: r.Endpoint},
+			printer.KeyValue{Key: "Pod", Value: r.Pod},
+			printer.KeyValue{Key: "Subnets", Value: printer.Join(r.Subnets)},
+			printer.KeyValue{Key: "Node Subnets", Value: printer.Join(r.NodeSubnets)},
+			printer.KeyValue{Key: "Peers", Value: printer.Join(r.Peers)},
+			printer.KeyValue{Key: "XFRM States", Value: fmt.Sprint(len(r.States))},
+			printer.KeyValue{Key: "XFRM Policies", Value: fmt.Sprint(len(r.Policies))},
+			printer.KeyValue{Key: "Routes", Value: fmt.Sprint(len(r.Routes))},
+			printer.KeyValue{Key: "Rules", Value: fmt.Sprint(len(r.Rules))},
+			printer.KeyValue{Key: "IPSets", Value: fmt.Sprint(len(r.IPSets))},
+			printer.KeyValue{Key: "IPTables Chains", Value: fmt.Sprint(len(r.Chains))},
+		)
+
+		if len(r.Findings) == 0 {
+			fmt.Fprintln(w, "\nNo problems found")
+			continue
+		}
+
+		fmt.Fprintln(w)
+		printer.PrintTable(w, findings(r.Findings), false)
+	}
+}
+
+func (results Results) Header(wide bool) []string {
+	header := []string{"NODE", "XFRM-STATES", "XFRM-POLICIES", "ROUTES", "ERRORS", "WARNINGS"}
+	if wide {
+		header = append(header, "ENDPOINT", "POD", "PEERS")
+	}
+
+	return header
+}
+
+func (results Results) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, r := range results {
+		single := Results{r}
+		row := []string{
+			r.Node,
+			fmt.Sprint(len(r.States)),
+			fmt.Sprint(len(r.Policies)),
+			fmt.Sprint(len(r.Routes)),
+			fmt.Sprint(single.Count(SeverityError)),
+			fmt.Sprint(single.Count(SeverityWarning)),
+		}
+		if wide {
+			row = append(row, r.Endpoint, r.Pod, printer.Join(r.Peers))
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+type findings []Finding
+
+func (fs findings) Header(wide bool) []string {
+	return []string{"SEVERITY", "CATEGORY", "MESSAGE"}
+}
+
+func (fs findings) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, f := range fs {
+		rows = append(rows, []string{f.Severity, f.Category, f.Message})
+	}
+
+	return rows
+}
@@ -3,15 +3,15 @@ package nodes
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
-	"strings"
 
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/fabedge/fabctl/pkg/printer"
 	"github.com/fabedge/fabctl/pkg/types"
 	"github.com/fabedge/fabctl/pkg/util"
 	nodeutil "github.com/fabedge/fabedge/pkg/util/node"
@@ -21,45 +21,22 @@ func New(clientGetter types.ClientGetter) *cobra.Command {
 	var selector string
 	var edgeOnly bool
 	cmd := &cobra.Command{
-		Use:   "nodes [node1] [node2]...",
-		Short: "Show network information about edge nodes",
+		Use:         "nodes [node1] [node2]...",
+		Short:       "Show network information about edge nodes",
+		Annotations: types.MultiContexts(),
 		Run: func(cmd *cobra.Command, args []string) {
-			cli, err := clientGetter.GetClient()
+			p, err := clientGetter.GetPrinter()
 			util.CheckError(err)
 
-			cluster := types.NewCluster(cli)
-			util.CheckError(cluster.ExtractArgumentsFromFabEdge())
-			util.CheckError(cluster.LoadCommunities())
-
-			var nodes []corev1.Node
-			if len(args) > 0 {
-				for _, name := range args {
-					node, err := cli.GetNode(context.Background(), name)
-					if err != nil {
-						fmt.Fprint(os.Stderr, err.Error())
-					} else {
-						nodes = append(nodes, node)
-					}
+			err = types.PrintInContexts(clientGetter, p, func(getter types.ClientGetter) (interface{}, error) {
+				cli, err := getter.GetClient()
+				if err != nil {
+					return nil, err
 				}
-			} else {
-				if edgeOnly {
-					nodes, err = cli.ListNodes(context.Background(), cluster.EdgeLabels)
-					util.CheckError(err)
-				} else {
-					l, err := labels.Parse(selector)
-					util.CheckError(err)
-
-					var nodeList corev1.NodeList
-					err = cli.List(context.Background(), &nodeList, client.MatchingLabelsSelector{Selector: l})
-					util.CheckError(err)
-
-					nodes = nodeList.Items
-				}
-			}
 
-			for _, node := range nodes {
-				displayNodeInfo(node, cluster)
-			}
+				return listNodes(cli, args, selector, edgeOnly)
+			})
+			util.CheckError(err)
 		},
 	}
 
@@ -71,31 +48,121 @@ func New(clientGetter types.ClientGetter) *cobra.Command {
 	return cmd
 }
 
-func displayNodeInfo(node corev1.Node, cluster *types.Cluster) {
+func listNodes(cli *types.Client, names []string, selector string, edgeOnly bool) (NodeList, error) {
+	cluster := types.NewCluster(cli)
+	if err := cluster.ExtractArgumentsFromFabEdge(); err != nil {
+		return nil, err
+	}
+	if err := cluster.LoadCommunities(); err != nil {
+		return nil, err
+	}
+
+	var nodes []corev1.Node
+	if len(names) > 0 {
+		for _, name := range names {
+			node, err := cli.GetNode(context.Background(), name)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+			} else {
+				nodes = append(nodes, node)
+			}
+		}
+	} else {
+		if edgeOnly {
+			var err error
+			nodes, err = cli.ListNodes(context.Background(), cluster.EdgeLabels)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			l, err := labels.Parse(selector)
+			if err != nil {
+				return nil, err
+			}
+
+			var nodeList corev1.NodeList
+			err = cli.List(context.Background(), &nodeList, client.MatchingLabelsSelector{Selector: l})
+			if err != nil {
+				return nil, err
+			}
+
+			nodes = nodeList.Items
+		}
+	}
+
+	nodeList := make(NodeList, 0, len(nodes))
+	for _, node := range nodes {
+		nodeList = append(nodeList, newNode(node, cluster))
+	}
+
+	return nodeList, nil
+}
+
+type Node struct {
+	Name            string   `json:"name"`
+	PublicAddresses []string `json:"publicAddresses"`
+	NodeSubnets     []string `json:"nodeSubnets"`
+	PodCIDRs        []string `json:"podCIDRs"`
+	EdgePodCIDRs    []string `json:"edgePodCIDRs"`
+	Communities     []string `json:"communities"`
+	Peers           []string `json:"peers"`
+}
+
+type NodeList []Node
+
+func newNode(node corev1.Node, cluster *types.Cluster) Node {
 	endpoint := cluster.NewEndpoint(node)
-	podCIDRs := nodeutil.GetPodCIDRs(node)
 
-	communityNames, peers := cluster.EdgeToCommunities[endpoint.Name], sets.NewString()
-	for _, name := range communityNames {
-		peers.Insert(cluster.Communities[name].Spec.Members...)
+	communityNames, peers := cluster.EdgeToCommunities[endpoint.Name], cluster.CommunityPeers(endpoint.Name)
+
+	return Node{
+		Name:            node.Name,
+		PublicAddresses: endpoint.PublicAddresses,
+		NodeSubnets:     endpoint.NodeSubnets,
+		PodCIDRs:        nodeutil.GetPodCIDRs(node),
+		EdgePodCIDRs:    endpoint.Subnets,
+		Communities:     communityNames,
+		Peers:           peers.List(),
+	}
+}
+
+func (nodes NodeList) Describe(w io.Writer) {
+	for _, node := range nodes {
+		printer.Describe(w,
+			printer.KeyValue{Key: "Name", Value: node.Name},
+			printer.KeyValue{Key: "Public Addresses", Value: printer.Join(node.PublicAddresses)},
+			printer.KeyValue{Key: "Node Subnets", Value: printer.Join(node.NodeSubnets)},
+			printer.KeyValue{Key: "PodCIDRs", Value: printer.Join(node.PodCIDRs)},
+			printer.KeyValue{Key: "EdgePodCIDRs", Value: printer.Join(node.EdgePodCIDRs)},
+			printer.KeyValue{Key: "Communities", Value: printer.Join(node.Communities)},
+			printer.KeyValue{Key: "Peers", Value: printer.Join(node.Peers)},
+		)
 	}
-	peers.Delete(endpoint.Name)
-
-	fmt.Printf(`
-Name:             %s
-Public Addresses: %s
-Node Subnets:     %s
-PodCIDRs:         %s
-EdgePodCIDRs:     %s
-Communities:      %s
-Peers:            %s
-`,
-		node.Name,
-		strings.Join(endpoint.PublicAddresses, ","),
-		strings.Join(endpoint.NodeSubnets, ","),
-		strings.Join(podCIDRs, ","),
-		strings.Join(endpoint.Subnets, ","),
-		strings.Join(communityNames, ","),
-		strings.Join(peers.List(), ","),
-	)
+}
+
+func (nodes NodeList) Header(wide bool) []string {
+	header := []string{"NAME", "PUBLIC-ADDRESSES", "EDGE-POD-CIDRS", "COMMUNITIES"}
+	if wide {
+		header = append(header, "NODE-SUBNETS", "POD-CIDRS", "PEERS")
+	}
+
+	return header
+}
+
+func (nodes NodeList) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, node := range nodes {
+		row := []string{
+			node.Name,
+			printer.Join(node.PublicAddresses),
+			printer.Join(node.EdgePodCIDRs),
+			printer.Join(node.Communities),
+		}
+		if wide {
+			row = append(row, printer.Join(node.NodeSubnets), printer.Join(node.PodCIDRs), printer.Join(node.Peers))
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
 }
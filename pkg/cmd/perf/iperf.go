@@ -0,0 +1,69 @@
+package perf
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// iperfReport is the part of iperf3 JSON output used by perf
+type iperfReport struct {
+	End struct {
+		SumSent     iperfSum `json:"sum_sent"`
+		SumReceived iperfSum `json:"sum_received"`
+		// only for UDP
+		Sum iperfSum `json:"sum"`
+	} `json:"end"`
+	Error string `json:"error"`
+}
+
+type iperfSum struct {
+	Seconds       float64 `json:"seconds"`
+	Bytes         int64   `json:"bytes"`
+	BitsPerSecond float64 `json:"bits_per_second"`
+	Retransmits   int     `json:"retransmits"`
+	JitterMs      float64 `json:"jitter_ms"`
+	LostPackets   int     `json:"lost_packets"`
+	Packets       int     `json:"packets"`
+	LostPercent   float64 `json:"lost_percent"`
+}
+
+// parseReport parses output of iperf3 with -J flag into result, errors reported by iperf3 are returned too
+func parseReport(output string, result *Result) error {
+	var report iperfReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		return fmt.Errorf("failed to parse output of iperf3: %s", err)
+	}
+
+	if report.Error != "" {
+		return fmt.Errorf("iperf3: %s", report.Error)
+	}
+
+	end := report.End
+	if result.Protocol == ProtocolUDP {
+		result.Seconds = end.Sum.Seconds
+		result.SentBitsPerSecond = end.Sum.BitsPerSecond
+		result.ReceivedBitsPerSecond = end.Sum.BitsPerSecond * (100 - end.Sum.LostPercent) / 100
+		result.JitterMs = end.Sum.JitterMs
+		result.LostPercent = end.Sum.LostPercent
+	} else {
+		result.Seconds = end.SumSent.Seconds
+		result.SentBitsPerSecond = end.SumSent.BitsPerSecond
+		result.ReceivedBitsPerSecond = end.SumReceived.BitsPerSecond
+		result.Retransmits = end.SumSent.Retransmits
+	}
+
+	return nil
+}
+
+// formatBits formats bits per second like iperf3 does, e.g. 94.2 Mbits/sec
+func formatBits(bps float64) string {
+	units := []string{"bits/sec", "Kbits/sec", "Mbits/sec", "Gbits/sec"}
+
+	i := 0
+	for bps >= 1000 && i < len(units)-1 {
+		bps /= 1000
+		i++
+	}
+
+	return fmt.Sprintf("%.3g %s", bps, units[i])
+}
@@ -0,0 +1,88 @@
+package perf
+
+import (
+	"reflect"
+	"testing"
+)
+
+// trimmed output of "iperf3 -c 10.233.67.5 -J"
+const tcpReport = `{
+	"start": {
+		"connected": [{"socket": 5, "local_host": "10.233.64.9", "local_port": 41876, "remote_host": "10.233.67.5", "remote_port": 5201}]
+	},
+	"end": {
+		"sum_sent": {"start": 0, "end": 10.000151, "seconds": 10.000151, "bytes": 117833728, "bits_per_second": 94265561.6, "retransmits": 12, "sender": true},
+		"sum_received": {"start": 0, "end": 10.03921, "seconds": 10.03921, "bytes": 116785152, "bits_per_second": 93064032.5, "sender": true}
+	}
+}`
+
+// trimmed output of "iperf3 -c 10.233.67.5 -u -b 10M -J"
+const udpReport = `{
+	"end": {
+		"sum": {"start": 0, "end": 10.000205, "seconds": 10.000205, "bytes": 12502240, "bits_per_second": 10000000, "jitter_ms": 0.087, "lost_packets": 215, "packets": 8600, "lost_percent": 2.5, "sender": true}
+	}
+}`
+
+func TestParseReport(t *testing.T) {
+	testCases := []struct {
+		name     string
+		output   string
+		protocol string
+		expected Result
+		err      bool
+	}{
+		{
+			name:     "tcp",
+			output:   tcpReport,
+			protocol: ProtocolTCP,
+			expected: Result{
+				Protocol:              ProtocolTCP,
+				Seconds:               10.000151,
+				SentBitsPerSecond:     94265561.6,
+				ReceivedBitsPerSecond: 93064032.5,
+				Retransmits:           12,
+			},
+		},
+		{
+			name:     "udp",
+			output:   udpReport,
+			protocol: ProtocolUDP,
+			expected: Result{
+				Protocol:              ProtocolUDP,
+				Seconds:               10.000205,
+				SentBitsPerSecond:     10000000,
+				ReceivedBitsPerSecond: 9750000,
+				JitterMs:              0.087,
+				LostPercent:           2.5,
+			},
+		},
+		{
+			name:     "iperf3 error",
+			output:   `{"start": {}, "intervals": [], "end": {}, "error": "unable to connect to server: Connection refused"}`,
+			protocol: ProtocolTCP,
+			expected: Result{Protocol: ProtocolTCP},
+			err:      true,
+		},
+		{
+			name:     "not json",
+			output:   "iperf3: error - unable to connect to server: Connection refused\n",
+			protocol: ProtocolTCP,
+			expected: Result{Protocol: ProtocolTCP},
+			err:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := Result{Protocol: tc.protocol}
+			err := parseReport(tc.output, &result)
+			if (err != nil) != tc.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,377 @@
+package perf
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/fabedge/fabctl/pkg/cmd/nettool"
+	"github.com/fabedge/fabctl/pkg/printer"
+	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
+)
+
+const (
+	ProtocolTCP = "tcp"
+	ProtocolUDP = "udp"
+
+	NetworkPod  = "pod"
+	NetworkHost = "host"
+	NetworkBoth = "both"
+)
+
+type options struct {
+	image          string
+	prepareTimeout time.Duration
+	keepPods       bool
+	network        string
+	port           uint
+	udp            bool
+	parallel       uint
+	duration       uint
+	reverse        bool
+	bandwidth      string
+}
+
+// Result is the throughput from client to server measured by iperf3, if reverse is true, server sends data to client
+type Result struct {
+	Network               string  `json:"network"`
+	Client                string  `json:"client"`
+	ClientIP              string  `json:"clientIP"`
+	Server                string  `json:"server"`
+	ServerIP              string  `json:"serverIP"`
+	Protocol              string  `json:"protocol"`
+	Reverse               bool    `json:"reverse"`
+	Seconds               float64 `json:"seconds"`
+	SentBitsPerSecond     float64 `json:"sentBitsPerSecond"`
+	ReceivedBitsPerSecond float64 `json:"receivedBitsPerSecond"`
+	Retransmits           int     `json:"retransmits"`
+	JitterMs              float64 `json:"jitterMs"`
+	LostPercent           float64 `json:"lostPercent"`
+	Error                 string  `json:"error,omitempty"`
+}
+
+type Results []Result
+
+func New(clientGetter types.ClientGetter) *cobra.Command {
+	opts := &options{}
+
+	cmd := &cobra.Command{
+		Use:   "perf clientNode serverNode [flags]",
+		Short: "Measure throughput between two nodes with iperf3",
+		Long: `Measure throughput between two nodes with iperf3. An iperf3 server is started in net-tool pod on serverNode,
+then iperf3 client in net-tool pod on clientNode sends data to it. The image of net-tool pod should contain iperf3.
+
+Traffic between pods goes through IPSec tunnels while traffic between nodes doesn't, use --network both
+to see the overhead of IPSec tunnels.`,
+		Example: `
+fabctl perf edge1 edge2
+fabctl perf edge1 edge2 --udp --bandwidth 100M
+fabctl perf edge1 edge2 -P 4 -t 30 --reverse
+fabctl perf edge1 edge2 --network both
+`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var networks []string
+			switch opts.network {
+			case NetworkPod, NetworkHost:
+				networks = []string{opts.network}
+			case NetworkBoth:
+				networks = []string{NetworkPod, NetworkHost}
+			default:
+				util.Exitf("unknown network: %s, it should be one of pod, host and both\n", opts.network)
+			}
+
+			p, err := clientGetter.GetPrinter()
+			util.CheckError(err)
+
+			client, err := clientGetter.GetClient()
+			util.CheckError(err)
+
+			var results Results
+			for _, network := range networks {
+				results = append(results, measure(client, args[0], args[1], network == NetworkHost, opts))
+			}
+
+			util.CheckError(p.Print(results))
+
+			for _, r := range results {
+				if r.Error != "" {
+					util.Exitf("failed to measure throughput over %s network\n", r.Network)
+				}
+			}
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.StringVarP(&opts.image, "net-tool-image", "i", "praqma/network-multitool:extra", "The image of net-tool pod, it should contain iperf3")
+	fs.DurationVar(&opts.prepareTimeout, "prepare-timeout", 30*time.Second, "The length of time to prepare net-tool pods and iperf3 server")
+	fs.BoolVarP(&opts.keepPods, "keep", "k", false, "keep pods after test if finished")
+	fs.StringVar(&opts.network, "network", NetworkPod, "The network of net-tool pods, one of pod, host and both")
+	fs.UintVar(&opts.port, "port", 5201, "The port of iperf3 server")
+	fs.BoolVarP(&opts.udp, "udp", "u", false, "Use UDP rather than TCP")
+	fs.UintVarP(&opts.parallel, "parallel", "P", 1, "The number of parallel client streams")
+	fs.UintVarP(&opts.duration, "duration", "t", 10, "The seconds to transmit")
+	fs.BoolVarP(&opts.reverse, "reverse", "R", false, "Run in reverse mode, server sends and client receives")
+	fs.StringVarP(&opts.bandwidth, "bandwidth", "b", "", "Target bandwidth in bits/sec, e.g. 100M, iperf3 uses 1M for UDP by default")
+
+	return cmd
+}
+
+func measure(client *types.Client, clientNode, serverNode string, hostNetwork bool, opts *options) Result {
+	result := Result{
+		Network:  NetworkPod,
+		Client:   clientNode,
+		Server:   serverNode,
+		Protocol: ProtocolTCP,
+		Reverse:  opts.reverse,
+	}
+	if hostNetwork {
+		result.Network = NetworkHost
+	}
+	if opts.udp {
+		result.Protocol = ProtocolUDP
+	}
+
+	// prepare net tool pods
+	var (
+		clientPod, serverPod corev1.Pod
+		err                  error
+	)
+	func() {
+		ctx, cancel := context.WithTimeout(context.Background(), opts.prepareTimeout)
+		defer cancel()
+
+		clientPod, err = nettool.GetOrCreatePod(ctx, client, clientNode, opts.image, hostNetwork, opts.prepareTimeout, nettool.WithPodName(podName(clientNode, hostNetwork)))
+		if err != nil {
+			return
+		}
+
+		serverPod, err = nettool.GetOrCreatePod(ctx, client, serverNode, opts.image, hostNetwork, opts.prepareTimeout, nettool.WithPodName(podName(serverNode, hostNetwork)))
+	}()
+
+	if !opts.keepPods {
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), opts.prepareTimeout)
+			defer cancel()
+
+			for _, pod := range []corev1.Pod{clientPod, serverPod} {
+				if pod.Name != "" {
+					nettool.DeletePod(ctx, client, pod)
+				}
+			}
+		}()
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.ClientIP, result.ServerIP = clientPod.Status.PodIP, serverPod.Status.PodIP
+
+	stopServer, err := startServer(client, serverPod, opts)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer stopServer()
+
+	cmd := []string{
+		"iperf3", "-J",
+		"-c", result.ServerIP,
+		"-p", fmt.Sprint(opts.port),
+		"-P", fmt.Sprint(opts.parallel),
+		"-t", fmt.Sprint(opts.duration),
+	}
+	if opts.udp {
+		cmd = append(cmd, "-u")
+	}
+	if opts.bandwidth != "" {
+		cmd = append(cmd, "-b", opts.bandwidth)
+	}
+	if opts.reverse {
+		cmd = append(cmd, "-R")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(opts.duration)*time.Second+opts.prepareTimeout)
+	defer cancel()
+
+	// iperf3 prints errors in JSON output too, so output is parsed even if it exits with non-zero code
+	output := client.ExecCapture(ctx, clientPod.Name, nettool.ContainerName, cmd)
+	if output.Err != nil {
+		result.Error = output.Err.Error()
+		return result
+	}
+
+	if err = parseReport(output.Stdout, &result); err != nil {
+		if output.ExitCode != 0 && strings.TrimSpace(output.Stdout) == "" {
+			err = output.AsError()
+		}
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// podName returns the name of net-tool pod used by perf, the image of perf pods contains iperf3
+// which the default net-tool image doesn't, so net-tool pods created by other commands are not used
+func podName(nodeName string, hostNetwork bool) string {
+	if hostNetwork {
+		return fmt.Sprintf("host-perf-%s", nodeName)
+	}
+
+	return fmt.Sprintf("perf-%s", nodeName)
+}
+
+// startServer starts an iperf3 server which exits after serving one client, it waits until server is listening.
+// The returned function should be called to close the stream of server and kill the server.
+func startServer(client *types.Client, pod corev1.Pod, opts *options) (func(), error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	reader, writer := io.Pipe()
+
+	serverCmd := []string{"iperf3", "-s", "-1", "-p", fmt.Sprint(opts.port)}
+	stop := func() {
+		cancel()
+
+		// closing the stream doesn't kill the server, it keeps listening if no client connected to it
+		ctx, cancel := context.WithTimeout(context.Background(), opts.prepareTimeout)
+		defer cancel()
+		client.ExecCapture(ctx, pod.Name, nettool.ContainerName, []string{"pkill", "-f", strings.Join(serverCmd, " ")})
+	}
+
+	go func() {
+		err := client.ExecStream(ctx, pod.Name, nettool.ContainerName, serverCmd, types.ExecOptions{
+			Stdout: writer,
+			Stderr: writer,
+		})
+		if err == nil {
+			err = fmt.Errorf("iperf3 server exited")
+		}
+		writer.CloseWithError(err)
+	}()
+
+	ready := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			if strings.Contains(scanner.Text(), "Server listening") {
+				ready <- nil
+				// keep reading to avoid blocking server
+				_, _ = io.Copy(io.Discard, reader)
+				return
+			}
+		}
+
+		ready <- fmt.Errorf("failed to start iperf3 server: %v", scanner.Err())
+	}()
+
+	select {
+	case err := <-ready:
+		if err != nil {
+			stop()
+			return nil, err
+		}
+	case <-time.After(opts.prepareTimeout):
+		stop()
+		return nil, fmt.Errorf("iperf3 server is not ready in %s", opts.prepareTimeout)
+	}
+
+	return stop, nil
+}
+
+func (results Results) Describe(w io.Writer) {
+	for _, r := range results {
+		kvs := []printer.KeyValue{
+			{Key: "Network", Value: r.Network},
+			{Key: "Client", Value: fmt.Sprintf("%s(%s)", r.Client, r.ClientIP)},
+			{Key: "Server", Value: fmt.Sprintf("%s(%s)", r.Server, r.ServerIP)},
+			{Key: "Protocol", Value: r.Protocol},
+			{Key: "Reverse", Value: fmt.Sprint(r.Reverse)},
+		}
+
+		switch {
+		case r.Error != "":
+			kvs = append(kvs, printer.KeyValue{Key: "Error", Value: r.Error})
+		case r.Protocol == ProtocolUDP:
+			kvs = append(kvs,
+				printer.KeyValue{Key: "Seconds", Value: fmt.Sprintf("%.2f", r.Seconds)},
+				printer.KeyValue{Key: "Bandwidth", Value: formatBits(r.SentBitsPerSecond)},
+				printer.KeyValue{Key: "Jitter", Value: fmt.Sprintf("%.3f ms", r.JitterMs)},
+				printer.KeyValue{Key: "Lost", Value: fmt.Sprintf("%.2g%%", r.LostPercent)},
+			)
+		default:
+			kvs = append(kvs,
+				printer.KeyValue{Key: "Seconds", Value: fmt.Sprintf("%.2f", r.Seconds)},
+				printer.KeyValue{Key: "Sent", Value: formatBits(r.SentBitsPerSecond)},
+				printer.KeyValue{Key: "Received", Value: formatBits(r.ReceivedBitsPerSecond)},
+				printer.KeyValue{Key: "Retransmits", Value: fmt.Sprint(r.Retransmits)},
+			)
+		}
+
+		printer.Describe(w, kvs...)
+	}
+
+	if overhead, ok := results.overhead(); ok {
+		fmt.Fprintf(w, "\nThroughput over pod network is %.1f%% lower than host network\n", overhead)
+	}
+}
+
+// overhead returns the percentage of throughput lost over pod network compared to host network
+func (results Results) overhead() (float64, bool) {
+	var pod, host float64
+	for _, r := range results {
+		if r.Error != "" {
+			return 0, false
+		}
+
+		switch r.Network {
+		case NetworkPod:
+			pod = r.ReceivedBitsPerSecond
+		case NetworkHost:
+			host = r.ReceivedBitsPerSecond
+		}
+	}
+
+	if pod == 0 || host == 0 {
+		return 0, false
+	}
+
+	return (host - pod) / host * 100, true
+}
+
+func (results Results) Header(wide bool) []string {
+	header := []string{"NETWORK", "CLIENT", "SERVER", "PROTOCOL", "SENT", "RECEIVED", "RETRANSMITS", "JITTER", "LOST"}
+	if wide {
+		header = append(header, "CLIENT-IP", "SERVER-IP", "REVERSE", "SECONDS", "ERROR")
+	}
+
+	return header
+}
+
+func (results Results) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, r := range results {
+		row := []string{r.Network, r.Client, r.Server, r.Protocol, "", "", "", "", ""}
+		if r.Error == "" {
+			row[4], row[5] = formatBits(r.SentBitsPerSecond), formatBits(r.ReceivedBitsPerSecond)
+			if r.Protocol == ProtocolUDP {
+				row[7], row[8] = fmt.Sprintf("%.3fms", r.JitterMs), fmt.Sprintf("%.2g%%", r.LostPercent)
+			} else {
+				row[6] = fmt.Sprint(r.Retransmits)
+			}
+		}
+
+		if wide {
+			row = append(row, r.ClientIP, r.ServerIP, fmt.Sprint(r.Reverse), fmt.Sprintf("%.2f", r.Seconds), r.Error)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
@@ -0,0 +1,429 @@
+package ping
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/fabedge/fabctl/pkg/cmd/nettool"
+	"github.com/fabedge/fabctl/pkg/printer"
+	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
+)
+
+// matrixFlags select nodes to ping each other
+type matrixFlags struct {
+	AllEdges    bool
+	Selector    string
+	Community   string
+	Parallelism int
+}
+
+func addMatrixFlags(mf *matrixFlags, fs *pflag.FlagSet) {
+	fs.BoolVar(&mf.AllEdges, "all-edges", false, "Ping between every pair of edge nodes")
+	fs.StringVarP(&mf.Selector, "selector", "l", "", "Ping between every pair of nodes matched by this label selector")
+	fs.StringVar(&mf.Community, "community", "", "Ping between every pair of edge nodes of current cluster in this community")
+	fs.IntVar(&mf.Parallelism, "parallelism", 10, "The maximum number of pods to prepare or pings to execute at the same time")
+}
+
+func (mf *matrixFlags) enabled() bool {
+	return mf.AllEdges || mf.Selector != "" || mf.Community != ""
+}
+
+// Pair is the result of ping from a node to another node through a path
+type Pair struct {
+	Path   string `json:"path"`
+	From   string `json:"from"`
+	FromIP string `json:"fromIP,omitempty"`
+	To     string `json:"to"`
+	ToIP   string `json:"toIP,omitempty"`
+	Stats
+	Error string `json:"error,omitempty"`
+}
+
+// Failed returns true if ping can't be executed or any packet is lost
+func (p Pair) Failed() bool {
+	return p.Error != "" || p.Loss > 0
+}
+
+type Matrix struct {
+	Nodes []string `json:"nodes"`
+	Paths []string `json:"paths"`
+	Pairs []Pair   `json:"pairs"`
+	// paths are kept to report CHILD SAs
+	paths []Path
+}
+
+// runMatrix pings between every pair of nodes through every path and prints results
+func runMatrix(client *types.Client, p printer.Printer, nodeNames []string, paths []Path, parallelism int, opts *options) {
+	if len(nodeNames) < 2 {
+		util.Exitf("at least two nodes are required, but %d nodes are selected\n", len(nodeNames))
+	}
+
+	// net-tool pods are indexed by network (0 for pod network and 1 for host network) and node
+	var (
+		pods [2][]corev1.Pod
+		errs [2][]error
+		used [2]bool
+	)
+	for _, path := range paths {
+		used[networkIndex(path.FromHost)] = true
+		used[networkIndex(path.ToHost)] = true
+	}
+
+	for network := range pods {
+		pods[network] = make([]corev1.Pod, len(nodeNames))
+		errs[network] = make([]error, len(nodeNames))
+		if !used[network] {
+			continue
+		}
+
+		util.Parallel(parallelism, len(nodeNames), func(i int) {
+			ctx, cancel := context.WithTimeout(context.Background(), opts.prepareTimeout)
+			defer cancel()
+
+			pods[network][i], errs[network][i] = nettool.GetOrCreatePod(ctx, client, nodeNames[i], opts.image, network == 1, opts.prepareTimeout)
+		})
+	}
+
+	type pairIndex struct {
+		path     Path
+		from, to int
+	}
+
+	var (
+		pairs   []Pair
+		indexes []pairIndex
+	)
+	for _, path := range paths {
+		for i := range nodeNames {
+			for j := range nodeNames {
+				if i != j {
+					pairs = append(pairs, Pair{Path: path.Name, From: nodeNames[i], To: nodeNames[j]})
+					indexes = append(indexes, pairIndex{path: path, from: i, to: j})
+				}
+			}
+		}
+	}
+
+	// ping may not exit on time if deadline is not specified, give it enough time
+	timeout := time.Duration(opts.pingCount+opts.pingDeadline)*time.Second + 30*time.Second
+	util.Parallel(parallelism, len(pairs), func(k int) {
+		pair, index := &pairs[k], indexes[k]
+		fromNet, toNet := networkIndex(index.path.FromHost), networkIndex(index.path.ToHost)
+		fromPod, toPod := pods[fromNet][index.from], pods[toNet][index.to]
+		pair.FromIP, pair.ToIP = fromPod.Status.PodIP, toPod.Status.PodIP
+
+		switch {
+		case errs[fromNet][index.from] != nil:
+			pair.Error = fmt.Sprintf("net-tool pod %s is not ready: %s", nettool.PodName(pair.From, index.path.FromHost), errs[fromNet][index.from])
+			return
+		case errs[toNet][index.to] != nil:
+			pair.Error = fmt.Sprintf("net-tool pod %s is not ready: %s", nettool.PodName(pair.To, index.path.ToHost), errs[toNet][index.to])
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		result := client.ExecCapture(ctx, fromPod.Name, nettool.ContainerName, pingCommand(pair.ToIP, opts.pingDeadline, opts.pingCount))
+		if result.Err != nil {
+			pair.Error = result.Err.Error()
+			return
+		}
+
+		// ping exits with non-zero code when packets are lost, statistics are still printed
+		stats, err := parseStats(result.Stdout)
+		if err != nil {
+			pair.Error = fmt.Sprintf("%s: %s", err, strings.TrimSpace(result.Stderr))
+			return
+		}
+		pair.Stats = stats
+	})
+
+	if !opts.keepPods {
+		for network := range pods {
+			util.Parallel(parallelism, len(nodeNames), func(i int) {
+				if pods[network][i].Name == "" {
+					return
+				}
+
+				ctx, cancel := context.WithTimeout(context.Background(), opts.prepareTimeout)
+				defer cancel()
+
+				nettool.DeletePod(ctx, client, pods[network][i])
+			})
+		}
+	}
+
+	matrix := Matrix{Nodes: nodeNames, Pairs: pairs, paths: paths}
+	for _, path := range paths {
+		matrix.Paths = append(matrix.Paths, path.Name)
+	}
+	util.CheckError(p.Print(matrix))
+
+	if failed := matrix.Failed(); failed > 0 {
+		util.Exitf("%d of %d pairs failed\n", failed, len(pairs))
+	}
+}
+
+func networkIndex(hostNetwork bool) int {
+	if hostNetwork {
+		return 1
+	}
+
+	return 0
+}
+
+// getNodeNames returns names of nodes selected by flags, only one of them can be used
+func getNodeNames(client *types.Client, mf *matrixFlags) ([]string, error) {
+	count := 0
+	for _, used := range []bool{mf.AllEdges, mf.Selector != "", mf.Community != ""} {
+		if used {
+			count++
+		}
+	}
+	if count > 1 {
+		return nil, fmt.Errorf("only one of --all-edges, --selector and --community can be used")
+	}
+
+	ctx := context.Background()
+	if mf.Selector != "" {
+		nodes, err := client.ListNodesBySelector(ctx, mf.Selector)
+		if err != nil {
+			return nil, err
+		}
+
+		return nodeNamesOf(nodes), nil
+	}
+
+	cluster := types.NewCluster(client)
+	if err := cluster.ExtractArgumentsFromFabEdge(); err != nil {
+		return nil, err
+	}
+
+	nodes, err := client.ListNodes(ctx, cluster.EdgeLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	if mf.AllEdges {
+		return nodeNamesOf(nodes), nil
+	}
+
+	if err = cluster.LoadCommunities(); err != nil {
+		return nil, err
+	}
+
+	community, ok := cluster.Communities[mf.Community]
+	if !ok {
+		return nil, fmt.Errorf("community %s is not found", mf.Community)
+	}
+
+	members := sets.NewString(community.Spec.Members...)
+	var selected []corev1.Node
+	for _, node := range nodes {
+		ep := cluster.NewEndpoint(node)
+		if members.Has(ep.Name) {
+			selected = append(selected, node)
+			members.Delete(ep.Name)
+		}
+	}
+
+	// connectors and edges of other clusters can't be reached by net-tool pods of current cluster
+	if members.Len() > 0 {
+		fmt.Fprintf(os.Stderr, "members not in current cluster's edges are skipped: %s\n", strings.Join(members.List(), ", "))
+	}
+
+	return nodeNamesOf(selected), nil
+}
+
+func nodeNamesOf(nodes []corev1.Node) []string {
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+
+	return sets.NewString(names...).List()
+}
+
+// Failed returns how many pairs failed
+func (m Matrix) Failed() int {
+	count := 0
+	for _, pair := range m.Pairs {
+		if pair.Failed() {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Describe prints a matrix of packet loss and average RTT for each path, each row is the source node and
+// each column is the target node. If multiple paths are tested, CHILD SAs of paths are summarized.
+func (m Matrix) Describe(w io.Writer) {
+	cells := make(map[string]map[string]Pair)
+	for _, pair := range m.Pairs {
+		key := pair.Path + "/" + pair.From
+		if cells[key] == nil {
+			cells[key] = make(map[string]Pair)
+		}
+		cells[key][pair.To] = pair
+	}
+
+	for n, path := range m.paths {
+		if len(m.paths) > 1 {
+			if n > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "%s:\n", path)
+		}
+
+		header := []string{"FROM \\ TO"}
+		for i := range m.Nodes {
+			header = append(header, fmt.Sprint(i+1))
+		}
+
+		var rows [][]string
+		for i, from := range m.Nodes {
+			row := []string{fmt.Sprintf("%d: %s", i+1, from)}
+			for _, to := range m.Nodes {
+				pair, ok := cells[path.Name+"/"+from][to]
+				if !ok {
+					row = append(row, "-")
+					continue
+				}
+				row = append(row, cellOf(pair))
+			}
+			rows = append(rows, row)
+		}
+
+		printer.PrintTable(w, printer.SimpleTable{Columns: header, Data: rows}, false)
+	}
+	fmt.Fprintln(w, "\nCells are packet loss/average RTT, ERR means ping can't be executed")
+
+	if len(m.paths) > 1 {
+		fmt.Fprintln(w)
+		printer.PrintTable(w, m.pathSummary(), false)
+	}
+
+	var failures []string
+	for _, pair := range m.Pairs {
+		if !pair.Failed() {
+			continue
+		}
+
+		if pair.Error != "" {
+			failures = append(failures, fmt.Sprintf("%s %s -> %s: %s", pair.Path, pair.From, pair.To, pair.Error))
+		} else {
+			failures = append(failures, fmt.Sprintf("%s %s(%s) -> %s(%s): %d/%d received, %g%% packet loss",
+				pair.Path, pair.From, pair.FromIP, pair.To, pair.ToIP, pair.Received, pair.Transmitted, pair.Loss))
+		}
+	}
+
+	if len(failures) > 0 {
+		fmt.Fprintf(w, "\nFailed pairs:\n  %s\n", strings.Join(failures, "\n  "))
+	}
+}
+
+// pathSummary counts failed pairs of each path. A CHILD SA appears broken if all pairs of its path
+// failed while pairs of node->node path, which don't go through tunnels, are fine.
+func (m Matrix) pathSummary() printer.SimpleTable {
+	total, failed := make(map[string]int), make(map[string]int)
+	for _, pair := range m.Pairs {
+		total[pair.Path]++
+		if pair.Failed() {
+			failed[pair.Path]++
+		}
+	}
+
+	summary := printer.SimpleTable{Columns: []string{"PATH", "CHILD-SA", "FAILED", "STATUS"}}
+	for _, path := range m.paths {
+		status := "ok"
+		switch {
+		case failed[path.Name] == 0:
+		case failed[path.Name] < total[path.Name]:
+			status = "partially broken"
+		case path.ChildSA == "":
+			status = "broken, check underlay network"
+		case failed[pathN2N.Name] == 0 && total[pathN2N.Name] > 0:
+			status = fmt.Sprintf("broken, check %s CHILD SAs", path.ChildSA)
+		default:
+			status = "broken"
+		}
+
+		childSA := path.ChildSA
+		if childSA == "" {
+			childSA = "-"
+		}
+
+		summary.Data = append(summary.Data, []string{
+			path.Name,
+			childSA,
+			fmt.Sprintf("%d/%d", failed[path.Name], total[path.Name]),
+			status,
+		})
+	}
+
+	return summary
+}
+
+func cellOf(pair Pair) string {
+	switch {
+	case pair.Error != "":
+		return "ERR"
+	case pair.Received == 0:
+		return fmt.Sprintf("%g%%", pair.Loss)
+	default:
+		return fmt.Sprintf("%g%%/%.2fms", pair.Loss, pair.AvgRTT)
+	}
+}
+
+func (m Matrix) Header(wide bool) []string {
+	header := []string{"PATH", "FROM", "TO", "LOSS", "AVG-RTT"}
+	if wide {
+		header = append(header, "FROM-IP", "TO-IP", "TRANSMITTED", "RECEIVED", "MIN-RTT", "MAX-RTT", "ERROR")
+	}
+
+	return header
+}
+
+func (m Matrix) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, pair := range m.Pairs {
+		row := []string{pair.Path, pair.From, pair.To, "", ""}
+		if pair.Error == "" {
+			row[3] = fmt.Sprintf("%g%%", pair.Loss)
+			if pair.Received > 0 {
+				row[4] = fmt.Sprintf("%.3fms", pair.AvgRTT)
+			}
+		}
+
+		if wide {
+			var minRTT, maxRTT string
+			if pair.Received > 0 {
+				minRTT, maxRTT = fmt.Sprintf("%.3fms", pair.MinRTT), fmt.Sprintf("%.3fms", pair.MaxRTT)
+			}
+
+			row = append(row,
+				pair.FromIP,
+				pair.ToIP,
+				fmt.Sprint(pair.Transmitted),
+				fmt.Sprint(pair.Received),
+				minRTT,
+				maxRTT,
+				pair.Error,
+			)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
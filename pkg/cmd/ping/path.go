@@ -0,0 +1,46 @@
+package ping
+
+import "fmt"
+
+const (
+	ModePod   = "pod"
+	ModeHost  = "host"
+	ModeMixed = "mixed"
+)
+
+// Path describes networks of source and destination of ping and the CHILD SA of FabEdge
+// which packets go through. Node to node traffic doesn't go through tunnels.
+type Path struct {
+	Name     string
+	FromHost bool
+	ToHost   bool
+	ChildSA  string
+}
+
+var (
+	pathP2P = Path{Name: "pod->pod", ChildSA: "p2p"}
+	pathN2P = Path{Name: "node->pod", FromHost: true, ChildSA: "n2p"}
+	pathP2N = Path{Name: "pod->node", ToHost: true, ChildSA: "p2n"}
+	pathN2N = Path{Name: "node->node", FromHost: true, ToHost: true}
+)
+
+func pathsOf(mode string) ([]Path, error) {
+	switch mode {
+	case ModePod:
+		return []Path{pathP2P}, nil
+	case ModeHost:
+		return []Path{pathN2N}, nil
+	case ModeMixed:
+		return []Path{pathP2P, pathN2P, pathP2N, pathN2N}, nil
+	default:
+		return nil, fmt.Errorf("unknown mode: %s, it should be one of pod, host and mixed", mode)
+	}
+}
+
+func (p Path) String() string {
+	if p.ChildSA == "" {
+		return p.Name
+	}
+
+	return fmt.Sprintf("%s(%s)", p.Name, p.ChildSA)
+}
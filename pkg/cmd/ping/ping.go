@@ -3,146 +3,215 @@ package ping
 import (
 	"context"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/wait"
 
+	"github.com/fabedge/fabctl/pkg/cmd/nettool"
 	"github.com/fabedge/fabctl/pkg/types"
 	"github.com/fabedge/fabctl/pkg/util"
-	"github.com/fabedge/fabedge/pkg/common/constants"
 )
 
-const containerName = "net-tool"
+type options struct {
+	image          string
+	prepareTimeout time.Duration
+	pingDeadline   uint
+	pingCount      uint
+	keepPods       bool
+	mode           string
+}
 
 func New(clientGetter types.ClientGetter) *cobra.Command {
-	var image string
-	var prepareTimeout time.Duration
-	var pingDeadline uint
-	var pingCount uint
-	var keepPods bool
+	opts := &options{}
+	mf := &matrixFlags{}
 
 	cmd := &cobra.Command{
-		Use:   "ping nodeName nodeName",
+		Use:   "ping [nodeName target] [flags]",
 		Short: "Test if network between two nodes if works",
-		Args:  cobra.ExactArgs(2),
+		Long: `Test if network between two nodes if works. If --all-edges, --selector or --community is provided,
+ping is executed between every pair of selected nodes and a matrix of packet loss and RTT is printed.
+
+With --mode host, ping is executed between host-network net-tool pods. With --mode mixed, all of pod->pod,
+node->pod, pod->node and node->node are tested and the CHILD SAs (p2p, n2p and p2n) which appear broken are reported.
+
+Target can be one of:
+  node:NAME or NAME          net-tool pod on the node, ping is executed in both directions
+  pod:NAMESPACE/NAME         IP of the pod
+  svc:NAMESPACE/NAME         cluster IP of the service, it may not answer ping when kube-proxy works in iptables mode
+  ip:ADDRESS                 any address
+  endpoint:CLUSTER.NODE      first node address of the endpoint found in Cluster resources`,
+		Example: `
+fabctl ping edge1 edge2
+fabctl ping edge1 pod:default/nginx-6799fc88d8-xl7dc
+fabctl ping edge1 svc:default/nginx
+fabctl ping edge1 ip:10.233.64.10
+fabctl ping edge1 endpoint:shanghai.edge1
+fabctl ping --all-edges
+fabctl ping --selector topology.kubernetes.io/zone=beijing
+fabctl ping --community beijing-edges -o wide
+fabctl ping edge1 edge2 --mode mixed
+fabctl ping --all-edges --mode mixed
+`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if mf.enabled() {
+				return cobra.NoArgs(cmd, args)
+			}
+
+			return cobra.ExactArgs(2)(cmd, args)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
-			if pingCount == 0 {
+			if opts.pingCount == 0 {
 				util.Exitf("Ping count should be greater that zero\n")
 			}
 
 			client, err := clientGetter.GetClient()
 			util.CheckError(err)
 
-			// prepare net tool pods
-			var pod1, pod2 corev1.Pod
-			func() {
-				ctx, cancel := context.WithTimeout(context.Background(), prepareTimeout)
-				defer cancel()
-
-				nodeName1, nodeName2 := args[0], args[1]
-				pod1 = getOrCreateNetToolPod(ctx, client, nodeName1, image, prepareTimeout)
-				pod2 = getOrCreateNetToolPod(ctx, client, nodeName2, image, prepareTimeout)
-			}()
-
-			if !keepPods {
-				// delete net tool pods
-				defer func() {
-					ctx, cancel := context.WithTimeout(context.Background(), prepareTimeout)
-					defer cancel()
-
-					deletePod(ctx, client, pod1)
-					deletePod(ctx, client, pod2)
-				}()
+			paths, err := pathsOf(opts.mode)
+			util.CheckError(err)
+
+			p, err := clientGetter.GetPrinter()
+			util.CheckError(err)
+
+			if mf.enabled() {
+				nodeNames, err := getNodeNames(client, mf)
+				util.CheckError(err)
+
+				runMatrix(client, p, nodeNames, paths, mf.Parallelism, opts)
+				return
+			}
+
+			source, err := nettool.ParseTarget(args[0])
+			util.CheckError(err)
+			if source.Kind != nettool.TargetNode {
+				util.Exitf("ping can only be executed from a node, but got %s\n", source)
 			}
 
-			ping(client, pod1, pod2, pingDeadline, pingCount)
-			ping(client, pod2, pod1, pingDeadline, pingCount)
+			target, err := nettool.ParseTarget(args[1])
+			util.CheckError(err)
+
+			switch {
+			case target.Kind != nettool.TargetNode:
+				pingTarget(client, source.Name, target, opts)
+			case opts.mode == ModePod:
+				pingNodes(client, source.Name, target.Name, opts)
+			default:
+				runMatrix(client, p, []string{source.Name, target.Name}, paths, mf.Parallelism, opts)
+			}
 		},
 	}
 
 	fs := cmd.Flags()
-	fs.StringVarP(&image, "net-tool-image", "i", "praqma/network-multitool:minimal", "The image of net-tool pod")
-	fs.DurationVar(&prepareTimeout, "prepare-timeout", 30*time.Second, "The length of time to prepare net-tool pods which are used to execute ping command")
-	fs.UintVar(&pingDeadline, "ping-deadline", 0, "The deadline argument of ping command")
-	fs.UintVar(&pingCount, "ping-count", 5, "The count argument of ping command")
-	fs.BoolVarP(&keepPods, "keep", "k", false, "keep pods after test if finished")
+	fs.StringVarP(&opts.image, "net-tool-image", "i", "praqma/network-multitool:minimal", "The image of net-tool pod")
+	fs.DurationVar(&opts.prepareTimeout, "prepare-timeout", 30*time.Second, "The length of time to prepare net-tool pods which are used to execute ping command")
+	fs.UintVar(&opts.pingDeadline, "ping-deadline", 0, "The deadline argument of ping command")
+	fs.UintVar(&opts.pingCount, "ping-count", 5, "The count argument of ping command")
+	fs.BoolVarP(&opts.keepPods, "keep", "k", false, "keep pods after test if finished")
+	fs.StringVar(&opts.mode, "mode", ModePod, "The network of net-tool pods, one of pod, host and mixed")
+	addMatrixFlags(mf, fs)
+
 	return cmd
 }
 
-func getOrCreateNetToolPod(ctx context.Context, client *types.Client, nodeName string, image string, timeout time.Duration) corev1.Pod {
-	var (
-		podName   = fmt.Sprintf("net-tool-%s", nodeName)
-		namespace = client.GetNamespace()
-
-		pod corev1.Pod
-		key = types.ObjectKey{Name: podName, Namespace: namespace}
-	)
+func pingNodes(client *types.Client, nodeName1, nodeName2 string, opts *options) {
+	// prepare net tool pods
+	var pod1, pod2 corev1.Pod
+	func() {
+		ctx, cancel := context.WithTimeout(context.Background(), opts.prepareTimeout)
+		defer cancel()
 
-	err := client.Get(ctx, key, &pod)
-	switch {
-	case err == nil:
-	case !errors.IsNotFound(err):
+		var err error
+		pod1, err = nettool.GetOrCreatePod(ctx, client, nodeName1, opts.image, false, opts.prepareTimeout)
 		util.CheckError(err)
+		pod2, err = nettool.GetOrCreatePod(ctx, client, nodeName2, opts.image, false, opts.prepareTimeout)
+		util.CheckError(err)
+	}()
+
+	if !opts.keepPods {
+		// delete net tool pods
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), opts.prepareTimeout)
+			defer cancel()
+
+			nettool.DeletePod(ctx, client, pod1)
+			nettool.DeletePod(ctx, client, pod2)
+		}()
+	}
+
+	ping(client, pod1, pod2, opts.pingDeadline, opts.pingCount)
+	ping(client, pod2, pod1, opts.pingDeadline, opts.pingCount)
+}
+
+// pingTarget pings a target which is not a node from net-tool pods on specified node, the network
+// of net-tool pods depends on mode
+func pingTarget(client *types.Client, nodeName string, target nettool.Target, opts *options) {
+	var hostNetworks []bool
+	switch opts.mode {
+	case ModePod:
+		hostNetworks = []bool{false}
+	case ModeHost:
+		hostNetworks = []bool{true}
 	default:
-		pod = corev1.Pod{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      podName,
-				Namespace: client.GetNamespace(),
-				Labels: map[string]string{
-					constants.KeyFabEdgeAPP: "net-tool",
-					constants.KeyCreatedBy:  "fabctl",
-				},
-			},
-			Spec: corev1.PodSpec{
-				HostNetwork: false,
-				NodeName:    nodeName,
-				DNSPolicy:   corev1.DNSClusterFirstWithHostNet,
-				// workaround, or it will fail at edgecore
-				AutomountServiceAccountToken: new(bool),
-				Containers: []corev1.Container{
-					{
-						Name:            containerName,
-						Image:           image,
-						ImagePullPolicy: corev1.PullIfNotPresent,
-						Ports: []corev1.ContainerPort{
-							{
-								Name:          "http",
-								ContainerPort: 80,
-							},
-						},
-					},
-				},
-				Tolerations: []corev1.Toleration{
-					{
-						Key:      "",
-						Operator: corev1.TolerationOpExists,
-					},
-				},
-			},
+		hostNetworks = []bool{false, true}
+	}
+
+	var (
+		pods []corev1.Pod
+		ip   string
+	)
+	err := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), opts.prepareTimeout)
+		defer cancel()
+
+		var err error
+		if ip, err = target.ResolveIP(ctx, client); err != nil {
+			return err
 		}
 
-		util.CheckError(client.Create(ctx, &pod))
+		for _, hostNetwork := range hostNetworks {
+			pod, err := nettool.GetOrCreatePod(ctx, client, nodeName, opts.image, hostNetwork, opts.prepareTimeout)
+			if pod.Name != "" {
+				pods = append(pods, pod)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}()
+
+	if err == nil {
+		for _, pod := range pods {
+			fmt.Printf("Ping from %s(%s) -> %s(%s) \n\n", pod.Name, pod.Status.PodIP, target, ip)
+			if err = client.Exec(pod.Name, nettool.ContainerName, pingCommand(ip, opts.pingDeadline, opts.pingCount)); err != nil {
+				break
+			}
+		}
 	}
 
-	err = wait.PollImmediate(time.Second, timeout, func() (bool, error) {
-		err = client.Get(ctx, key, &pod)
-		if err != nil {
-			return false, err
+	// pods are deleted before CheckError, which exits without running deferred functions
+	if !opts.keepPods {
+		ctx, cancel := context.WithTimeout(context.Background(), opts.prepareTimeout)
+		defer cancel()
+
+		for _, pod := range pods {
+			nettool.DeletePod(ctx, client, pod)
 		}
+	}
 
-		return pod.Status.Phase == corev1.PodRunning, nil
-	})
 	util.CheckError(err)
-
-	return pod
 }
 
 func ping(client *types.Client, pod1, pod2 corev1.Pod, deadline, count uint) {
+	cmd := pingCommand(pod2.Status.PodIP, deadline, count)
+
+	fmt.Printf("Ping from %s(%s) -> %s(%s) \n\n", pod1.Name, pod1.Status.PodIP, pod2.Name, pod2.Status.PodIP)
+	util.CheckError(client.Exec(pod1.Name, nettool.ContainerName, cmd))
+}
+
+func pingCommand(ip string, deadline, count uint) []string {
 	cmd := []string{"ping"}
 
 	if deadline > 0 {
@@ -153,15 +222,6 @@ func ping(client *types.Client, pod1, pod2 corev1.Pod, deadline, count uint) {
 		count = 1
 	}
 	cmd = append(cmd, "-c", fmt.Sprint(count))
-	cmd = append(cmd, pod2.Status.PodIP)
-
-	fmt.Printf("Ping from %s(%s) -> %s(%s) \n\n", pod1.Name, pod1.Status.PodIP, pod2.Name, pod2.Status.PodIP)
-	util.CheckError(client.Exec(pod1.Name, containerName, cmd))
-}
 
-func deletePod(ctx context.Context, client *types.Client, pod corev1.Pod) {
-	err := client.Delete(ctx, &pod)
-	if err != nil && !errors.IsNotFound(err) {
-		fmt.Fprintf(os.Stderr, "failed to delete pod %s/%s", pod.Namespace, pod.Namespace)
-	}
+	return append(cmd, ip)
 }
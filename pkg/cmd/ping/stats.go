@@ -0,0 +1,47 @@
+package ping
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// e.g. "5 packets transmitted, 5 received, 0% packet loss" of iputils
+	// or "5 packets transmitted, 5 packets received, 0% packet loss" of busybox
+	packetsRegexp = regexp.MustCompile(`(\d+) packets transmitted, (\d+) (?:packets )?received.*?([\d.]+)% packet loss`)
+	// e.g. "rtt min/avg/max/mdev = 0.477/0.592/0.812/0.123 ms" or "round-trip min/avg/max = 0.477/0.592/0.812 ms"
+	rttRegexp = regexp.MustCompile(`min/avg/max\S* = ([\d.]+)/([\d.]+)/([\d.]+)`)
+)
+
+// Stats is the statistics of ping, RTTs are in milliseconds and they are zero if no packets are received
+type Stats struct {
+	Transmitted int     `json:"transmitted"`
+	Received    int     `json:"received"`
+	Loss        float64 `json:"loss"`
+	MinRTT      float64 `json:"minRTT"`
+	AvgRTT      float64 `json:"avgRTT"`
+	MaxRTT      float64 `json:"maxRTT"`
+}
+
+// parseStats parses statistics from output of ping command
+func parseStats(output string) (Stats, error) {
+	var stats Stats
+
+	matches := packetsRegexp.FindStringSubmatch(output)
+	if matches == nil {
+		return stats, fmt.Errorf("no ping statistics found")
+	}
+
+	stats.Transmitted, _ = strconv.Atoi(matches[1])
+	stats.Received, _ = strconv.Atoi(matches[2])
+	stats.Loss, _ = strconv.ParseFloat(matches[3], 64)
+
+	if matches = rttRegexp.FindStringSubmatch(output); matches != nil {
+		stats.MinRTT, _ = strconv.ParseFloat(matches[1], 64)
+		stats.AvgRTT, _ = strconv.ParseFloat(matches[2], 64)
+		stats.MaxRTT, _ = strconv.ParseFloat(matches[3], 64)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,70 @@
+package ping
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStats(t *testing.T) {
+	testCases := []struct {
+		name     string
+		output   string
+		expected Stats
+		err      bool
+	}{
+		{
+			name: "iputils",
+			output: `PING 10.233.67.5 (10.233.67.5) 56(84) bytes of data.
+64 bytes from 10.233.67.5: icmp_seq=1 ttl=62 time=0.812 ms
+64 bytes from 10.233.67.5: icmp_seq=2 ttl=62 time=0.477 ms
+64 bytes from 10.233.67.5: icmp_seq=3 ttl=62 time=0.533 ms
+64 bytes from 10.233.67.5: icmp_seq=4 ttl=62 time=0.562 ms
+64 bytes from 10.233.67.5: icmp_seq=5 ttl=62 time=0.576 ms
+
+--- 10.233.67.5 ping statistics ---
+5 packets transmitted, 5 received, 0% packet loss, time 4005ms
+rtt min/avg/max/mdev = 0.477/0.592/0.812/0.123 ms
+`,
+			expected: Stats{Transmitted: 5, Received: 5, Loss: 0, MinRTT: 0.477, AvgRTT: 0.592, MaxRTT: 0.812},
+		},
+		{
+			name: "busybox",
+			output: `PING 10.233.67.5 (10.233.67.5): 56 data bytes
+64 bytes from 10.233.67.5: seq=0 ttl=62 time=1.018 ms
+64 bytes from 10.233.67.5: seq=2 ttl=62 time=0.654 ms
+
+--- 10.233.67.5 ping statistics ---
+3 packets transmitted, 2 packets received, 33% packet loss
+round-trip min/avg/max = 0.654/0.836/1.018 ms
+`,
+			expected: Stats{Transmitted: 3, Received: 2, Loss: 33, MinRTT: 0.654, AvgRTT: 0.836, MaxRTT: 1.018},
+		},
+		{
+			name: "no reply",
+			output: `PING 10.233.67.5 (10.233.67.5) 56(84) bytes of data.
+
+--- 10.233.67.5 ping statistics ---
+5 packets transmitted, 0 received, +3 errors, 100% packet loss, time 4077ms
+`,
+			expected: Stats{Transmitted: 5, Received: 0, Loss: 100},
+		},
+		{
+			name:   "no statistics",
+			output: "ping: bad address 'edge3'\n",
+			err:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stats, err := parseStats(tc.output)
+			if (err != nil) != tc.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(stats, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, stats)
+			}
+		})
+	}
+}
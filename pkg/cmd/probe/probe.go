@@ -0,0 +1,192 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/fabedge/fabctl/pkg/cmd/nettool"
+	"github.com/fabedge/fabctl/pkg/printer"
+	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
+)
+
+type options struct {
+	image          string
+	prepareTimeout time.Duration
+	keepPods       bool
+	hostNetwork    bool
+	timeout        time.Duration
+}
+
+// Result is the result of a probe from net-tool pod on a node, latencies are in milliseconds
+type Result struct {
+	Probe     string   `json:"probe"`
+	Node      string   `json:"node"`
+	Pod       string   `json:"pod"`
+	Target    string   `json:"target"`
+	Address   string   `json:"address"`
+	Success   bool     `json:"success"`
+	Status    string   `json:"status,omitempty"`
+	ConnectMs float64  `json:"connectMs,omitempty"`
+	TotalMs   float64  `json:"totalMs,omitempty"`
+	Answers   []string `json:"answers,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// session holds net-tool pods prepared for a probe
+type session struct {
+	client *types.Client
+	opts   *options
+	source corev1.Pod
+	pods   []corev1.Pod
+}
+
+func New(clientGetter types.ClientGetter) *cobra.Command {
+	opts := &options{}
+
+	cmd := &cobra.Command{
+		Use:   "probe [command] nodeName target [flags]",
+		Short: "Probe TCP, HTTP, UDP or DNS services from net-tool pod on specified node",
+		Long: `Probe TCP, HTTP, UDP or DNS services from net-tool pod on specified node.
+
+Target can be one of:
+  node:NAME or NAME          net-tool pod on the node, it listens on HTTP port 30080 and HTTPS port 30443
+  pod:NAMESPACE/NAME         IP of the pod
+  svc:NAMESPACE/NAME         cluster IP of the service
+  ip:ADDRESS                 any address
+  endpoint:CLUSTER.NODE      first node address of the endpoint found in Cluster resources`,
+	}
+
+	fs := cmd.PersistentFlags()
+	fs.StringVarP(&opts.image, "net-tool-image", "i", "praqma/network-multitool:minimal", "The image of net-tool pod")
+	fs.DurationVar(&opts.prepareTimeout, "prepare-timeout", 30*time.Second, "The length of time to prepare net-tool pods")
+	fs.BoolVarP(&opts.keepPods, "keep", "k", false, "keep pods after test if finished")
+	fs.BoolVar(&opts.hostNetwork, "host", false, "Use host-network net-tool pods")
+	fs.DurationVar(&opts.timeout, "timeout", 5*time.Second, "The length of time to wait for a probe")
+
+	cmd.AddCommand(newTCPCommand(clientGetter, opts))
+	cmd.AddCommand(newHTTPCommand(clientGetter, opts))
+	cmd.AddCommand(newUDPCommand(clientGetter, opts))
+	cmd.AddCommand(newDNSCommand(clientGetter, opts))
+
+	return cmd
+}
+
+// run prepares net-tool pod on nodeName, executes fn, then prints result and exits if probe failed
+func run(clientGetter types.ClientGetter, opts *options, probe, nodeName string, fn func(s *session) Result) {
+	p, err := clientGetter.GetPrinter()
+	util.CheckError(err)
+
+	client, err := clientGetter.GetClient()
+	util.CheckError(err)
+
+	s := &session{client: client, opts: opts}
+	// pods prepared before the error are deleted by close
+	if s.source, err = s.preparePod(nodeName); err != nil {
+		s.close()
+		util.CheckError(err)
+	}
+
+	result := fn(s)
+	result.Probe, result.Node, result.Pod = probe, nodeName, s.source.Name
+
+	s.close()
+
+	util.CheckError(p.Print(result))
+	if !result.Success {
+		util.Exitf("%s probe to %s failed\n", probe, result.Target)
+	}
+}
+
+// preparePod gets or creates net-tool pod on specified node, the pod is deleted when session is closed
+func (s *session) preparePod(nodeName string) (corev1.Pod, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.prepareTimeout)
+	defer cancel()
+
+	pod, err := nettool.GetOrCreatePod(ctx, s.client, nodeName, s.opts.image, s.opts.hostNetwork, s.opts.prepareTimeout)
+	if pod.Name != "" {
+		s.pods = append(s.pods, pod)
+	}
+
+	return pod, err
+}
+
+func (s *session) close() {
+	if s.opts.keepPods {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.prepareTimeout)
+	defer cancel()
+
+	for _, pod := range s.pods {
+		nettool.DeletePod(ctx, s.client, pod)
+	}
+}
+
+// exec executes cmd in source net-tool pod, the execution is interrupted if it lasts too long
+func (s *session) exec(cmd ...string) types.ExecResult {
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.timeout+10*time.Second)
+	defer cancel()
+
+	return s.client.ExecCapture(ctx, s.source.Name, nettool.ContainerName, cmd)
+}
+
+func (r Result) Describe(w io.Writer) {
+	kvs := []printer.KeyValue{
+		{Key: "Probe", Value: r.Probe},
+		{Key: "From", Value: fmt.Sprintf("%s(%s)", r.Node, r.Pod)},
+		{Key: "Target", Value: r.Target},
+		{Key: "Address", Value: r.Address},
+		{Key: "Success", Value: fmt.Sprint(r.Success)},
+	}
+
+	if r.Status != "" {
+		kvs = append(kvs, printer.KeyValue{Key: "Status", Value: r.Status})
+	}
+	if r.ConnectMs > 0 {
+		kvs = append(kvs, printer.KeyValue{Key: "Connect Time", Value: formatMs(r.ConnectMs)})
+	}
+	if r.TotalMs > 0 {
+		kvs = append(kvs, printer.KeyValue{Key: "Total Time", Value: formatMs(r.TotalMs)})
+	}
+	if len(r.Answers) > 0 {
+		kvs = append(kvs, printer.KeyValue{Key: "Answers", Value: printer.Join(r.Answers)})
+	}
+	if r.Error != "" {
+		kvs = append(kvs, printer.KeyValue{Key: "Error", Value: r.Error})
+	}
+
+	printer.Describe(w, kvs...)
+}
+
+func (r Result) Header(wide bool) []string {
+	header := []string{"PROBE", "NODE", "TARGET", "ADDRESS", "SUCCESS", "STATUS", "TIME"}
+	if wide {
+		header = append(header, "POD", "CONNECT-TIME", "ANSWERS", "ERROR")
+	}
+
+	return header
+}
+
+func (r Result) Rows(wide bool) [][]string {
+	row := []string{r.Probe, r.Node, r.Target, r.Address, fmt.Sprint(r.Success), r.Status, formatMs(r.TotalMs)}
+	if wide {
+		row = append(row, r.Pod, formatMs(r.ConnectMs), printer.Join(r.Answers), r.Error)
+	}
+
+	return [][]string{row}
+}
+
+func formatMs(ms float64) string {
+	if ms == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%.3fms", ms)
+}
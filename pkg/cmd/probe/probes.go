@@ -0,0 +1,462 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/fabedge/fabctl/pkg/cmd/nettool"
+	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
+)
+
+var (
+	digStatusRegexp    = regexp.MustCompile(`status: (\w+)`)
+	digQueryTimeRegexp = regexp.MustCompile(`Query time: (\d+) msec`)
+	digServerRegexp    = regexp.MustCompile(`SERVER: (\S+)`)
+)
+
+// curl exit codes which are commonly seen in network problems
+var curlErrors = map[int]string{
+	6:  "could not resolve host",
+	7:  "failed to connect to host",
+	28: "operation timed out",
+	35: "SSL connect error",
+	52: "empty reply from server",
+	56: "failure in receiving network data",
+}
+
+func newTCPCommand(clientGetter types.ClientGetter, opts *options) *cobra.Command {
+	var port int32
+
+	cmd := &cobra.Command{
+		Use:   "tcp nodeName target [flags]",
+		Short: "Check if a TCP connection can be established to target",
+		Example: `
+fabctl probe tcp edge1 edge2
+fabctl probe tcp edge1 svc:default/nginx
+fabctl probe tcp edge1 ip:10.22.46.39 --port 6443
+`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			target, err := nettool.ParseTarget(args[1])
+			util.CheckError(err)
+
+			run(clientGetter, opts, "tcp", args[0], func(s *session) Result {
+				result := Result{Target: target.String()}
+
+				address, err := s.resolveAddress(target, port, nettool.DefaultHTTPPort)
+				if err != nil {
+					result.Error = err.Error()
+					return result
+				}
+				result.Address = address
+
+				// curl exits after connection is established because stdin is not attached
+				output := s.exec("curl", "-s", "-o", "/dev/null", "--max-time", seconds(opts.timeout),
+					"-w", "%{time_connect}", "telnet://"+address)
+				if err = curlError(output); err != nil {
+					result.Error = err.Error()
+					return result
+				}
+
+				result.Success = true
+				result.Status = "connected"
+				result.ConnectMs = parseSeconds(output.Stdout)
+				result.TotalMs = result.ConnectMs
+				return result
+			})
+		},
+	}
+
+	cmd.Flags().Int32Var(&port, "port", 0, "The port of target, default to HTTP port of net-tool pod for node targets and first port of pod or service")
+
+	return cmd
+}
+
+func newHTTPCommand(clientGetter types.ClientGetter, opts *options) *cobra.Command {
+	var (
+		port     int32
+		path     string
+		useHTTPS bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "http nodeName target [flags]",
+		Short: "Send an HTTP request to target and report status code and latency",
+		Example: `
+fabctl probe http edge1 edge2
+fabctl probe http edge1 edge2 --https
+fabctl probe http edge1 svc:default/nginx --path /healthz
+`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			target, err := nettool.ParseTarget(args[1])
+			util.CheckError(err)
+
+			scheme, defaultPort := "http", nettool.DefaultHTTPPort
+			if useHTTPS {
+				scheme, defaultPort = "https", nettool.DefaultHTTPSPort
+			}
+
+			run(clientGetter, opts, scheme, args[0], func(s *session) Result {
+				result := Result{Target: target.String()}
+
+				address, err := s.resolveAddress(target, port, defaultPort)
+				if err != nil {
+					result.Error = err.Error()
+					return result
+				}
+
+				if !strings.HasPrefix(path, "/") {
+					path = "/" + path
+				}
+				result.Address = fmt.Sprintf("%s://%s%s", scheme, address, path)
+
+				output := s.exec("curl", "-s", "-k", "-o", "/dev/null", "--max-time", seconds(opts.timeout),
+					"-w", "%{http_code} %{time_connect} %{time_total}", result.Address)
+				if err = curlError(output); err != nil {
+					result.Error = err.Error()
+					return result
+				}
+
+				fields := strings.Fields(output.Stdout)
+				if len(fields) != 3 {
+					result.Error = fmt.Sprintf("unexpected output of curl: %s", output.Stdout)
+					return result
+				}
+
+				// any response proves the path works, status code is reported for user to judge
+				result.Success = fields[0] != "000"
+				result.Status = fields[0]
+				result.ConnectMs = parseSeconds(fields[1])
+				result.TotalMs = parseSeconds(fields[2])
+				return result
+			})
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.Int32Var(&port, "port", 0, "The port of target, default to HTTP(S) port of net-tool pod for node targets and first port of pod or service")
+	fs.StringVar(&path, "path", "/", "The path of HTTP request")
+	fs.BoolVar(&useHTTPS, "https", false, "Use HTTPS, certificate of target is not verified")
+
+	return cmd
+}
+
+func newUDPCommand(clientGetter types.ClientGetter, opts *options) *cobra.Command {
+	var port int32
+
+	cmd := &cobra.Command{
+		Use:   "udp nodeName target [flags]",
+		Short: "Send a UDP datagram to target",
+		Long: `Send a UDP datagram to target. If target is a node, a UDP listener is started in net-tool pod on that node
+to verify the datagram is received. For other targets, probe only fails when ICMP port unreachable is received.`,
+		Example: `
+fabctl probe udp edge1 edge2
+fabctl probe udp edge1 svc:kube-system/coredns --port 53
+`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			target, err := nettool.ParseTarget(args[1])
+			util.CheckError(err)
+
+			run(clientGetter, opts, "udp", args[0], func(s *session) Result {
+				result := Result{Target: target.String()}
+
+				address, err := s.resolveAddress(target, port, nettool.DefaultHTTPPort)
+				if err != nil {
+					result.Error = err.Error()
+					return result
+				}
+				result.Address = address
+
+				if target.Kind == nettool.TargetNode {
+					probeUDPListener(s, address, &result)
+					return result
+				}
+
+				host, udpPort, _ := net.SplitHostPort(address)
+				output := s.exec("nc", "-u", "-z", "-w", seconds(opts.timeout), host, udpPort)
+				if err = output.AsError(); err != nil {
+					result.Status = "closed"
+					result.Error = err.Error()
+					return result
+				}
+
+				result.Success = true
+				result.Status = "open|filtered"
+				return result
+			})
+		},
+	}
+
+	cmd.Flags().Int32Var(&port, "port", 0, "The port of target, default to HTTP port of net-tool pod for node targets and first port of pod or service")
+
+	return cmd
+}
+
+// probeUDPListener starts a UDP listener in target net-tool pod, then sends a token to it from source pod
+func probeUDPListener(s *session, address string, result *Result) {
+	targetPod := s.pods[len(s.pods)-1]
+	host, port, _ := net.SplitHostPort(address)
+	token := fmt.Sprintf("fabctl-probe-%d", time.Now().UnixNano())
+
+	// closing the stream doesn't kill the listener, it would keep running in the pod kept by --keep,
+	// it's killed after the stream is closed
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), s.opts.timeout)
+		defer cancel()
+		s.client.ExecCapture(ctx, targetPod.Name, nettool.ContainerName, []string{"pkill", "-f", "nc -u -l.* " + port})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.timeout+5*time.Second)
+	defer cancel()
+
+	reader, writer := io.Pipe()
+	go func() {
+		// the listen flags of busybox nc and openbsd nc are different
+		listen := fmt.Sprintf("nc -u -l -p %s || nc -u -l %s", port, port)
+		err := s.client.ExecStream(ctx, targetPod.Name, nettool.ContainerName, []string{"sh", "-c", listen}, types.ExecOptions{
+			Stdout: writer,
+		})
+		writer.CloseWithError(err)
+	}()
+
+	received := make(chan time.Time, 1)
+	go func() {
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			if strings.Contains(scanner.Text(), token) {
+				received <- time.Now()
+				break
+			}
+		}
+		_, _ = io.Copy(io.Discard, reader)
+	}()
+
+	// give listener some time to start
+	time.Sleep(time.Second)
+
+	sentAt := time.Now()
+	output := s.exec("sh", "-c", fmt.Sprintf("echo %s | nc -u -w 1 %s %s", token, host, port))
+	if err := output.AsError(); err != nil {
+		result.Error = err.Error()
+		return
+	}
+
+	select {
+	case at := <-received:
+		result.Success = true
+		result.Status = "received"
+		// it includes the time of executing commands, so it's only a rough value
+		result.TotalMs = float64(at.Sub(sentAt).Microseconds()) / 1000
+	case <-ctx.Done():
+		result.Status = "lost"
+		result.Error = "datagram is not received by listener"
+	}
+}
+
+func newDNSCommand(clientGetter types.ClientGetter, opts *options) *cobra.Command {
+	var (
+		server    string
+		useFabDNS bool
+		zone      string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "dns nodeName name [flags]",
+		Short: "Resolve a domain name from net-tool pod on specified node",
+		Long: `Resolve a domain name from net-tool pod on specified node. If name is like svc:NAMESPACE/NAME, it's converted
+to NAME.NAMESPACE.svc.global which is served by fabdns, this is used to verify cross-cluster service discovery.`,
+		Example: `
+fabctl probe dns edge1 kubernetes.default.svc.cluster.local
+fabctl probe dns edge1 svc:default/nginx
+fabctl probe dns edge1 svc:default/nginx --fabdns
+`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			name := args[1]
+			if strings.HasPrefix(name, nettool.TargetService+":") {
+				key := nettool.NamespacedKey(strings.TrimPrefix(name, nettool.TargetService+":"))
+				name = fmt.Sprintf("%s.%s.svc.%s", key.Name, key.Namespace, zone)
+			}
+
+			run(clientGetter, opts, "dns", args[0], func(s *session) Result {
+				result := Result{Target: name}
+
+				if useFabDNS {
+					var svc corev1.Service
+					key := types.ObjectKey{Namespace: s.client.GetNamespace(), Name: "fabdns"}
+					if err := s.client.Get(context.Background(), key, &svc); err != nil {
+						result.Error = fmt.Sprintf("failed to get fabdns service: %s", err)
+						return result
+					}
+					server = svc.Spec.ClusterIP
+				}
+
+				digCmd := []string{"dig", fmt.Sprintf("+time=%s", seconds(opts.timeout)), "+tries=1", name}
+				if server != "" {
+					digCmd = append(digCmd, "@"+server)
+				}
+
+				output := s.exec(digCmd...)
+				if matches := digServerRegexp.FindStringSubmatch(output.Stdout); matches != nil {
+					result.Address = matches[1]
+				}
+				if err := output.AsError(); err != nil {
+					result.Error = err.Error()
+					return result
+				}
+
+				parseDig(output.Stdout, &result)
+				if result.Status == "" {
+					result.Error = fmt.Sprintf("unexpected output of dig: %s", strings.TrimSpace(output.Stdout))
+					return result
+				}
+
+				result.Success = result.Status == "NOERROR" && len(result.Answers) > 0
+				if !result.Success && result.Status == "NOERROR" {
+					result.Error = "no answers"
+				}
+				return result
+			})
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.StringVar(&server, "dns-server", "", "The DNS server to query, the nameserver of net-tool pod is used by default")
+	fs.BoolVar(&useFabDNS, "fabdns", false, "Query fabdns service directly")
+	fs.StringVar(&zone, "zone", "global", "The zone of fabdns, used to convert svc:NAMESPACE/NAME to domain name")
+
+	return cmd
+}
+
+// parseDig parses status, query time and answers from output of dig
+func parseDig(output string, result *Result) {
+	if matches := digStatusRegexp.FindStringSubmatch(output); matches != nil {
+		result.Status = matches[1]
+	}
+
+	if matches := digQueryTimeRegexp.FindStringSubmatch(output); matches != nil {
+		ms, _ := strconv.ParseFloat(matches[1], 64)
+		result.TotalMs = ms
+	}
+
+	inAnswers := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, ";; ANSWER SECTION:"):
+			inAnswers = true
+		case line == "" || strings.HasPrefix(line, ";"):
+			inAnswers = false
+		case inAnswers:
+			// e.g. nginx.default.svc.global. 5 IN A 10.233.16.100
+			if fields := strings.Fields(line); len(fields) >= 5 {
+				result.Answers = append(result.Answers, strings.Join(fields[4:], " "))
+			}
+		}
+	}
+}
+
+// resolveAddress returns host:port of target, if target is a node, net-tool pod on it is prepared
+func (s *session) resolveAddress(target nettool.Target, port, defaultPort int32) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.prepareTimeout)
+	defer cancel()
+
+	var ip string
+	if target.Kind == nettool.TargetNode {
+		pod, err := s.preparePod(target.Name)
+		if err != nil {
+			return "", err
+		}
+		ip = pod.Status.PodIP
+	} else {
+		var err error
+		if ip, err = target.ResolveIP(ctx, s.client); err != nil {
+			return "", err
+		}
+	}
+
+	if port == 0 {
+		var err error
+		if port, err = s.portOf(ctx, target); err != nil {
+			return "", err
+		}
+	}
+
+	if port == 0 {
+		if target.Kind != nettool.TargetNode {
+			return "", fmt.Errorf("--port is required for %s", target)
+		}
+		port = defaultPort
+	}
+
+	return net.JoinHostPort(ip, fmt.Sprint(port)), nil
+}
+
+// portOf returns the first port of service or pod target, 0 is returned for other targets
+func (s *session) portOf(ctx context.Context, target nettool.Target) (int32, error) {
+	switch target.Kind {
+	case nettool.TargetService:
+		var svc corev1.Service
+		if err := s.client.Get(ctx, nettool.NamespacedKey(target.Name), &svc); err != nil {
+			return 0, err
+		}
+
+		if len(svc.Spec.Ports) > 0 {
+			return svc.Spec.Ports[0].Port, nil
+		}
+	case nettool.TargetPod:
+		var pod corev1.Pod
+		if err := s.client.Get(ctx, nettool.NamespacedKey(target.Name), &pod); err != nil {
+			return 0, err
+		}
+
+		for _, c := range pod.Spec.Containers {
+			if len(c.Ports) > 0 {
+				return c.Ports[0].ContainerPort, nil
+			}
+		}
+	}
+
+	return 0, nil
+}
+
+func curlError(output types.ExecResult) error {
+	if output.Err != nil {
+		return output.Err
+	}
+
+	if msg, ok := curlErrors[output.ExitCode]; ok {
+		return fmt.Errorf("curl: (%d) %s", output.ExitCode, msg)
+	}
+
+	return output.AsError()
+}
+
+func seconds(d time.Duration) string {
+	s := int(d.Seconds())
+	if s < 1 {
+		s = 1
+	}
+
+	return fmt.Sprint(s)
+}
+
+// parseSeconds parses time printed by curl in seconds and returns milliseconds
+func parseSeconds(value string) float64 {
+	s, _ := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	return s * 1000
+}
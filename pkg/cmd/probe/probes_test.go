@@ -0,0 +1,88 @@
+package probe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDig(t *testing.T) {
+	testCases := []struct {
+		name     string
+		output   string
+		expected Result
+	}{
+		{
+			name: "answers",
+			output: `
+; <<>> DiG 9.16.20 <<>> nginx.default.svc.global
+;; global options: +cmd
+;; Got answer:
+;; ->>HEADER<<- opcode: QUERY, status: NOERROR, id: 40914
+;; flags: qr aa rd; QUERY: 1, ANSWER: 2, AUTHORITY: 0, ADDITIONAL: 1
+;; WARNING: recursion requested but not available
+
+;; OPT PSEUDOSECTION:
+; EDNS: version: 0, flags:; udp: 4096
+;; QUESTION SECTION:
+;nginx.default.svc.global.	IN	A
+
+;; ANSWER SECTION:
+nginx.default.svc.global. 5	IN	A	10.233.16.100
+nginx.default.svc.global. 5	IN	A	10.234.16.100
+
+;; Query time: 3 msec
+;; SERVER: 10.233.0.10#53(10.233.0.10)
+;; WHEN: Wed Oct 18 02:19:27 UTC 2023
+;; MSG SIZE  rcvd: 141
+`,
+			expected: Result{
+				Status:  "NOERROR",
+				TotalMs: 3,
+				Answers: []string{"10.233.16.100", "10.234.16.100"},
+			},
+		},
+		{
+			name: "cname",
+			output: `;; ->>HEADER<<- opcode: QUERY, status: NOERROR, id: 5120
+;; ANSWER SECTION:
+www.example.com.	300	IN	CNAME	example.com.
+example.com.	300	IN	A	93.184.216.34
+
+;; Query time: 21 msec
+`,
+			expected: Result{
+				Status:  "NOERROR",
+				TotalMs: 21,
+				Answers: []string{"example.com.", "93.184.216.34"},
+			},
+		},
+		{
+			name: "nxdomain",
+			output: `;; ->>HEADER<<- opcode: QUERY, status: NXDOMAIN, id: 22049
+;; flags: qr aa rd; QUERY: 1, ANSWER: 0, AUTHORITY: 1, ADDITIONAL: 1
+
+;; AUTHORITY SECTION:
+global.			30	IN	SOA	ns.dns.global. hostmaster.global. 1697595567 7200 1800 86400 30
+
+;; Query time: 1 msec
+`,
+			expected: Result{Status: "NXDOMAIN", TotalMs: 1},
+		},
+		{
+			name:     "timeout",
+			output:   ";; connection timed out; no servers could be reached\n",
+			expected: Result{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var result Result
+			parseDig(tc.output, &result)
+
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, result)
+			}
+		})
+	}
+}
@@ -5,32 +5,55 @@ import (
 
 	"github.com/fabedge/fabctl/pkg/cmd/cert"
 	"github.com/fabedge/fabctl/pkg/cmd/clusterinfo"
+	"github.com/fabedge/fabctl/pkg/cmd/clusters"
+	"github.com/fabedge/fabctl/pkg/cmd/collect"
+	"github.com/fabedge/fabctl/pkg/cmd/community"
+	"github.com/fabedge/fabctl/pkg/cmd/doctor"
 	"github.com/fabedge/fabctl/pkg/cmd/images"
+	"github.com/fabedge/fabctl/pkg/cmd/mtu"
 	"github.com/fabedge/fabctl/pkg/cmd/nettool"
+	"github.com/fabedge/fabctl/pkg/cmd/nodenet"
 	"github.com/fabedge/fabctl/pkg/cmd/nodes"
+	"github.com/fabedge/fabctl/pkg/cmd/perf"
 	"github.com/fabedge/fabctl/pkg/cmd/ping"
+	"github.com/fabedge/fabctl/pkg/cmd/probe"
 	"github.com/fabedge/fabctl/pkg/cmd/swanctl"
 	"github.com/fabedge/fabctl/pkg/cmd/topology"
+	"github.com/fabedge/fabctl/pkg/cmd/trace"
+	"github.com/fabedge/fabctl/pkg/cmd/tunnels"
 	"github.com/fabedge/fabctl/pkg/cmd/version"
 	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
 )
 
 func New() *cobra.Command {
+	clientFactory := types.NewClientFlags()
 	cmd := &cobra.Command{
 		Use: "fabctl <command> <subcommand>",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			util.CheckError(clientFactory.Validate(cmd))
+		},
 	}
-
-	clientFactory := types.NewClientFlags()
 	clientFactory.AddFlags(cmd.PersistentFlags())
 
 	cmd.AddCommand(clusterinfo.New(clientFactory))
+	cmd.AddCommand(clusters.New(clientFactory))
 	cmd.AddCommand(ping.New(clientFactory))
+	cmd.AddCommand(mtu.New(clientFactory))
+	cmd.AddCommand(perf.New(clientFactory))
+	cmd.AddCommand(probe.New(clientFactory))
+	cmd.AddCommand(trace.New(clientFactory))
 	cmd.AddCommand(images.New(clientFactory))
 	cmd.AddCommand(nodes.New(clientFactory))
+	cmd.AddCommand(nodenet.New(clientFactory))
 	cmd.AddCommand(nettool.New(clientFactory))
 	cmd.AddCommand(swanctl.New(clientFactory))
 	cmd.AddCommand(topology.New(clientFactory))
 	cmd.AddCommand(cert.New(clientFactory))
+	cmd.AddCommand(doctor.New(clientFactory))
+	cmd.AddCommand(collect.New(clientFactory))
+	cmd.AddCommand(community.New(clientFactory))
+	cmd.AddCommand(tunnels.New(clientFactory))
 	cmd.AddCommand(version.New())
 
 	return cmd
@@ -0,0 +1,165 @@
+package swanctl
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fabedge/fabctl/pkg/printer"
+	"github.com/fabedge/fabctl/pkg/strongswan"
+	"github.com/fabedge/fabctl/pkg/util"
+)
+
+const execTimeout = 30 * time.Second
+
+// PodSAs are IKE SAs listed in a strongswan container
+type PodSAs struct {
+	Pod    string             `json:"pod"`
+	IKESAs []strongswan.IKESA `json:"ikeSAs"`
+}
+
+type PodSAsList []PodSAs
+
+// PodConnections are connections loaded in a strongswan container
+type PodConnections struct {
+	Pod         string                  `json:"pod"`
+	Connections []strongswan.Connection `json:"connections"`
+}
+
+type PodConnectionsList []PodConnections
+
+// newPodSAsList parses SAs from outputs of swanctl --list-sa --pretty, pods failed to execute swanctl are returned as failures
+func newPodSAsList(results []podResult) (PodSAsList, []failure) {
+	var (
+		list     PodSAsList
+		failures []failure
+	)
+	for _, r := range results {
+		if err := r.result.AsError(); err != nil {
+			failures = append(failures, failure{target: r.pod, err: err})
+			continue
+		}
+
+		sas, err := strongswan.ParseSAs(r.result.Stdout)
+		if err != nil {
+			failures = append(failures, failure{target: r.pod, err: err})
+			continue
+		}
+
+		list = append(list, PodSAs{Pod: r.pod, IKESAs: sas})
+	}
+
+	return list, failures
+}
+
+// newPodConnectionsList parses connections from outputs of swanctl --list-conns --pretty, pods failed to execute swanctl are returned as failures
+func newPodConnectionsList(results []podResult) (PodConnectionsList, []failure) {
+	var (
+		list     PodConnectionsList
+		failures []failure
+	)
+	for _, r := range results {
+		if err := r.result.AsError(); err != nil {
+			failures = append(failures, failure{target: r.pod, err: err})
+			continue
+		}
+
+		conns, err := strongswan.ParseConnections(r.result.Stdout)
+		if err != nil {
+			failures = append(failures, failure{target: r.pod, err: err})
+			continue
+		}
+
+		list = append(list, PodConnections{Pod: r.pod, Connections: conns})
+	}
+
+	return list, failures
+}
+
+func (list PodSAsList) Header(wide bool) []string {
+	header := []string{"POD", "NAME", "STATE", "REMOTE-HOST", "ESTABLISHED", "CHILD-SAS"}
+	if wide {
+		header = append(header, "LOCAL-HOST", "LOCAL-ID", "REMOTE-ID", "ALGORITHMS", "REKEY-IN", "BYTES-IN", "BYTES-OUT")
+	}
+
+	return header
+}
+
+func (list PodSAsList) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, item := range list {
+		for _, sa := range item.IKESAs {
+			row := []string{
+				item.Pod,
+				sa.Name,
+				sa.State,
+				sa.RemoteHost,
+				util.FormatSeconds(sa.Established),
+				fmt.Sprintf("%d/%d", sa.InstalledChildSAs(), len(sa.ChildSAs)),
+			}
+
+			if wide {
+				var bytesIn, bytesOut uint64
+				for _, child := range sa.ChildSAs {
+					bytesIn += child.BytesIn
+					bytesOut += child.BytesOut
+				}
+
+				row = append(row,
+					sa.LocalHost,
+					sa.LocalID,
+					sa.RemoteID,
+					sa.Algorithms(),
+					util.FormatSeconds(sa.RekeyTime),
+					fmt.Sprint(bytesIn),
+					fmt.Sprint(bytesOut),
+				)
+			}
+
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+func (list PodConnectionsList) Header(wide bool) []string {
+	header := []string{"POD", "NAME", "VERSION", "REMOTE-ADDRS", "CHILDREN"}
+	if wide {
+		header = append(header, "LOCAL-ADDRS", "LOCAL-ID", "REMOTE-ID", "REKEY-TIME")
+	}
+
+	return header
+}
+
+func (list PodConnectionsList) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, item := range list {
+		for _, conn := range item.Connections {
+			var children []string
+			for _, child := range conn.Children {
+				children = append(children, child.Name)
+			}
+
+			row := []string{
+				item.Pod,
+				conn.Name,
+				conn.Version,
+				printer.Join(conn.RemoteAddrs),
+				printer.Join(children),
+			}
+
+			if wide {
+				row = append(row,
+					printer.Join(conn.LocalAddrs),
+					conn.LocalID(),
+					conn.RemoteID(),
+					util.FormatSeconds(conn.RekeyTime),
+				)
+			}
+
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
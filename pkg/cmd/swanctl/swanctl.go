@@ -1,25 +1,23 @@
 package swanctl
 
 import (
-	"context"
 	"fmt"
 	"os"
-	"strings"
 
 	"github.com/spf13/cobra"
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/fabedge/fabctl/pkg/printer"
 	"github.com/fabedge/fabctl/pkg/types"
 	"github.com/fabedge/fabctl/pkg/util"
 )
 
 func New(clientGetter types.ClientGetter) *cobra.Command {
+	tf := &targetFlags{}
 	cmd := &cobra.Command{
-		Use:   "swanctl [command] edge [flags]",
+		Use:   "swanctl [command] edge... [flags]",
 		Short: "Execute swanctl command in strongswan containers",
-		Long:  `Execute swanctl command in strongswan containers. There are four subcommands and you can also execute other swanctl subcommands`,
+		Long: `Execute swanctl command in strongswan containers. There are four subcommands and you can also execute other swanctl subcommands.
+Multiple edges can be specified, swanctl is executed in their strongswan containers concurrently and outputs are printed in order.`,
 		Example: `
 fabctl swanctl list-conns edge1
 
@@ -27,43 +25,60 @@ To execute command on connectors, just input:
 
 fabctl swanctl list-conns connector
 
+To execute command on multiple edges or all edges and connectors:
+
+fabctl swanctl list-sa edge1 edge2
+fabctl swanctl list-sa -l topology.kubernetes.io/zone=beijing
+fabctl swanctl list-sa --all
+
 To execute others swanctl commands, input like this:
 fabctl swanctl edge1 -- --version
 fabctl swanctl connector -- --version
+fabctl swanctl --all-edges -- --stats
 `,
-		Args: cobra.MinimumNArgs(1),
+		Args: cobra.ArbitraryArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			cli, err := clientGetter.GetClient()
 			util.CheckError(err)
 
-			execute(cli, args[0], args[1:]...)
+			// edges are placed before --, the rest are swanctl flags
+			edges, flags := args, []string{}
+			if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+				edges, flags = args[:dash], args[dash:]
+			}
+
+			pods, failures := getTargetPods(cli, edges, tf)
+			execute(cli, pods, failures, tf.Parallelism, flags...)
 		},
 	}
 
+	addTargetFlags(tf, cmd.Flags())
+
 	cmd.AddCommand(newSubCommand(
 		"--list-conns",
 		clientGetter,
-		"list-conns edge [flags]",
-		"List loaded configurations of strongswan container in specified edge",
+		"list-conns edge... [flags]",
+		"List loaded configurations of strongswan containers in specified edges",
 	))
 	cmd.AddCommand(newSubCommand(
 		"--list-sa",
 		clientGetter,
-		"list-sa [edge] [flags]",
-		"List currently active IKE_SAs of strongswan container in specified edge",
+		"list-sa edge... [flags]",
+		"List currently active IKE_SAs of strongswan containers in specified edges",
+		addIKE,
 	))
 	cmd.AddCommand(newSubCommand(
 		"--initiate",
 		clientGetter,
-		"initiate edge [flags]",
-		"Initiate connection of strongswan container in specified edge",
+		"initiate edge... [flags]",
+		"Initiate connection of strongswan containers in specified edges",
 		addIKE, addChild, addTimeout,
 	))
 	cmd.AddCommand(newSubCommand(
 		"--terminate",
 		clientGetter,
-		"terminate edge [flags]",
-		"Terminate connection of strongswan container in specified edge",
+		"terminate edge... [flags]",
+		"Terminate connection of strongswan containers in specified edges",
 		addIKE, addChild, addTimeout,
 	))
 
@@ -72,19 +87,50 @@ fabctl swanctl connector -- --version
 
 func newSubCommand(name string, clientGetter types.ClientGetter, usage, short string, funcs ...addFlagFunc) *cobra.Command {
 	sf := &swanctlFlags{}
+	tf := &targetFlags{}
 	cmd := &cobra.Command{
 		Use:   usage,
 		Short: short,
-		Args:  cobra.ExactArgs(1),
+		Args:  cobra.ArbitraryArgs,
 		Run: func(cmd *cobra.Command, args []string) {
+			p, err := clientGetter.GetPrinter()
+			util.CheckError(err)
+
 			cli, err := clientGetter.GetClient()
 			util.CheckError(err)
 
-			execute(cli, args[0], sf.build(name)...)
+			if p.Format == printer.FormatDefault {
+				pods, failures := getTargetPods(cli, args, tf)
+				execute(cli, pods, failures, tf.Parallelism, sf.build(name)...)
+				return
+			}
+
+			if name != "--list-sa" && name != "--list-conns" {
+				util.Exitf("output format %s is not supported by %s\n", p.Format, cmd.Name())
+			}
+
+			pods, failures := getTargetPods(cli, args, tf)
+			total := len(pods) + len(failures)
+
+			// structured output is built from pretty printed VICI messages
+			flags := (&swanctlFlags{IKE: sf.IKE, Child: sf.Child, Timeout: sf.Timeout}).build(name, "--pretty")
+			results := runOnPods(cli, pods, flags, tf.Parallelism, execTimeout)
+			if name == "--list-sa" {
+				list, errs := newPodSAsList(results)
+				util.CheckError(p.Print(list))
+				failures = append(failures, errs...)
+			} else {
+				list, errs := newPodConnectionsList(results)
+				util.CheckError(p.Print(list))
+				failures = append(failures, errs...)
+			}
+
+			exitOnFailures(failures, total)
 		},
 	}
 
 	addRawAndPretty(sf, cmd.Flags())
+	addTargetFlags(tf, cmd.Flags())
 
 	for _, addFlag := range funcs {
 		addFlag(sf, cmd.Flags())
@@ -93,70 +139,44 @@ func newSubCommand(name string, clientGetter types.ClientGetter, usage, short st
 	return cmd
 }
 
-func execute(client *types.Client, edgeName string, flags ...string) {
-	if edgeName == "connector" {
-		executeOnConnectors(client, flags...)
-		return
-	}
+// execute runs swanctl in every pod and prints outputs in the order of pods. Output of a single pod
+// is streamed, outputs of multiple pods are buffered to avoid being mixed together.
+func execute(cli *types.Client, pods []string, failures []failure, parallelism int, flags ...string) {
+	total := len(pods) + len(failures)
 
-	podName := edgeName
-	// if edgeName has prefix like fabedge-connector or fabedge-agent, user may pass a pod name, just use it directly
-	if !strings.HasPrefix(edgeName, "fabedge-connector") && !strings.HasPrefix(edgeName, "fabedge-agent") {
-		podName = getAgentPodName(client, edgeName)
+	if len(pods) == 1 {
+		fmt.Printf(headerFormat, pods[0])
+		if err := cli.Exec(pods[0], "strongswan", append([]string{"swanctl"}, flags...)); err != nil {
+			failures = append(failures, failure{target: pods[0], err: err})
+		}
+		exitOnFailures(failures, total)
+		return
 	}
 
-	cmd := append([]string{"swanctl"}, flags...)
-
-	fmt.Printf("========================== %s =================================\n", podName)
-	err := client.Exec(podName, "strongswan", cmd)
-	util.CheckError(err)
-}
-
-//
-func getAgentPodName(cli *types.Client, edgeName string) string {
-	agentName := fmt.Sprintf("fabedge-agent-%s", edgeName)
-
-	var (
-		pod corev1.Pod
-		key = types.ObjectKey{Name: agentName, Namespace: cli.GetNamespace()}
-	)
-	err := cli.Get(context.Background(), key, &pod)
-	if err == nil {
-		return pod.Name
-	}
+	for _, r := range runOnPods(cli, pods, flags, parallelism, 0) {
+		fmt.Printf(headerFormat, r.pod)
+		fmt.Print(r.result.Stdout)
+		fmt.Fprint(os.Stderr, r.result.Stderr)
 
-	if errors.IsNotFound(err) {
-		var podList corev1.PodList
-		err = cli.List(context.Background(), &podList,
-			client.InNamespace(cli.GetNamespace()),
-			client.MatchingLabels{
-				"fabedge.io/name": agentName,
-			})
-		util.CheckError(err)
-
-		if len(podList.Items) == 0 {
-			util.Exitf("no agent pod for node: %s", edgeName)
+		if err := r.result.AsError(); err != nil {
+			failures = append(failures, failure{target: r.pod, err: err})
 		}
-
-		return podList.Items[0].Name
 	}
 
-	util.Exitf("failed to get agent pod: %s", err)
-	return ""
+	exitOnFailures(failures, total)
 }
 
-func executeOnConnectors(cli *types.Client, cmdFlags ...string) {
-	var pods corev1.PodList
-	err := cli.List(context.Background(), &pods, client.MatchingLabels{
-		"app": "fabedge-connector",
-	})
-	util.CheckError(err)
-
-	if len(pods.Items) == 0 {
-		fmt.Fprintln(os.Stderr, "no connectors found")
+// getTargetPods resolves pods from edges and target flags, it exits if no pod can be found
+func getTargetPods(cli *types.Client, edges []string, tf *targetFlags) ([]string, []failure) {
+	if len(edges) == 0 && tf.empty() {
+		util.Exitf("at least one edge is required, or use --selector, --all-edges or --all\n")
 	}
 
-	for _, pod := range pods.Items {
-		execute(cli, pod.Name, cmdFlags...)
+	pods, failures := tf.resolvePods(cli, edges)
+	if len(pods) == 0 {
+		exitOnFailures(failures, len(failures))
+		util.Exitf("no strongswan pods found\n")
 	}
+
+	return pods, failures
 }
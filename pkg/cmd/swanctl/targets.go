@@ -0,0 +1,237 @@
+package swanctl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
+)
+
+const headerFormat = "========================== %s =================================\n"
+
+// targetFlags decide which strongswan pods swanctl will be executed in
+type targetFlags struct {
+	Selector    string
+	AllEdges    bool
+	All         bool
+	Parallelism int
+}
+
+func addTargetFlags(tf *targetFlags, fs *pflag.FlagSet) {
+	fs.StringVarP(&tf.Selector, "selector", "l", "", "Execute swanctl in agents of edge nodes matched by this label selector")
+	fs.BoolVar(&tf.AllEdges, "all-edges", false, "Execute swanctl in agents of all edge nodes")
+	fs.BoolVar(&tf.All, "all", false, "Execute swanctl in agents of all edge nodes and connectors")
+	fs.IntVar(&tf.Parallelism, "parallelism", 10, "The maximum number of pods to execute swanctl at the same time")
+}
+
+func (tf *targetFlags) empty() bool {
+	return tf.Selector == "" && !tf.AllEdges && !tf.All
+}
+
+// failure records why swanctl can't be executed for a target, target may be a pod or an edge
+type failure struct {
+	target string
+	err    error
+}
+
+// podResult is the captured output of swanctl in a pod
+type podResult struct {
+	pod    string
+	result types.ExecResult
+}
+
+// resolvePods returns names of strongswan pods of edges and pods selected by flags.
+// Pod names are deduplicated and kept in the order they are resolved, edges which
+// have no pods are returned as failures.
+func (tf *targetFlags) resolvePods(cli *types.Client, edges []string) ([]string, []failure) {
+	var (
+		ctx      = context.Background()
+		pods     []string
+		failures []failure
+		seen     = sets.NewString()
+	)
+
+	add := func(names ...string) {
+		for _, name := range names {
+			if !seen.Has(name) {
+				seen.Insert(name)
+				pods = append(pods, name)
+			}
+		}
+	}
+
+	for _, edge := range edges {
+		names, err := getPodNames(cli, edge)
+		if err != nil {
+			failures = append(failures, failure{target: edge, err: err})
+			continue
+		}
+		add(names...)
+	}
+
+	if tf.AllEdges || tf.All || tf.Selector != "" {
+		agentPods, err := cli.ListAgentPods(ctx)
+		if err != nil {
+			return pods, append(failures, failure{target: "fabedge-agent", err: err})
+		}
+
+		var nodeNames sets.String
+		if tf.Selector != "" && !tf.AllEdges && !tf.All {
+			nodeNames, err = getNodeNames(cli, tf.Selector)
+			if err != nil {
+				return pods, append(failures, failure{target: tf.Selector, err: err})
+			}
+		}
+
+		for _, pod := range agentPods {
+			if nodeNames == nil || nodeNames.Has(pod.Spec.NodeName) {
+				add(pod.Name)
+			}
+		}
+	}
+
+	if tf.All {
+		names, err := getConnectorPodNames(cli)
+		if err != nil {
+			failures = append(failures, failure{target: "connector", err: err})
+		}
+		add(names...)
+	}
+
+	return pods, failures
+}
+
+func getNodeNames(cli *types.Client, selector string) (sets.String, error) {
+	nodes, err := cli.ListNodesBySelector(context.Background(), selector)
+	if err != nil {
+		return nil, err
+	}
+
+	names := sets.NewString()
+	for _, node := range nodes {
+		names.Insert(node.Name)
+	}
+
+	return names, nil
+}
+
+// getPodNames returns strongswan pods of an edge, if edgeName is connector, all connector pods are returned
+func getPodNames(cli *types.Client, edgeName string) ([]string, error) {
+	if edgeName == "connector" {
+		return getConnectorPodNames(cli)
+	}
+
+	name, err := getPodName(cli, edgeName)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{name}, nil
+}
+
+func getConnectorPodNames(cli *types.Client) ([]string, error) {
+	pods, err := cli.ListConnectorPods(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("no connectors found")
+	}
+
+	var names []string
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+
+	return names, nil
+}
+
+// getPodName returns the strongswan pod name of an edge. If edgeName has prefix like fabedge-connector
+// or fabedge-agent, user may pass a pod name, just use it directly
+func getPodName(cli *types.Client, edgeName string) (string, error) {
+	if strings.HasPrefix(edgeName, "fabedge-connector") || strings.HasPrefix(edgeName, "fabedge-agent") {
+		return edgeName, nil
+	}
+
+	return getAgentPodName(cli, edgeName)
+}
+
+func getAgentPodName(cli *types.Client, edgeName string) (string, error) {
+	agentName := fmt.Sprintf("fabedge-agent-%s", edgeName)
+
+	var (
+		pod corev1.Pod
+		key = types.ObjectKey{Name: agentName, Namespace: cli.GetNamespace()}
+	)
+	err := cli.Get(context.Background(), key, &pod)
+	if err == nil {
+		return pod.Name, nil
+	}
+
+	if !errors.IsNotFound(err) {
+		return "", fmt.Errorf("failed to get agent pod: %s", err)
+	}
+
+	var podList corev1.PodList
+	err = cli.List(context.Background(), &podList,
+		client.InNamespace(cli.GetNamespace()),
+		client.MatchingLabels{
+			"fabedge.io/name": agentName,
+		})
+	if err != nil {
+		return "", err
+	}
+
+	if len(podList.Items) == 0 {
+		return "", fmt.Errorf("no agent pod for node: %s", edgeName)
+	}
+
+	return podList.Items[0].Name, nil
+}
+
+// runOnPods executes swanctl in pods concurrently, results are in the same order as pods.
+// If timeout is zero, swanctl is not interrupted until it exits.
+func runOnPods(cli *types.Client, pods []string, flags []string, parallelism int, timeout time.Duration) []podResult {
+	cmd := append([]string{"swanctl"}, flags...)
+
+	results := make([]podResult, len(pods))
+	util.Parallel(parallelism, len(pods), func(i int) {
+		ctx := context.Background()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		results[i] = podResult{
+			pod:    pods[i],
+			result: cli.ExecCapture(ctx, pods[i], "strongswan", cmd),
+		}
+	})
+
+	return results
+}
+
+// exitOnFailures prints a summary of failures and exits, total is the number of all targets
+func exitOnFailures(failures []failure, total int) {
+	if len(failures) == 0 {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "\nswanctl failed in %d of %d targets:\n", len(failures), total)
+	for _, f := range failures {
+		fmt.Fprintf(os.Stderr, "  %s: %s\n", f.target, f.err)
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,59 @@
+package trace
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// matches hop lines of traceroute, e.g. " 1  10.233.64.1  0.045 ms", and tracepath, e.g. " 1:  10.233.64.1  0.071ms"
+	hopRegexp = regexp.MustCompile(`^\s*(\d+)\??:?\s+(.*)$`)
+	rttRegexp = regexp.MustCompile(`([\d.]+)\s*ms`)
+)
+
+// Hop is a hop reported by traceroute or tracepath, Node is the node which the address belongs to
+type Hop struct {
+	TTL     int      `json:"ttl"`
+	Address string   `json:"address,omitempty"`
+	RTTMs   float64  `json:"rttMs,omitempty"`
+	Node    string   `json:"node,omitempty"`
+	Notes   []string `json:"notes,omitempty"`
+}
+
+// parseHops parses output of "traceroute -n -q 1" or "tracepath -n", hops without reply have no address.
+func parseHops(output string) []Hop {
+	var hops []Hop
+	for _, line := range strings.Split(output, "\n") {
+		matches := hopRegexp.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+
+		ttl, _ := strconv.Atoi(matches[1])
+		rest := strings.TrimSpace(matches[2])
+
+		// tracepath prints local MTU as the first hop
+		if strings.HasPrefix(rest, "[LOCALHOST]") {
+			continue
+		}
+
+		// tracepath may report a TTL again when path MTU changes
+		if n := len(hops); n > 0 && hops[n-1].TTL == ttl {
+			continue
+		}
+
+		hop := Hop{TTL: ttl}
+		if fields := strings.Fields(rest); len(fields) > 0 && net.ParseIP(fields[0]) != nil {
+			hop.Address = fields[0]
+			if m := rttRegexp.FindStringSubmatch(rest); m != nil {
+				hop.RTTMs, _ = strconv.ParseFloat(m[1], 64)
+			}
+		}
+
+		hops = append(hops, hop)
+	}
+
+	return hops
+}
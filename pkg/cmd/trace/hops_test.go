@@ -0,0 +1,55 @@
+package trace
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseHops(t *testing.T) {
+	testCases := []struct {
+		name     string
+		output   string
+		expected []Hop
+	}{
+		{
+			name: "traceroute",
+			output: `traceroute to 10.233.67.5 (10.233.67.5), 30 hops max, 46 byte packets
+ 1  10.233.64.1  0.045 ms
+ 2  *
+ 3  10.233.67.5  1.337 ms
+`,
+			expected: []Hop{
+				{TTL: 1, Address: "10.233.64.1", RTTMs: 0.045},
+				{TTL: 2},
+				{TTL: 3, Address: "10.233.67.5", RTTMs: 1.337},
+			},
+		},
+		{
+			name: "tracepath",
+			output: ` 1?: [LOCALHOST]                      pmtu 1500
+ 1:  10.233.64.1                                           0.071ms
+ 1:  10.233.64.1                                           0.052ms pmtu 1450
+ 2:  no reply
+ 3:  10.233.67.5                                           1.204ms reached
+     Resume: pmtu 1450 hops 3 back 3
+`,
+			expected: []Hop{
+				{TTL: 1, Address: "10.233.64.1", RTTMs: 0.071},
+				{TTL: 2},
+				{TTL: 3, Address: "10.233.67.5", RTTMs: 1.204},
+			},
+		},
+		{
+			name:   "no hops",
+			output: "traceroute: bad address 'edge3'\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := parseHops(tc.output); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fabedge/fabctl/pkg/iproute"
+	"github.com/fabedge/fabctl/pkg/types"
+)
+
+const (
+	RoleSource      = "source"
+	RoleConnector   = "connector"
+	RoleDestination = "destination"
+)
+
+var (
+	devRegexp   = regexp.MustCompile(`\bdev (\S+)`)
+	tableRegexp = regexp.MustCompile(`\btable (\S+)`)
+)
+
+// NodeLookup is the result of route, rule and xfrm policy lookups on a node along the path,
+// they are executed in strongswan container of agent or connector pod which uses host network.
+type NodeLookup struct {
+	Role     string           `json:"role"`
+	Node     string           `json:"node"`
+	Pod      string           `json:"pod,omitempty"`
+	Route    string           `json:"route,omitempty"`
+	Table    string           `json:"table,omitempty"`
+	Rules    []string         `json:"rules,omitempty"`
+	Policies []iproute.Policy `json:"policies,omitempty"`
+	Notes    []string         `json:"notes,omitempty"`
+	Problems []string         `json:"problems,omitempty"`
+	Skipped  string           `json:"skipped,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// hasPolicy checks if any matched policy is of one of dirs
+func (l NodeLookup) hasPolicy(dirs ...string) bool {
+	for _, p := range l.Policies {
+		for _, dir := range dirs {
+			if p.Dir == dir {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// tunnelDst returns the remote address of the tunnel used by outbound policy
+func (l NodeLookup) tunnelDst() string {
+	for _, p := range l.Policies {
+		if p.Dir == iproute.DirOut {
+			return p.TunnelDst
+		}
+	}
+
+	return ""
+}
+
+// inspect executes lookups for packets from srcIP to dstIP. On the source node, the packets
+// come from interface of srcIP unless it's a local address, so the route is looked up with iif.
+func inspect(client *types.Client, l *NodeLookup, srcIP, dstIP string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	exec := func(cmd ...string) (string, error) {
+		result := client.ExecCapture(ctx, l.Pod, "strongswan", cmd)
+		return result.Stdout, result.AsError()
+	}
+
+	routeCmd := []string{"ip", "-o", "route", "get", dstIP}
+	if l.Role == RoleSource {
+		out, err := exec("ip", "-o", "route", "get", srcIP)
+		if err != nil {
+			l.Error = fmt.Sprintf("failed to look up route of %s: %s", srcIP, err)
+			return
+		}
+
+		routeCmd = append(routeCmd, "from", srcIP)
+		if !strings.HasPrefix(strings.TrimSpace(out), "local ") {
+			if matches := devRegexp.FindStringSubmatch(out); matches != nil {
+				routeCmd = append(routeCmd, "iif", matches[1])
+			}
+		}
+	}
+
+	out, err := exec(routeCmd...)
+	if err != nil {
+		l.Problems = append(l.Problems, fmt.Sprintf("no route to %s: %s", dstIP, err))
+	} else {
+		l.Route = strings.Join(strings.Fields(strings.ReplaceAll(out, "\\", " ")), " ")
+		l.Table = iproute.TableMain
+		if matches := tableRegexp.FindStringSubmatch(l.Route); matches != nil {
+			l.Table = matches[1]
+		}
+	}
+
+	out, err = exec("ip", "rule", "show")
+	if err != nil {
+		l.Error = fmt.Sprintf("failed to list rules: %s", err)
+		return
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			l.Rules = append(l.Rules, line)
+		}
+	}
+
+	out, err = exec("ip", "xfrm", "policy")
+	if err != nil {
+		l.Error = fmt.Sprintf("failed to list xfrm policies: %s", err)
+		return
+	}
+	l.Policies = matchPolicies(iproute.ParsePolicies(out), srcIP, dstIP)
+
+	if l.Table != "" && l.Table != iproute.TableMain {
+		note := fmt.Sprintf("route is from table %s", l.Table)
+		if rule := ruleOf(l.Rules, l.Table); rule != "" {
+			note += fmt.Sprintf(" selected by rule \"%s\"", rule)
+		}
+		l.Notes = append(l.Notes, note)
+	}
+}
+
+// ruleOf returns the first rule which looks up table
+func ruleOf(rules []string, table string) string {
+	for _, rule := range rules {
+		fields := strings.Fields(rule)
+		for i := 0; i < len(fields)-1; i++ {
+			if fields[i] == "lookup" && fields[i+1] == table {
+				return rule
+			}
+		}
+	}
+
+	return ""
+}
+
+// matchPolicies returns policies whose selector contains srcIP and dstIP
+func matchPolicies(policies []iproute.Policy, srcIP, dstIP string) []iproute.Policy {
+	var result []iproute.Policy
+	for _, p := range policies {
+		if iproute.Contains(p.Src, srcIP) && iproute.Contains(p.Dst, dstIP) {
+			result = append(result, p)
+		}
+	}
+
+	return result
+}
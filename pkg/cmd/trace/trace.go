@@ -0,0 +1,494 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/fabedge/fabctl/pkg/cmd/nettool"
+	"github.com/fabedge/fabctl/pkg/iproute"
+	"github.com/fabedge/fabctl/pkg/printer"
+	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
+)
+
+type options struct {
+	image          string
+	prepareTimeout time.Duration
+	keepPods       bool
+	hostNetwork    bool
+	maxHops        uint
+	wait           uint
+}
+
+// Result is the trace from net-tool pod on a node to a target, with lookups on nodes along the path.
+// Break tells where packets are lost if target is not reached.
+type Result struct {
+	From    string       `json:"from"`
+	FromIP  string       `json:"fromIP"`
+	To      string       `json:"to"`
+	ToIP    string       `json:"toIP"`
+	Tool    string       `json:"tool,omitempty"`
+	Hops    []Hop        `json:"hops"`
+	Reached bool         `json:"reached"`
+	Lookups []NodeLookup `json:"lookups"`
+	Break   string       `json:"break,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+type tracer struct {
+	client *types.Client
+	opts   *options
+
+	nodes []corev1.Node
+	// strongswan pods indexed by node name, they are agent pods or connector pod
+	strongswanPods map[string]corev1.Pod
+	edges          map[string]bool
+	connector      corev1.Pod
+
+	pods []corev1.Pod
+}
+
+func New(clientGetter types.ClientGetter) *cobra.Command {
+	opts := &options{}
+
+	cmd := &cobra.Command{
+		Use:   "trace nodeName target [flags]",
+		Short: "Trace the path from net-tool pod on specified node to target",
+		Long: `Trace the path from net-tool pod on specified node to target. traceroute or tracepath is executed in net-tool pod,
+route, rule and xfrm policy lookups are executed on source node, connector node and destination node, then hops are
+annotated with nodes and problems found by lookups, like missing routes or xfrm policies.
+
+Target can be one of:
+  node:NAME or NAME          net-tool pod on the node
+  pod:NAMESPACE/NAME         IP of the pod
+  svc:NAMESPACE/NAME         cluster IP of the service, xfrm policies are not checked because cluster IP is translated before
+  ip:ADDRESS                 any address
+  endpoint:CLUSTER.NODE      first node address of the endpoint found in Cluster resources`,
+		Example: `
+fabctl trace edge1 edge2
+fabctl trace edge1 pod:default/nginx-6799fc88d8-xl7dc
+fabctl trace edge1 edge2 --host -o json
+`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			p, err := clientGetter.GetPrinter()
+			util.CheckError(err)
+
+			client, err := clientGetter.GetClient()
+			util.CheckError(err)
+
+			target, err := nettool.ParseTarget(args[1])
+			util.CheckError(err)
+
+			t := &tracer{client: client, opts: opts}
+			result, err := t.trace(args[0], target)
+			t.close()
+			util.CheckError(err)
+
+			util.CheckError(p.Print(result))
+
+			if result.Error != "" || !result.Reached {
+				util.Exitf("failed to trace from %s to %s\n", result.From, result.To)
+			}
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.StringVarP(&opts.image, "net-tool-image", "i", "praqma/network-multitool:minimal", "The image of net-tool pod")
+	fs.DurationVar(&opts.prepareTimeout, "prepare-timeout", 30*time.Second, "The length of time to prepare net-tool pods")
+	fs.BoolVarP(&opts.keepPods, "keep", "k", false, "keep pods after test if finished")
+	fs.BoolVar(&opts.hostNetwork, "host", false, "Use host-network net-tool pods")
+	fs.UintVarP(&opts.maxHops, "max-hops", "m", 30, "The max number of hops")
+	fs.UintVarP(&opts.wait, "wait", "w", 1, "The seconds to wait for a reply of each hop")
+
+	return cmd
+}
+
+func (t *tracer) trace(nodeName string, target nettool.Target) (Result, error) {
+	result := Result{From: nodeName, To: target.String()}
+
+	var (
+		source  corev1.Pod
+		dstNode string
+	)
+	err := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), t.opts.prepareTimeout)
+		defer cancel()
+
+		if err := t.load(ctx); err != nil {
+			return err
+		}
+
+		var err error
+		if source, err = t.preparePod(ctx, nodeName); err != nil {
+			return err
+		}
+		result.FromIP = source.Status.PodIP
+
+		switch target.Kind {
+		case nettool.TargetNode:
+			pod, err := t.preparePod(ctx, target.Name)
+			if err != nil {
+				return err
+			}
+			result.ToIP, dstNode = pod.Status.PodIP, target.Name
+		case nettool.TargetPod:
+			var pod corev1.Pod
+			if err = t.client.Get(ctx, nettool.NamespacedKey(target.Name), &pod); err != nil {
+				return err
+			}
+			if pod.Status.PodIP == "" {
+				return fmt.Errorf("pod %s has no IP", target.Name)
+			}
+			result.ToIP, dstNode = pod.Status.PodIP, pod.Spec.NodeName
+		default:
+			if result.ToIP, err = target.ResolveIP(ctx, t.client); err != nil {
+				return err
+			}
+			dstNode = t.nodeOf(result.ToIP)
+		}
+
+		return nil
+	}()
+	if err != nil {
+		return result, err
+	}
+
+	var hops []Hop
+	result.Tool, hops, err = t.traceroute(source, result.ToIP)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	for _, hop := range hops {
+		hop.Node = t.nodeOf(hop.Address)
+		result.Hops = append(result.Hops, hop)
+	}
+	if n := len(result.Hops); n > 0 && result.Hops[n-1].Address == result.ToIP {
+		result.Reached = true
+	}
+
+	result.Lookups = t.lookup(nodeName, dstNode, result.FromIP, result.ToIP, target.Kind == nettool.TargetService)
+	result.annotate()
+
+	return result, nil
+}
+
+// load finds nodes and strongswan pods which are used to annotate hops and execute lookups
+func (t *tracer) load(ctx context.Context) error {
+	var nodes corev1.NodeList
+	if err := t.client.List(ctx, &nodes); err != nil {
+		return err
+	}
+	t.nodes = nodes.Items
+
+	agents, err := t.client.ListAgentPods(ctx)
+	if err != nil {
+		return err
+	}
+
+	connectors, err := t.client.ListConnectorPods(ctx)
+	if err != nil {
+		return err
+	}
+
+	t.strongswanPods = make(map[string]corev1.Pod)
+	t.edges = make(map[string]bool)
+	for _, pod := range agents {
+		if pod.Status.Phase == corev1.PodRunning {
+			t.strongswanPods[pod.Spec.NodeName] = pod
+			t.edges[pod.Spec.NodeName] = true
+		}
+	}
+
+	for _, pod := range connectors {
+		if pod.Status.Phase == corev1.PodRunning {
+			t.connector = pod
+			t.strongswanPods[pod.Spec.NodeName] = pod
+			break
+		}
+	}
+
+	return nil
+}
+
+func (t *tracer) preparePod(ctx context.Context, nodeName string) (corev1.Pod, error) {
+	pod, err := nettool.GetOrCreatePod(ctx, t.client, nodeName, t.opts.image, t.opts.hostNetwork, t.opts.prepareTimeout)
+	if pod.Name != "" {
+		t.pods = append(t.pods, pod)
+	}
+
+	return pod, err
+}
+
+func (t *tracer) close() {
+	if t.opts.keepPods {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.opts.prepareTimeout)
+	defer cancel()
+
+	for _, pod := range t.pods {
+		nettool.DeletePod(ctx, t.client, pod)
+	}
+}
+
+// traceroute executes traceroute in net-tool pod, tracepath is used if traceroute is not found
+func (t *tracer) traceroute(pod corev1.Pod, ip string) (string, []Hop, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(t.opts.maxHops*t.opts.wait)*time.Second+10*time.Second)
+	defer cancel()
+
+	tool := "traceroute"
+	cmd := []string{tool, "-n", "-q", "1", "-w", fmt.Sprint(t.opts.wait), "-m", fmt.Sprint(t.opts.maxHops), ip}
+
+	result := t.client.ExecCapture(ctx, pod.Name, nettool.ContainerName, cmd)
+	if result.Err == nil && result.ExitCode == 127 {
+		tool = "tracepath"
+		cmd = []string{tool, "-n", "-m", fmt.Sprint(t.opts.maxHops), ip}
+		result = t.client.ExecCapture(ctx, pod.Name, nettool.ContainerName, cmd)
+	}
+
+	// traceroute and tracepath exit with non-zero code if target is not reached
+	if result.Err != nil {
+		return tool, nil, result.Err
+	}
+
+	hops := parseHops(result.Stdout)
+	if len(hops) == 0 {
+		return tool, nil, result.AsError()
+	}
+
+	return tool, hops, nil
+}
+
+// lookup executes lookups on source node, connector node and destination node, then checks if
+// xfrm policies needed by the path exist. dstNode is empty if target is not on any node.
+func (t *tracer) lookup(srcNode, dstNode, srcIP, dstIP string, isService bool) []NodeLookup {
+	lookups := []NodeLookup{{Role: RoleSource, Node: srcNode}}
+	if dstNode != srcNode {
+		if connectorNode := t.connector.Spec.NodeName; connectorNode != "" && connectorNode != srcNode && connectorNode != dstNode {
+			lookups = append(lookups, NodeLookup{Role: RoleConnector, Node: connectorNode})
+		}
+
+		if dstNode != "" {
+			lookups = append(lookups, NodeLookup{Role: RoleDestination, Node: dstNode})
+		}
+	}
+
+	util.Parallel(len(lookups), len(lookups), func(i int) {
+		l := &lookups[i]
+
+		pod, ok := t.strongswanPods[l.Node]
+		if !ok {
+			l.Skipped = "no strongswan container on the node, it's neither an edge node nor the connector node"
+			return
+		}
+
+		l.Pod = pod.Name
+		inspect(t.client, l, srcIP, dstIP)
+	})
+
+	if dstNode == srcNode || isService {
+		return lookups
+	}
+
+	var (
+		srcEdge      = t.edges[srcNode]
+		dstEdge      = t.edges[dstNode]
+		srcConnector = srcNode == t.connector.Spec.NodeName
+		dstConnector = dstNode != "" && dstNode == t.connector.Spec.NodeName
+	)
+
+	for i := range lookups {
+		l := &lookups[i]
+		if l.Skipped != "" || l.Error != "" {
+			continue
+		}
+
+		var needOut, needIn bool
+		switch l.Role {
+		case RoleSource:
+			needOut = srcEdge || (srcConnector && dstEdge)
+		case RoleConnector:
+			// edges in the same community have tunnels between them, connector is not on the path
+			if tunnelDst := lookups[0].tunnelDst(); tunnelDst != "" && t.nodeOf(tunnelDst) == dstNode {
+				l.Skipped = fmt.Sprintf("tunnel of source node ends at %s, connector is not on the path", dstNode)
+				continue
+			}
+			needIn, needOut = srcEdge, dstEdge
+		case RoleDestination:
+			needIn = dstEdge || (dstConnector && srcEdge)
+		}
+
+		if needOut && !l.hasPolicy(iproute.DirOut) {
+			l.Problems = append(l.Problems, fmt.Sprintf("no xfrm policy with dir out matches %s -> %s, packets are not sent into tunnel", srcIP, dstIP))
+		}
+
+		if needIn && !l.hasPolicy(iproute.DirFwd, iproute.DirIn) {
+			l.Problems = append(l.Problems, fmt.Sprintf("no xfrm policy with dir fwd or in matches %s -> %s, packets from tunnel are dropped", srcIP, dstIP))
+		}
+	}
+
+	return lookups
+}
+
+// nodeOf returns the node which ip belongs to, the ip may be an address of node or in pod CIDR of node
+func (t *tracer) nodeOf(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+
+	for _, node := range t.nodes {
+		for _, address := range node.Status.Addresses {
+			if address.Address == ip {
+				return node.Name
+			}
+		}
+	}
+
+	for _, node := range t.nodes {
+		cidrs := node.Spec.PodCIDRs
+		if len(cidrs) == 0 && node.Spec.PodCIDR != "" {
+			cidrs = []string{node.Spec.PodCIDR}
+		}
+
+		for _, cidr := range cidrs {
+			if _, subnet, err := net.ParseCIDR(cidr); err == nil && subnet.Contains(addr) {
+				return node.Name
+			}
+		}
+	}
+
+	return ""
+}
+
+// annotate adds problems found by lookups to the first hop on the same node and finds where packets are lost
+func (r *Result) annotate() {
+	var problems []string
+	for _, l := range r.Lookups {
+		for _, problem := range l.Problems {
+			problems = append(problems, fmt.Sprintf("%s %s: %s", l.Role, l.Node, problem))
+		}
+
+		if len(l.Problems) == 0 {
+			continue
+		}
+
+		for i := range r.Hops {
+			if r.Hops[i].Node == l.Node {
+				r.Hops[i].Notes = append(r.Hops[i].Notes, l.Problems...)
+				break
+			}
+		}
+	}
+
+	if r.Reached || r.Error != "" {
+		return
+	}
+
+	last := -1
+	for i, hop := range r.Hops {
+		if hop.Address != "" {
+			last = i
+		}
+	}
+
+	switch {
+	case len(problems) > 0:
+		r.Break = problems[0]
+	case last == -1:
+		r.Break = "no hop replied"
+	default:
+		r.Break = fmt.Sprintf("packets are lost after hop %d %s", r.Hops[last].TTL, r.Hops[last].Address)
+	}
+
+	if last != -1 {
+		r.Hops[last].Notes = append(r.Hops[last].Notes, "last reply")
+	}
+}
+
+func (r Result) Describe(w io.Writer) {
+	kvs := []printer.KeyValue{
+		{Key: "From", Value: fmt.Sprintf("%s(%s)", r.From, r.FromIP)},
+		{Key: "To", Value: fmt.Sprintf("%s(%s)", r.To, r.ToIP)},
+		{Key: "Tool", Value: r.Tool},
+		{Key: "Reached", Value: fmt.Sprint(r.Reached)},
+	}
+	if r.Break != "" {
+		kvs = append(kvs, printer.KeyValue{Key: "Break", Value: r.Break})
+	}
+	if r.Error != "" {
+		kvs = append(kvs, printer.KeyValue{Key: "Error", Value: r.Error})
+	}
+	printer.Describe(w, kvs...)
+
+	if len(r.Hops) > 0 {
+		fmt.Fprintln(w)
+		printer.PrintTable(w, r, true)
+	}
+
+	for _, l := range r.Lookups {
+		kvs := []printer.KeyValue{
+			{Key: "Node", Value: fmt.Sprintf("%s(%s)", l.Node, l.Role)},
+		}
+
+		switch {
+		case l.Skipped != "":
+			kvs = append(kvs, printer.KeyValue{Key: "Skipped", Value: l.Skipped})
+		case l.Error != "":
+			kvs = append(kvs, printer.KeyValue{Key: "Error", Value: l.Error})
+		default:
+			kvs = append(kvs, printer.KeyValue{Key: "Route", Value: l.Route})
+			for _, p := range l.Policies {
+				kvs = append(kvs, printer.KeyValue{Key: "XFRM Policy", Value: p.String()})
+			}
+		}
+
+		for _, note := range l.Notes {
+			kvs = append(kvs, printer.KeyValue{Key: "Note", Value: note})
+		}
+		for _, problem := range l.Problems {
+			kvs = append(kvs, printer.KeyValue{Key: "Problem", Value: problem})
+		}
+
+		printer.Describe(w, kvs...)
+	}
+}
+
+func (r Result) Header(wide bool) []string {
+	header := []string{"HOP", "ADDRESS", "RTT", "NODE"}
+	if wide {
+		header = append(header, "NOTES")
+	}
+
+	return header
+}
+
+func (r Result) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, hop := range r.Hops {
+		address, rtt := hop.Address, ""
+		if address == "" {
+			address = "*"
+		}
+		if hop.RTTMs > 0 {
+			rtt = fmt.Sprintf("%.3fms", hop.RTTMs)
+		}
+
+		row := []string{fmt.Sprint(hop.TTL), address, rtt, hop.Node}
+		if wide {
+			row = append(row, strings.Join(hop.Notes, "; "))
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
@@ -0,0 +1,384 @@
+package tunnels
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/fabedge/fabctl/pkg/printer"
+	"github.com/fabedge/fabctl/pkg/strongswan"
+	"github.com/fabedge/fabctl/pkg/types"
+	"github.com/fabedge/fabctl/pkg/util"
+)
+
+const (
+	StatusEstablished = strongswan.IKEStateEstablished
+	StatusConnecting  = strongswan.IKEStateConnecting
+	StatusMissing     = "MISSING"
+	StatusUnexpected  = "UNEXPECTED"
+	// StatusUnknown means SAs of the source can't be listed
+	StatusUnknown = "UNKNOWN"
+)
+
+// Tunnel is the IPSec tunnel from a local endpoint to a peer endpoint, times are in seconds
+type Tunnel struct {
+	Source    string `json:"source"`
+	Pod       string `json:"pod"`
+	Peer      string `json:"peer"`
+	Expected  bool   `json:"expected"`
+	Status    string `json:"status"`
+	Age       int64  `json:"age"`
+	RekeyIn   int64  `json:"rekeyIn"`
+	ChildSAs  string `json:"childSAs,omitempty"`
+	RemoteIP  string `json:"remoteIP,omitempty"`
+	Message   string `json:"message,omitempty"`
+	sourceIdx int
+}
+
+// IsDown returns true if tunnel is expected but not established
+func (t Tunnel) IsDown() bool {
+	return t.Expected && t.Status != StatusEstablished
+}
+
+type TunnelList []Tunnel
+
+// source is a local endpoint whose strongswan container will be queried
+type source struct {
+	endpoint string
+	pod      *corev1.Pod
+	peers    sets.String
+}
+
+func New(clientGetter types.ClientGetter) *cobra.Command {
+	var parallelism int
+
+	cmd := &cobra.Command{
+		Use:   "tunnels [flags]",
+		Short: "Show status of IPSec tunnels between endpoints of current cluster and their peers",
+		Long: `Show status of IPSec tunnels between endpoints of current cluster and their peers. Expected tunnels are computed from
+communities and connector, then compared with IKE SAs listed in agents and connectors. If any expected tunnel is not established,
+tunnels exits with non-zero code.`,
+		Example: `
+fabctl tunnels
+fabctl tunnels -o wide
+fabctl tunnels -o json
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			p, err := clientGetter.GetPrinter()
+			util.CheckError(err)
+
+			cli, err := clientGetter.GetClient()
+			util.CheckError(err)
+
+			sources, err := getSources(cli)
+			util.CheckError(err)
+
+			results := make([]TunnelList, len(sources))
+			util.Parallel(parallelism, len(sources), func(i int) {
+				results[i] = inspect(cli, sources[i])
+			})
+
+			var tunnels TunnelList
+			for i, list := range results {
+				for _, t := range list {
+					t.sourceIdx = i
+					tunnels = append(tunnels, t)
+				}
+			}
+
+			util.CheckError(p.Print(tunnels))
+
+			if down := tunnels.Down(); down > 0 {
+				util.Exitf("%d expected tunnels are down\n", down)
+			}
+		},
+	}
+
+	cmd.Flags().IntVar(&parallelism, "parallelism", 10, "The maximum number of strongswan containers to query at the same time")
+
+	return cmd
+}
+
+// getSources computes expected peers of connector and each edge node of current cluster
+func getSources(cli *types.Client) ([]source, error) {
+	ctx := context.Background()
+
+	cluster := types.NewCluster(cli)
+	if err := cluster.ExtractArgumentsFromFabEdge(); err != nil {
+		return nil, err
+	}
+
+	if err := cluster.LoadCommunities(); err != nil {
+		return nil, err
+	}
+
+	edgeNodes, err := cli.ListNodes(ctx, cluster.EdgeLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	agentPods, err := cli.ListAgentPods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	podOfNode := make(map[string]corev1.Pod)
+	for _, pod := range agentPods {
+		podOfNode[pod.Spec.NodeName] = pod
+	}
+
+	var sources []source
+	for _, node := range edgeNodes {
+		ep := cluster.NewEndpoint(node)
+		src := source{
+			endpoint: ep.Name,
+			peers:    cluster.EdgePeers(ep.Name),
+		}
+		if pod, ok := podOfNode[node.Name]; ok {
+			src.pod = &pod
+		}
+		sources = append(sources, src)
+	}
+
+	connectors, err := cli.ListConnectorPods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	connectorName := cluster.ConnectorName()
+	connectorPeers := cluster.ConnectorPeers(edgeNodes)
+	if len(connectors) == 0 {
+		sources = append(sources, source{endpoint: connectorName, peers: connectorPeers})
+	}
+
+	for i := range connectors {
+		sources = append(sources, source{
+			endpoint: connectorName,
+			pod:      &connectors[i],
+			peers:    connectorPeers,
+		})
+	}
+
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].endpoint < sources[j].endpoint
+	})
+
+	return sources, nil
+}
+
+func inspect(cli *types.Client, src source) TunnelList {
+	unknown := func(podName, msg string) TunnelList {
+		var tunnels TunnelList
+		for _, peer := range src.peers.List() {
+			tunnels = append(tunnels, Tunnel{
+				Source:   src.endpoint,
+				Pod:      podName,
+				Peer:     peer,
+				Expected: true,
+				Status:   StatusUnknown,
+				Message:  msg,
+			})
+		}
+
+		return tunnels
+	}
+
+	if src.pod == nil {
+		return unknown("", "no strongswan pod")
+	}
+
+	pod := src.pod
+	if pod.Status.Phase != corev1.PodRunning {
+		return unknown(pod.Name, fmt.Sprintf("pod is %s", pod.Status.Phase))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result := cli.ExecCapture(ctx, pod.Name, "strongswan", []string{"swanctl", "--list-sa", "--pretty"})
+	if err := result.AsError(); err != nil {
+		return unknown(pod.Name, err.Error())
+	}
+
+	sas, err := strongswan.ParseSAs(result.Stdout)
+	if err != nil {
+		return unknown(pod.Name, err.Error())
+	}
+
+	saOfPeer := make(map[string]strongswan.IKESA)
+	for _, sa := range sas {
+		// prefer established SA if there are duplicated SAs
+		if old, ok := saOfPeer[sa.Name]; ok && old.IsEstablished() {
+			continue
+		}
+		saOfPeer[sa.Name] = sa
+	}
+
+	var tunnels TunnelList
+	for _, peer := range sets.StringKeySet(saOfPeer).Union(src.peers).List() {
+		t := Tunnel{
+			Source:   src.endpoint,
+			Pod:      pod.Name,
+			Peer:     peer,
+			Expected: src.peers.Has(peer),
+		}
+
+		sa, found := saOfPeer[peer]
+		switch {
+		case !found:
+			t.Status = StatusMissing
+		case !t.Expected:
+			t.Status = StatusUnexpected
+		default:
+			t.Status = sa.State
+		}
+
+		if found {
+			t.Age = sa.Established
+			t.RekeyIn = sa.RekeyTime
+			t.RemoteIP = sa.RemoteHost
+			t.ChildSAs = fmt.Sprintf("%d/%d", sa.InstalledChildSAs(), len(sa.ChildSAs))
+		}
+
+		tunnels = append(tunnels, t)
+	}
+
+	return tunnels
+}
+
+// Down returns how many expected tunnels are not established
+func (tunnels TunnelList) Down() int {
+	count := 0
+	for _, t := range tunnels {
+		if t.IsDown() {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Describe prints tunnels as a matrix, each row is a source and each column is a peer
+func (tunnels TunnelList) Describe(w io.Writer) {
+	type rowKey struct {
+		idx  int
+		name string
+	}
+
+	var rows []rowKey
+	seen := make(map[rowKey]bool)
+	peers := sets.NewString()
+	cells := make(map[rowKey]map[string]Tunnel)
+	for _, t := range tunnels {
+		key := rowKey{idx: t.sourceIdx, name: t.Source}
+		if t.Pod != "" {
+			key.name = fmt.Sprintf("%s(%s)", t.Source, t.Pod)
+		}
+
+		if !seen[key] {
+			seen[key] = true
+			rows = append(rows, key)
+			cells[key] = make(map[string]Tunnel)
+		}
+
+		cells[key][t.Peer] = t
+		peers.Insert(t.Peer)
+	}
+
+	header := []string{"SOURCE \\ PEER"}
+	for i := range peers.List() {
+		header = append(header, fmt.Sprint(i+1))
+	}
+
+	var lines [][]string
+	for _, key := range rows {
+		line := []string{key.name}
+		for _, peer := range peers.List() {
+			t, ok := cells[key][peer]
+			if !ok {
+				line = append(line, "-")
+				continue
+			}
+			line = append(line, symbolOf(t))
+		}
+		lines = append(lines, line)
+	}
+
+	printer.PrintTable(w, printer.SimpleTable{Columns: header, Data: lines}, false)
+
+	fmt.Fprintln(w)
+	for i, peer := range peers.List() {
+		fmt.Fprintf(w, "%d: %s\n", i+1, peer)
+	}
+	fmt.Fprintln(w, "\nE: established, C: connecting, X: missing, ?: unknown, +: unexpected, -: not expected")
+
+	var problems []string
+	for _, t := range tunnels {
+		if t.IsDown() || t.Status == StatusUnexpected {
+			problem := fmt.Sprintf("%s -> %s: %s", t.Source, t.Peer, t.Status)
+			if t.Pod != "" {
+				problem = fmt.Sprintf("%s(%s) -> %s: %s", t.Source, t.Pod, t.Peer, t.Status)
+			}
+			if t.Message != "" {
+				problem = fmt.Sprintf("%s, %s", problem, t.Message)
+			}
+			problems = append(problems, problem)
+		}
+	}
+
+	if len(problems) > 0 {
+		fmt.Fprintf(w, "\nProblems:\n  %s\n", strings.Join(problems, "\n  "))
+	}
+}
+
+func symbolOf(t Tunnel) string {
+	switch t.Status {
+	case StatusEstablished:
+		return "E"
+	case StatusConnecting:
+		return "C"
+	case StatusMissing:
+		return "X"
+	case StatusUnknown:
+		return "?"
+	case StatusUnexpected:
+		return "+"
+	default:
+		// other IKE states, e.g. REKEYING, DELETING
+		return t.Status
+	}
+}
+
+func (tunnels TunnelList) Header(wide bool) []string {
+	header := []string{"SOURCE", "PEER", "EXPECTED", "STATUS", "AGE", "REKEY-IN"}
+	if wide {
+		header = append(header, "POD", "REMOTE-IP", "CHILD-SAS", "MESSAGE")
+	}
+
+	return header
+}
+
+func (tunnels TunnelList) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, t := range tunnels {
+		row := []string{t.Source, t.Peer, fmt.Sprint(t.Expected), t.Status, "", ""}
+		if t.Age > 0 || t.RekeyIn > 0 {
+			row[4], row[5] = util.FormatSeconds(t.Age), util.FormatSeconds(t.RekeyIn)
+		}
+
+		if wide {
+			row = append(row, t.Pod, t.RemoteIP, t.ChildSAs, t.Message)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
@@ -0,0 +1,152 @@
+package iproute
+
+import (
+	"net"
+	"strings"
+)
+
+const (
+	TableMain  = "main"
+	TableLocal = "local"
+)
+
+// route types which may be the first field of a route
+var routeTypes = map[string]bool{
+	"unicast": true, "local": true, "broadcast": true, "multicast": true, "anycast": true,
+	"unreachable": true, "blackhole": true, "prohibit": true, "throw": true, "nat": true,
+}
+
+// Route is a route listed by "ip route show table all", Dst is always in CIDR format
+type Route struct {
+	Type  string `json:"type,omitempty"`
+	Dst   string `json:"dst"`
+	Via   string `json:"via,omitempty"`
+	Dev   string `json:"dev,omitempty"`
+	Table string `json:"table"`
+}
+
+// Rule is a policy routing rule listed by "ip rule"
+type Rule struct {
+	Priority string `json:"priority"`
+	Selector string `json:"selector"`
+	Table    string `json:"table,omitempty"`
+}
+
+// ParseRoutes parses output of "ip route show table all", routes without table are in main table
+func ParseRoutes(output string) []Route {
+	var routes []Route
+	for _, line := range strings.Split(output, "\n") {
+		// nexthops of multipath routes are indented
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		route := Route{Table: TableMain}
+		if routeTypes[fields[0]] {
+			route.Type, fields = fields[0], fields[1:]
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		route.Dst = Normalize(fields[0])
+		if table := valueOf(fields, "table"); table != "" {
+			route.Table = table
+		}
+		route.Via = valueOf(fields, "via")
+		route.Dev = valueOf(fields, "dev")
+
+		routes = append(routes, route)
+	}
+
+	return routes
+}
+
+// ParseRules parses output of "ip rule", e.g. "220:	from all lookup 220"
+func ParseRules(output string) []Rule {
+	var rules []Rule
+	for _, line := range strings.Split(output, "\n") {
+		index := strings.Index(line, ":")
+		if index == -1 {
+			continue
+		}
+
+		selector := strings.TrimSpace(line[index+1:])
+		rules = append(rules, Rule{
+			Priority: strings.TrimSpace(line[:index]),
+			Selector: selector,
+			Table:    valueOf(strings.Fields(selector), "lookup"),
+		})
+	}
+
+	return rules
+}
+
+// Contains checks if ip is in cidr, cidr may be a single address too
+func Contains(cidr, ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	if !strings.Contains(cidr, "/") {
+		return net.ParseIP(cidr).Equal(addr)
+	}
+
+	_, subnet, err := net.ParseCIDR(cidr)
+	return err == nil && subnet.Contains(addr)
+}
+
+// Covers checks if subnet is inside of cidr, both of them may be single addresses
+func Covers(cidr, subnet string) bool {
+	_, outer, err := net.ParseCIDR(Normalize(cidr))
+	if err != nil {
+		return false
+	}
+
+	_, inner, err := net.ParseCIDR(Normalize(subnet))
+	if err != nil {
+		return false
+	}
+
+	outerOnes, outerBits := outer.Mask.Size()
+	innerOnes, innerBits := inner.Mask.Size()
+
+	return outerBits == innerBits && outerOnes <= innerOnes && outer.Contains(inner.IP)
+}
+
+// SameSubnet checks if a and b are the same subnet, addresses are taken as host subnets
+func SameSubnet(a, b string) bool {
+	return Normalize(a) == Normalize(b)
+}
+
+// Normalize converts default to 0.0.0.0/0 and addresses to host subnets, subnets are masked
+func Normalize(dst string) string {
+	if dst == "default" {
+		return "0.0.0.0/0"
+	}
+
+	if !strings.Contains(dst, "/") {
+		ip := net.ParseIP(dst)
+		if ip == nil {
+			return dst
+		}
+
+		if ip.To4() != nil {
+			return ip.String() + "/32"
+		}
+		return ip.String() + "/128"
+	}
+
+	_, subnet, err := net.ParseCIDR(dst)
+	if err != nil {
+		return dst
+	}
+
+	return subnet.String()
+}
@@ -0,0 +1,138 @@
+package iproute
+
+import (
+	"reflect"
+	"testing"
+)
+
+// captured by "ip route show table all" on edge1
+const routeOutput = `10.233.64.0/18 via 10.22.46.47 dev eth0 table 220 proto static src 10.233.67.1 
+unreachable 10.233.70.0/24 table 220 
+default via 10.22.46.1 dev eth0 proto dhcp src 10.22.46.18 metric 100 
+10.22.46.0/24 dev eth0 proto kernel scope link src 10.22.46.18 
+10.233.67.0/24 dev br-fabedge proto kernel scope link src 10.233.67.1 
+10.233.80.0/24 proto static metric 1024 
+	nexthop via 10.22.46.1 dev eth0 weight 1 
+	nexthop via 10.22.46.2 dev eth1 weight 1 
+broadcast 10.22.46.255 dev eth0 table local proto kernel scope link src 10.22.46.18 
+local 10.22.46.18 dev eth0 table local proto kernel scope host src 10.22.46.18 
+fe80::/64 dev eth0 proto kernel metric 256 pref medium
+`
+
+// captured by "ip rule" on edge1
+const ruleOutput = `0:	from all lookup local
+220:	from all lookup 220
+32766:	from all lookup main
+32767:	from all lookup default
+`
+
+func TestParseRoutes(t *testing.T) {
+	expected := []Route{
+		{Dst: "10.233.64.0/18", Via: "10.22.46.47", Dev: "eth0", Table: "220"},
+		{Type: "unreachable", Dst: "10.233.70.0/24", Table: "220"},
+		{Dst: "0.0.0.0/0", Via: "10.22.46.1", Dev: "eth0", Table: TableMain},
+		{Dst: "10.22.46.0/24", Dev: "eth0", Table: TableMain},
+		{Dst: "10.233.67.0/24", Dev: "br-fabedge", Table: TableMain},
+		{Dst: "10.233.80.0/24", Table: TableMain},
+		{Type: "broadcast", Dst: "10.22.46.255/32", Dev: "eth0", Table: TableLocal},
+		{Type: "local", Dst: "10.22.46.18/32", Dev: "eth0", Table: TableLocal},
+		{Dst: "fe80::/64", Dev: "eth0", Table: TableMain},
+	}
+
+	if got := ParseRoutes(routeOutput); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	expected := []Rule{
+		{Priority: "0", Selector: "from all lookup local", Table: TableLocal},
+		{Priority: "220", Selector: "from all lookup 220", Table: "220"},
+		{Priority: "32766", Selector: "from all lookup main", Table: TableMain},
+		{Priority: "32767", Selector: "from all lookup default", Table: "default"},
+	}
+
+	if got := ParseRules(ruleOutput); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	testCases := []struct {
+		dst      string
+		expected string
+	}{
+		{"default", "0.0.0.0/0"},
+		{"10.22.46.18", "10.22.46.18/32"},
+		{"fd00::1", "fd00::1/128"},
+		{"10.233.67.1/24", "10.233.67.0/24"},
+		{"10.233.67.0/24", "10.233.67.0/24"},
+		{"invalid", "invalid"},
+	}
+
+	for _, tc := range testCases {
+		if got := Normalize(tc.dst); got != tc.expected {
+			t.Errorf("Normalize(%q): expected %q, got %q", tc.dst, tc.expected, got)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	testCases := []struct {
+		cidr     string
+		ip       string
+		expected bool
+	}{
+		{"10.233.67.0/24", "10.233.67.10", true},
+		{"10.233.67.0/24", "10.233.68.10", false},
+		{"10.22.46.18", "10.22.46.18", true},
+		{"10.22.46.18", "10.22.46.19", false},
+		{"10.233.67.0/24", "invalid", false},
+	}
+
+	for _, tc := range testCases {
+		if got := Contains(tc.cidr, tc.ip); got != tc.expected {
+			t.Errorf("Contains(%q, %q): expected %t, got %t", tc.cidr, tc.ip, tc.expected, got)
+		}
+	}
+}
+
+func TestCovers(t *testing.T) {
+	testCases := []struct {
+		cidr     string
+		subnet   string
+		expected bool
+	}{
+		{"10.233.64.0/18", "10.233.67.0/24", true},
+		{"10.233.67.0/24", "10.233.64.0/18", false},
+		{"10.233.67.0/24", "10.233.67.0/24", true},
+		{"10.233.67.0/24", "10.233.67.10", true},
+		{"default", "10.233.67.0/24", true},
+		{"10.233.67.0/24", "10.233.68.0/24", false},
+		{"::/0", "10.233.67.0/24", false},
+		{"invalid", "10.233.67.0/24", false},
+	}
+
+	for _, tc := range testCases {
+		if got := Covers(tc.cidr, tc.subnet); got != tc.expected {
+			t.Errorf("Covers(%q, %q): expected %t, got %t", tc.cidr, tc.subnet, tc.expected, got)
+		}
+	}
+}
+
+func TestSameSubnet(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"10.22.46.18", "10.22.46.18/32", true},
+		{"10.233.67.1/24", "10.233.67.0/24", true},
+		{"10.233.67.0/24", "10.233.67.0/25", false},
+	}
+
+	for _, tc := range testCases {
+		if got := SameSubnet(tc.a, tc.b); got != tc.expected {
+			t.Errorf("SameSubnet(%q, %q): expected %t, got %t", tc.a, tc.b, tc.expected, got)
+		}
+	}
+}
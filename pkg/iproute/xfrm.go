@@ -0,0 +1,132 @@
+package iproute
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	DirIn  = "in"
+	DirOut = "out"
+	DirFwd = "fwd"
+)
+
+// Policy is a xfrm policy listed by "ip xfrm policy", tunnel addresses come from its template
+type Policy struct {
+	Src       string `json:"src"`
+	Dst       string `json:"dst"`
+	Dir       string `json:"dir"`
+	Priority  string `json:"priority,omitempty"`
+	TunnelSrc string `json:"tunnelSrc,omitempty"`
+	TunnelDst string `json:"tunnelDst,omitempty"`
+	ReqID     string `json:"reqID,omitempty"`
+}
+
+func (p Policy) String() string {
+	s := fmt.Sprintf("%s %s -> %s", p.Dir, p.Src, p.Dst)
+	if p.TunnelDst != "" {
+		s += fmt.Sprintf(" tunnel %s -> %s", p.TunnelSrc, p.TunnelDst)
+	}
+
+	return s
+}
+
+// State is a xfrm state listed by "ip xfrm state"
+type State struct {
+	Src   string `json:"src"`
+	Dst   string `json:"dst"`
+	Proto string `json:"proto"`
+	SPI   string `json:"spi"`
+	ReqID string `json:"reqID,omitempty"`
+	Mode  string `json:"mode,omitempty"`
+}
+
+func (s State) String() string {
+	return fmt.Sprintf("%s %s -> %s spi %s", s.Proto, s.Src, s.Dst, s.SPI)
+}
+
+// ParsePolicies parses output of "ip xfrm policy", each policy starts with a "src ... dst ..." line
+// followed by indented dir and tmpl lines. Socket policies are ignored.
+func ParsePolicies(output string) []Policy {
+	var (
+		policies []Policy
+		current  *Policy
+	)
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "src":
+			if len(fields) < 4 || fields[2] != "dst" {
+				current = nil
+				continue
+			}
+			policies = append(policies, Policy{Src: fields[1], Dst: fields[3]})
+			current = &policies[len(policies)-1]
+		case "dir":
+			if current != nil && len(fields) > 1 {
+				current.Dir = fields[1]
+				current.Priority = valueOf(fields, "priority")
+			}
+		case "tmpl":
+			if current != nil && len(fields) >= 5 && fields[1] == "src" && fields[3] == "dst" {
+				current.TunnelSrc, current.TunnelDst = fields[2], fields[4]
+			}
+		case "proto":
+			if current != nil {
+				current.ReqID = valueOf(fields, "reqid")
+			}
+		}
+	}
+
+	var result []Policy
+	for _, p := range policies {
+		if p.Dir == DirIn || p.Dir == DirOut || p.Dir == DirFwd {
+			result = append(result, p)
+		}
+	}
+
+	return result
+}
+
+// ParseStates parses output of "ip xfrm state", each state starts with a "src ... dst ..." line
+// followed by indented lines like "proto esp spi 0xc2f9a4ab reqid 1 mode tunnel".
+func ParseStates(output string) []State {
+	var states []State
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case fields[0] == "src" && len(fields) >= 4 && fields[2] == "dst" && !strings.HasPrefix(line, "\t"):
+			states = append(states, State{Src: fields[1], Dst: fields[3]})
+		case fields[0] == "proto" && len(states) > 0:
+			s := &states[len(states)-1]
+			if s.Proto == "" {
+				s.Proto = fields[1]
+				s.SPI = valueOf(fields, "spi")
+				s.ReqID = valueOf(fields, "reqid")
+				s.Mode = valueOf(fields, "mode")
+			}
+		}
+	}
+
+	return states
+}
+
+// valueOf returns the field after key, e.g. valueOf(["reqid", "1"], "reqid") returns "1"
+func valueOf(fields []string, key string) string {
+	for i := 0; i < len(fields)-1; i++ {
+		if fields[i] == key {
+			return fields[i+1]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,136 @@
+package iproute
+
+import (
+	"reflect"
+	"testing"
+)
+
+// captured by "ip xfrm state" on edge1, keys are removed
+const xfrmStateOutput = `src 10.22.46.18 dst 10.22.46.47
+	proto esp spi 0xc27b9d41 reqid 3 mode tunnel
+	replay-window 0 flag af-unspec
+	aead rfc4106(gcm(aes)) 0x 128
+	encap type espinudp sport 4500 dport 4500 addr 0.0.0.0
+	anti-replay context: seq 0x0, oseq 0xd3, bitmap 0x00000000
+src 10.22.46.47 dst 10.22.46.18
+	proto esp spi 0xc8e1a6f2 reqid 3 mode tunnel
+	replay-window 32 flag af-unspec
+	aead rfc4106(gcm(aes)) 0x 128
+	encap type espinudp sport 4500 dport 4500 addr 0.0.0.0
+	anti-replay context: seq 0xf0, oseq 0x0, bitmap 0xffffffff
+	sel src 0.0.0.0/0 dst 0.0.0.0/0
+`
+
+// captured by "ip xfrm policy" on edge1
+const xfrmPolicyOutput = `src 10.233.67.0/24 dst 10.233.64.0/18 
+	dir out priority 375423 ptype main 
+	tmpl src 10.22.46.18 dst 10.22.46.47
+		proto esp spi 0xc27b9d41 reqid 3 mode tunnel
+src 10.233.64.0/18 dst 10.233.67.0/24 
+	dir fwd priority 375423 ptype main 
+	tmpl src 10.22.46.47 dst 10.22.46.18
+		proto esp reqid 3 mode tunnel
+src 10.233.64.0/18 dst 10.233.67.0/24 
+	dir in priority 375423 ptype main 
+	tmpl src 10.22.46.47 dst 10.22.46.18
+		proto esp reqid 3 mode tunnel
+src 0.0.0.0/0 dst 0.0.0.0/0 
+	socket in priority 0 ptype main 
+src 0.0.0.0/0 dst 0.0.0.0/0 
+	socket out priority 0 ptype main 
+src ::/0 dst ::/0 
+	socket in priority 0 ptype main 
+`
+
+func TestParseStates(t *testing.T) {
+	testCases := []struct {
+		name     string
+		output   string
+		expected []State
+	}{
+		{
+			name:   "states of a tunnel",
+			output: xfrmStateOutput,
+			expected: []State{
+				{Src: "10.22.46.18", Dst: "10.22.46.47", Proto: "esp", SPI: "0xc27b9d41", ReqID: "3", Mode: "tunnel"},
+				{Src: "10.22.46.47", Dst: "10.22.46.18", Proto: "esp", SPI: "0xc8e1a6f2", ReqID: "3", Mode: "tunnel"},
+			},
+		},
+		{
+			name:     "no states",
+			output:   "",
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ParseStates(tc.output); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParsePolicies(t *testing.T) {
+	testCases := []struct {
+		name     string
+		output   string
+		expected []Policy
+	}{
+		{
+			name:   "socket policies are ignored",
+			output: xfrmPolicyOutput,
+			expected: []Policy{
+				{Src: "10.233.67.0/24", Dst: "10.233.64.0/18", Dir: DirOut, Priority: "375423", TunnelSrc: "10.22.46.18", TunnelDst: "10.22.46.47", ReqID: "3"},
+				{Src: "10.233.64.0/18", Dst: "10.233.67.0/24", Dir: DirFwd, Priority: "375423", TunnelSrc: "10.22.46.47", TunnelDst: "10.22.46.18", ReqID: "3"},
+				{Src: "10.233.64.0/18", Dst: "10.233.67.0/24", Dir: DirIn, Priority: "375423", TunnelSrc: "10.22.46.47", TunnelDst: "10.22.46.18", ReqID: "3"},
+			},
+		},
+		{
+			name:     "no policies",
+			output:   "",
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ParsePolicies(tc.output); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestPolicyAndStateString(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    interface{ String() string }
+		expected string
+	}{
+		{
+			name:     "policy with tunnel",
+			value:    Policy{Src: "10.233.67.0/24", Dst: "10.233.64.0/18", Dir: DirOut, TunnelSrc: "10.22.46.18", TunnelDst: "10.22.46.47"},
+			expected: "out 10.233.67.0/24 -> 10.233.64.0/18 tunnel 10.22.46.18 -> 10.22.46.47",
+		},
+		{
+			name:     "policy without tunnel",
+			value:    Policy{Src: "10.233.64.0/18", Dst: "10.233.67.0/24", Dir: DirIn},
+			expected: "in 10.233.64.0/18 -> 10.233.67.0/24",
+		},
+		{
+			name:     "state",
+			value:    State{Src: "10.22.46.18", Dst: "10.22.46.47", Proto: "esp", SPI: "0xc27b9d41"},
+			expected: "esp 10.22.46.18 -> 10.22.46.47 spi 0xc27b9d41",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.value.String(); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
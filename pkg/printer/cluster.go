@@ -0,0 +1,73 @@
+package printer
+
+import (
+	"fmt"
+	"io"
+)
+
+// ClusterResult is the result of a command executed against a cluster
+type ClusterResult struct {
+	Cluster string      `json:"cluster"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// ClusterResults merges results from multiple clusters, when printed as a table,
+// a CLUSTER column is inserted before columns of each result
+type ClusterResults []ClusterResult
+
+func (results ClusterResults) Describe(w io.Writer) {
+	for _, r := range results {
+		fmt.Fprintf(w, "========================== %s =================================\n", r.Cluster)
+		if r.Error != "" {
+			fmt.Fprintf(w, "Error: %s\n\n", r.Error)
+			continue
+		}
+
+		if describer, ok := r.Result.(Describer); ok {
+			describer.Describe(w)
+		} else if table, ok := r.Result.(Table); ok {
+			PrintTable(w, table, false)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func (results ClusterResults) Header(wide bool) []string {
+	for _, r := range results {
+		if table, ok := r.Result.(Table); ok {
+			return append([]string{"CLUSTER"}, table.Header(wide)...)
+		}
+	}
+
+	return []string{"CLUSTER"}
+}
+
+func (results ClusterResults) Rows(wide bool) [][]string {
+	var rows [][]string
+	for _, r := range results {
+		// errors are not rows, they are reported by Errors
+		table, ok := r.Result.(Table)
+		if !ok {
+			continue
+		}
+
+		for _, row := range table.Rows(wide) {
+			rows = append(rows, append([]string{r.Cluster}, row...))
+		}
+	}
+
+	return rows
+}
+
+// Errors returns errors of clusters which failed to execute command, each one is prefixed with cluster name
+func (results ClusterResults) Errors() []string {
+	var errs []string
+	for _, r := range results {
+		if r.Error != "" {
+			errs = append(errs, fmt.Sprintf("%s: %s", r.Cluster, r.Error))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,169 @@
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"sigs.k8s.io/yaml"
+)
+
+type Format string
+
+const (
+	// FormatDefault prints results in a human-readable description format
+	FormatDefault Format = ""
+	FormatJSON    Format = "json"
+	FormatYAML    Format = "yaml"
+	FormatTable   Format = "table"
+	FormatWide    Format = "wide"
+)
+
+var formats = []Format{FormatJSON, FormatYAML, FormatTable, FormatWide}
+
+// Describer is implemented by results which can describe themselves
+// in a human-readable format, it's used when no output format is specified
+type Describer interface {
+	Describe(w io.Writer)
+}
+
+// Table is implemented by results which can be printed as a table.
+// If wide is true, more columns are expected.
+type Table interface {
+	Header(wide bool) []string
+	Rows(wide bool) [][]string
+}
+
+type Printer struct {
+	Format Format
+	Out    io.Writer
+}
+
+func New(format string) (Printer, error) {
+	p := Printer{
+		Format: Format(format),
+		Out:    os.Stdout,
+	}
+
+	if p.Format == FormatDefault {
+		return p, nil
+	}
+
+	for _, f := range formats {
+		if p.Format == f {
+			return p, nil
+		}
+	}
+
+	return p, fmt.Errorf("unknown output format: %s, possible values: %s", format, FormatNames())
+}
+
+func FormatNames() string {
+	var names []string
+	for _, f := range formats {
+		names = append(names, string(f))
+	}
+
+	return strings.Join(names, "|")
+}
+
+func (p Printer) Print(obj interface{}) error {
+	switch p.Format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(obj, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(p.Out, string(data))
+		return err
+	case FormatYAML:
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		_, err = p.Out.Write(data)
+		return err
+	case FormatTable, FormatWide:
+		table, ok := obj.(Table)
+		if !ok {
+			return fmt.Errorf("output format %s is not supported", p.Format)
+		}
+		return PrintTable(p.Out, table, p.Format == FormatWide)
+	default:
+		if describer, ok := obj.(Describer); ok {
+			describer.Describe(p.Out)
+			return nil
+		}
+
+		if table, ok := obj.(Table); ok {
+			return PrintTable(p.Out, table, false)
+		}
+
+		return fmt.Errorf("no default output format for %T", obj)
+	}
+}
+
+// IsStructured returns true if output is json or yaml
+func (p Printer) IsStructured() bool {
+	return p.Format == FormatJSON || p.Format == FormatYAML
+}
+
+func PrintTable(w io.Writer, table Table, wide bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+
+	fmt.Fprintln(tw, strings.Join(table.Header(wide), "\t"))
+	for _, row := range table.Rows(wide) {
+		// rows may be kept by the table, e.g. SimpleTable, so they are not changed
+		cells := make([]string, len(row))
+		for i := range row {
+			cells[i] = row[i]
+			if cells[i] == "" {
+				cells[i] = "<none>"
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// SimpleTable is a table whose header and rows are built already, it's used to print
+// matrices and summaries inside descriptions
+type SimpleTable struct {
+	Columns []string
+	Data    [][]string
+}
+
+func (t SimpleTable) Header(wide bool) []string {
+	return t.Columns
+}
+
+func (t SimpleTable) Rows(wide bool) [][]string {
+	return t.Data
+}
+
+// KeyValue is a line of description output
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// Describe writes key-values as an aligned block which is
+// separated with previous output by a blank line
+func Describe(w io.Writer, kvs ...KeyValue) {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+
+	fmt.Fprintln(tw)
+	for _, kv := range kvs {
+		fmt.Fprintf(tw, "%s:\t%s\n", kv.Key, kv.Value)
+	}
+	tw.Flush()
+}
+
+// Join joins values with comma, it's used to format slices in tables and descriptions
+func Join(values []string) string {
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,124 @@
+package strongswan
+
+import (
+	"sort"
+	"strings"
+)
+
+// Connection is a connection loaded in strongswan, listed by swanctl --list-conns, times are in seconds
+type Connection struct {
+	Name        string        `json:"name"`
+	Version     string        `json:"version"`
+	LocalAddrs  []string      `json:"localAddrs"`
+	RemoteAddrs []string      `json:"remoteAddrs"`
+	ReauthTime  int64         `json:"reauthTime"`
+	RekeyTime   int64         `json:"rekeyTime"`
+	Unique      string        `json:"unique"`
+	LocalAuths  []Auth        `json:"localAuths"`
+	RemoteAuths []Auth        `json:"remoteAuths"`
+	Children    []ChildConfig `json:"children"`
+}
+
+// Auth is an authentication round of a connection
+type Auth struct {
+	Class   string   `json:"class"`
+	ID      string   `json:"id,omitempty"`
+	Certs   []string `json:"certs,omitempty"`
+	CACerts []string `json:"caCerts,omitempty"`
+}
+
+// ChildConfig is a CHILD SA config of a connection
+type ChildConfig struct {
+	Name        string   `json:"name"`
+	Mode        string   `json:"mode"`
+	RekeyTime   int64    `json:"rekeyTime"`
+	DPDAction   string   `json:"dpdAction,omitempty"`
+	CloseAction string   `json:"closeAction,omitempty"`
+	LocalTS     []string `json:"localTS"`
+	RemoteTS    []string `json:"remoteTS"`
+}
+
+// ParseConnections parses output of swanctl --list-conns --pretty
+func ParseConnections(output string) ([]Connection, error) {
+	events, err := ParseVICI(output)
+	if err != nil {
+		return nil, err
+	}
+
+	var conns []Connection
+	for _, event := range events {
+		for _, s := range event.Sections {
+			conns = append(conns, newConnection(s))
+		}
+	}
+
+	return conns, nil
+}
+
+func newConnection(s *Section) Connection {
+	conn := Connection{
+		Name:        s.Name,
+		Version:     s.Values["version"],
+		LocalAddrs:  s.Lists["local_addrs"],
+		RemoteAddrs: s.Lists["remote_addrs"],
+		ReauthTime:  s.Int("reauth_time"),
+		RekeyTime:   s.Int("rekey_time"),
+		Unique:      s.Values["unique"],
+		Children:    []ChildConfig{},
+	}
+
+	for _, sub := range s.Sections {
+		switch {
+		// authentication sections are named as local-1, remote-1 and so on
+		case strings.HasPrefix(sub.Name, "local"):
+			conn.LocalAuths = append(conn.LocalAuths, newAuth(sub))
+		case strings.HasPrefix(sub.Name, "remote"):
+			conn.RemoteAuths = append(conn.RemoteAuths, newAuth(sub))
+		case sub.Name == "children":
+			for _, c := range sub.Sections {
+				conn.Children = append(conn.Children, ChildConfig{
+					Name:        c.Name,
+					Mode:        c.Values["mode"],
+					RekeyTime:   c.Int("rekey_time"),
+					DPDAction:   c.Values["dpd_action"],
+					CloseAction: c.Values["close_action"],
+					LocalTS:     c.Lists["local-ts"],
+					RemoteTS:    c.Lists["remote-ts"],
+				})
+			}
+		}
+	}
+
+	sort.Slice(conn.Children, func(i, j int) bool {
+		return conn.Children[i].Name < conn.Children[j].Name
+	})
+
+	return conn
+}
+
+func newAuth(s *Section) Auth {
+	return Auth{
+		Class:   s.Values["class"],
+		ID:      s.Values["id"],
+		Certs:   s.Lists["certs"],
+		CACerts: s.Lists["cacerts"],
+	}
+}
+
+// LocalID returns the id of first local authentication round
+func (conn Connection) LocalID() string {
+	if len(conn.LocalAuths) == 0 {
+		return ""
+	}
+
+	return conn.LocalAuths[0].ID
+}
+
+// RemoteID returns the id of first remote authentication round
+func (conn Connection) RemoteID() string {
+	if len(conn.RemoteAuths) == 0 {
+		return ""
+	}
+
+	return conn.RemoteAuths[0].ID
+}
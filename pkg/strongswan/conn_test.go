@@ -0,0 +1,156 @@
+package strongswan
+
+import (
+	"reflect"
+	"testing"
+)
+
+// captured by swanctl --list-conns --pretty in agent pod
+const listConnsOutput = `list-conn event {
+  beijing.connector {
+    local_addrs [
+      %any
+    ]
+    remote_addrs [
+      10.22.46.47
+    ]
+    version = IKEv2
+    reauth_time = 0
+    rekey_time = 14400
+    unique = UNIQUE_NO
+    dpd_delay = 30
+    local-1 {
+      class = public key
+      id = C=CN, O=fabedge.io, CN=beijing.edge1
+      certs [
+        C=CN, O=fabedge.io, CN=beijing.edge1
+      ]
+    }
+    remote-1 {
+      class = public key
+      id = C=CN, O=fabedge.io, CN=beijing.connector
+      cacerts [
+        CN=fabedge-ca
+      ]
+    }
+    children {
+      beijing.connector-p2p {
+        mode = TUNNEL
+        rekey_time = 3600
+        rekey_bytes = 0
+        rekey_packets = 0
+        dpd_action = restart
+        close_action = none
+        local-ts [
+          10.233.67.0/24
+        ]
+        remote-ts [
+          10.233.64.0/18
+          10.233.0.0/18
+        ]
+      }
+      beijing.connector-n2p {
+        mode = TUNNEL
+        rekey_time = 3600
+        local-ts [
+          10.22.46.18/32
+        ]
+        remote-ts [
+          10.233.64.0/18
+        ]
+      }
+    }
+  }
+}
+list-conns reply {
+}
+`
+
+func TestParseConnections(t *testing.T) {
+	conns, err := ParseConnections(listConnsOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []Connection{
+		{
+			Name:        "beijing.connector",
+			Version:     "IKEv2",
+			LocalAddrs:  []string{"%any"},
+			RemoteAddrs: []string{"10.22.46.47"},
+			RekeyTime:   14400,
+			Unique:      "UNIQUE_NO",
+			LocalAuths: []Auth{
+				{
+					Class: "public key",
+					ID:    "C=CN, O=fabedge.io, CN=beijing.edge1",
+					Certs: []string{"C=CN, O=fabedge.io, CN=beijing.edge1"},
+				},
+			},
+			RemoteAuths: []Auth{
+				{
+					Class:   "public key",
+					ID:      "C=CN, O=fabedge.io, CN=beijing.connector",
+					CACerts: []string{"CN=fabedge-ca"},
+				},
+			},
+			Children: []ChildConfig{
+				{
+					Name:      "beijing.connector-n2p",
+					Mode:      "TUNNEL",
+					RekeyTime: 3600,
+					LocalTS:   []string{"10.22.46.18/32"},
+					RemoteTS:  []string{"10.233.64.0/18"},
+				},
+				{
+					Name:        "beijing.connector-p2p",
+					Mode:        "TUNNEL",
+					RekeyTime:   3600,
+					DPDAction:   "restart",
+					CloseAction: "none",
+					LocalTS:     []string{"10.233.67.0/24"},
+					RemoteTS:    []string{"10.233.64.0/18", "10.233.0.0/18"},
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(conns, expected) {
+		t.Errorf("expected %+v, got %+v", expected, conns)
+	}
+}
+
+func TestConnectionIDs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		conn     Connection
+		localID  string
+		remoteID string
+	}{
+		{
+			name: "first rounds are used",
+			conn: Connection{
+				LocalAuths:  []Auth{{ID: "C=CN, O=fabedge.io, CN=beijing.edge1"}, {ID: "edge1"}},
+				RemoteAuths: []Auth{{ID: "C=CN, O=fabedge.io, CN=beijing.connector"}},
+			},
+			localID:  "C=CN, O=fabedge.io, CN=beijing.edge1",
+			remoteID: "C=CN, O=fabedge.io, CN=beijing.connector",
+		},
+		{
+			name: "no authentication rounds",
+			conn: Connection{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.conn.LocalID(); got != tc.localID {
+				t.Errorf("expected local id %q, got %q", tc.localID, got)
+			}
+
+			if got := tc.conn.RemoteID(); got != tc.remoteID {
+				t.Errorf("expected remote id %q, got %q", tc.remoteID, got)
+			}
+		})
+	}
+}
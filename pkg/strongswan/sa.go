@@ -0,0 +1,211 @@
+package strongswan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	IKEStateEstablished = "ESTABLISHED"
+	IKEStateConnecting  = "CONNECTING"
+
+	ChildStateInstalled = "INSTALLED"
+)
+
+// IKESA is an IKE SA listed by swanctl --list-sa, times are in seconds
+type IKESA struct {
+	Name         string    `json:"name"`
+	UniqueID     string    `json:"uniqueID"`
+	Version      string    `json:"version"`
+	State        string    `json:"state"`
+	LocalHost    string    `json:"localHost"`
+	LocalPort    string    `json:"localPort"`
+	LocalID      string    `json:"localID"`
+	RemoteHost   string    `json:"remoteHost"`
+	RemotePort   string    `json:"remotePort"`
+	RemoteID     string    `json:"remoteID"`
+	Initiator    bool      `json:"initiator"`
+	InitiatorSPI string    `json:"initiatorSPI"`
+	ResponderSPI string    `json:"responderSPI"`
+	EncrAlg      string    `json:"encrAlg,omitempty"`
+	EncrKeysize  string    `json:"encrKeysize,omitempty"`
+	IntegAlg     string    `json:"integAlg,omitempty"`
+	IntegKeysize string    `json:"integKeysize,omitempty"`
+	PRFAlg       string    `json:"prfAlg,omitempty"`
+	DHGroup      string    `json:"dhGroup,omitempty"`
+	Established  int64     `json:"established"`
+	RekeyTime    int64     `json:"rekeyTime"`
+	ReauthTime   int64     `json:"reauthTime"`
+	ChildSAs     []ChildSA `json:"childSAs"`
+}
+
+// ChildSA is a CHILD SA of an IKE SA, times are in seconds
+type ChildSA struct {
+	Name         string   `json:"name"`
+	UniqueID     string   `json:"uniqueID"`
+	ReqID        string   `json:"reqID"`
+	State        string   `json:"state"`
+	Mode         string   `json:"mode"`
+	Protocol     string   `json:"protocol"`
+	SPIIn        string   `json:"spiIn"`
+	SPIOut       string   `json:"spiOut"`
+	EncrAlg      string   `json:"encrAlg,omitempty"`
+	EncrKeysize  string   `json:"encrKeysize,omitempty"`
+	IntegAlg     string   `json:"integAlg,omitempty"`
+	IntegKeysize string   `json:"integKeysize,omitempty"`
+	DHGroup      string   `json:"dhGroup,omitempty"`
+	BytesIn      uint64   `json:"bytesIn"`
+	PacketsIn    uint64   `json:"packetsIn"`
+	BytesOut     uint64   `json:"bytesOut"`
+	PacketsOut   uint64   `json:"packetsOut"`
+	InstallTime  int64    `json:"installTime"`
+	RekeyTime    int64    `json:"rekeyTime"`
+	LifeTime     int64    `json:"lifeTime"`
+	LocalTS      []string `json:"localTS"`
+	RemoteTS     []string `json:"remoteTS"`
+}
+
+// ParseSAs parses output of swanctl --list-sa --pretty
+func ParseSAs(output string) ([]IKESA, error) {
+	events, err := ParseVICI(output)
+	if err != nil {
+		return nil, err
+	}
+
+	var sas []IKESA
+	for _, event := range events {
+		for _, s := range event.Sections {
+			sas = append(sas, newIKESA(s))
+		}
+	}
+
+	return sas, nil
+}
+
+func newIKESA(s *Section) IKESA {
+	sa := IKESA{
+		Name:         s.Name,
+		UniqueID:     s.Values["uniqueid"],
+		Version:      s.Values["version"],
+		State:        s.Values["state"],
+		LocalHost:    s.Values["local-host"],
+		LocalPort:    s.Values["local-port"],
+		LocalID:      s.Values["local-id"],
+		RemoteHost:   s.Values["remote-host"],
+		RemotePort:   s.Values["remote-port"],
+		RemoteID:     s.Values["remote-id"],
+		Initiator:    s.Bool("initiator"),
+		InitiatorSPI: s.Values["initiator-spi"],
+		ResponderSPI: s.Values["responder-spi"],
+		EncrAlg:      s.Values["encr-alg"],
+		EncrKeysize:  s.Values["encr-keysize"],
+		IntegAlg:     s.Values["integ-alg"],
+		IntegKeysize: s.Values["integ-keysize"],
+		PRFAlg:       s.Values["prf-alg"],
+		DHGroup:      s.Values["dh-group"],
+		Established:  s.Int("established"),
+		RekeyTime:    s.Int("rekey-time"),
+		ReauthTime:   s.Int("reauth-time"),
+		ChildSAs:     []ChildSA{},
+	}
+
+	if children := s.Section("child-sas"); children != nil {
+		for _, c := range children.Sections {
+			sa.ChildSAs = append(sa.ChildSAs, newChildSA(c))
+		}
+	}
+
+	sort.Slice(sa.ChildSAs, func(i, j int) bool {
+		return sa.ChildSAs[i].Name < sa.ChildSAs[j].Name
+	})
+
+	return sa
+}
+
+func newChildSA(s *Section) ChildSA {
+	// child sections are named like name-uniqueid, name value is preferred
+	name := s.Values["name"]
+	if name == "" {
+		name = s.Name
+	}
+
+	return ChildSA{
+		Name:         name,
+		UniqueID:     s.Values["uniqueid"],
+		ReqID:        s.Values["reqid"],
+		State:        s.Values["state"],
+		Mode:         s.Values["mode"],
+		Protocol:     s.Values["protocol"],
+		SPIIn:        s.Values["spi-in"],
+		SPIOut:       s.Values["spi-out"],
+		EncrAlg:      s.Values["encr-alg"],
+		EncrKeysize:  s.Values["encr-keysize"],
+		IntegAlg:     s.Values["integ-alg"],
+		IntegKeysize: s.Values["integ-keysize"],
+		DHGroup:      s.Values["dh-group"],
+		BytesIn:      s.Uint("bytes-in"),
+		PacketsIn:    s.Uint("packets-in"),
+		BytesOut:     s.Uint("bytes-out"),
+		PacketsOut:   s.Uint("packets-out"),
+		InstallTime:  s.Int("install-time"),
+		RekeyTime:    s.Int("rekey-time"),
+		LifeTime:     s.Int("life-time"),
+		LocalTS:      s.Lists["local-ts"],
+		RemoteTS:     s.Lists["remote-ts"],
+	}
+}
+
+// Algorithms returns proposal of IKE SA in the format used by swanctl, e.g. AES_CBC-128/HMAC_SHA2_256_128/PRF_AES128_XCBC/ECP_256
+func (sa IKESA) Algorithms() string {
+	return joinAlgorithms(
+		withKeysize(sa.EncrAlg, sa.EncrKeysize),
+		withKeysize(sa.IntegAlg, sa.IntegKeysize),
+		sa.PRFAlg,
+		sa.DHGroup,
+	)
+}
+
+func (sa IKESA) IsEstablished() bool {
+	return sa.State == IKEStateEstablished
+}
+
+// InstalledChildSAs returns how many CHILD SAs are installed
+func (sa IKESA) InstalledChildSAs() int {
+	count := 0
+	for _, child := range sa.ChildSAs {
+		if child.State == ChildStateInstalled {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Algorithms returns proposal of CHILD SA, e.g. AES_GCM_16-128
+func (sa ChildSA) Algorithms() string {
+	return joinAlgorithms(
+		withKeysize(sa.EncrAlg, sa.EncrKeysize),
+		withKeysize(sa.IntegAlg, sa.IntegKeysize),
+		sa.DHGroup,
+	)
+}
+
+func withKeysize(alg, keysize string) string {
+	if alg == "" || keysize == "" {
+		return alg
+	}
+
+	return fmt.Sprintf("%s-%s", alg, keysize)
+}
+
+func joinAlgorithms(algs ...string) string {
+	var values []string
+	for _, alg := range algs {
+		if alg != "" {
+			values = append(values, alg)
+		}
+	}
+
+	return strings.Join(values, "/")
+}
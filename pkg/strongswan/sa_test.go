@@ -0,0 +1,305 @@
+package strongswan
+
+import (
+	"reflect"
+	"testing"
+)
+
+// captured by swanctl --list-sas --pretty in connector pod, SPIs and counters are changed
+const listSAsOutput = `list-sa event {
+  beijing.edge1 {
+    uniqueid = 3
+    version = 2
+    state = ESTABLISHED
+    local-host = 10.22.46.47
+    local-port = 4500
+    local-id = C=CN, O=fabedge.io, CN=beijing.connector
+    remote-host = 10.22.46.18
+    remote-port = 4500
+    remote-id = C=CN, O=fabedge.io, CN=beijing.edge1
+    initiator-spi = 5b07c9a3e1f2d4c6
+    responder-spi = 9e8d7c6b5a493827
+    nat-remote = yes
+    encr-alg = AES_CBC
+    encr-keysize = 128
+    integ-alg = HMAC_SHA2_256_128
+    prf-alg = PRF_AES128_XCBC
+    dh-group = ECP_256
+    established = 1523
+    rekey-time = 12413
+    child-sas {
+      beijing.edge1-p2p-9 {
+        name = beijing.edge1-p2p
+        uniqueid = 9
+        reqid = 3
+        state = INSTALLED
+        mode = TUNNEL
+        protocol = ESP
+        encap = yes
+        spi-in = c8e1a6f2
+        spi-out = c27b9d41
+        encr-alg = AES_GCM_16
+        encr-keysize = 128
+        bytes-in = 20160
+        packets-in = 240
+        use-in = 2
+        bytes-out = 17724
+        packets-out = 211
+        use-out = 2
+        rekey-time = 2011
+        life-time = 2557
+        install-time = 1043
+        local-ts [
+          10.233.64.0/18
+        ]
+        remote-ts [
+          10.233.67.0/24
+        ]
+      }
+      beijing.edge1-n2p-10 {
+        name = beijing.edge1-n2p
+        uniqueid = 10
+        reqid = 4
+        state = REKEYED
+        mode = TUNNEL
+        protocol = ESP
+        spi-in = c9b2d3e4
+        spi-out = c1a2b3c4
+        encr-alg = AES_GCM_16
+        encr-keysize = 128
+        bytes-in = 0
+        packets-in = 0
+        bytes-out = 0
+        packets-out = 0
+        rekey-time = 0
+        life-time = 7
+        install-time = 3593
+        local-ts [
+          10.22.46.47/32
+        ]
+        remote-ts [
+          10.233.67.0/24
+        ]
+      }
+    }
+  }
+}
+list-sa event {
+  beijing.edge2 {
+    uniqueid = 4
+    version = 2
+    state = CONNECTING
+    local-host = 10.22.46.47
+    local-port = 500
+    local-id = C=CN, O=fabedge.io, CN=beijing.connector
+    remote-host = 10.22.46.45
+    remote-port = 500
+    remote-id = %any
+    initiator = yes
+    initiator-spi = 0a1b2c3d4e5f6a7b
+    responder-spi = 0000000000000000
+    tasks-active [
+      IKE_VENDOR
+      IKE_INIT
+    ]
+    child-sas {
+    }
+  }
+}
+`
+
+func TestParseSAs(t *testing.T) {
+	sas, err := ParseSAs(listSAsOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []IKESA{
+		{
+			Name:         "beijing.edge1",
+			UniqueID:     "3",
+			Version:      "2",
+			State:        IKEStateEstablished,
+			LocalHost:    "10.22.46.47",
+			LocalPort:    "4500",
+			LocalID:      "C=CN, O=fabedge.io, CN=beijing.connector",
+			RemoteHost:   "10.22.46.18",
+			RemotePort:   "4500",
+			RemoteID:     "C=CN, O=fabedge.io, CN=beijing.edge1",
+			InitiatorSPI: "5b07c9a3e1f2d4c6",
+			ResponderSPI: "9e8d7c6b5a493827",
+			EncrAlg:      "AES_CBC",
+			EncrKeysize:  "128",
+			IntegAlg:     "HMAC_SHA2_256_128",
+			PRFAlg:       "PRF_AES128_XCBC",
+			DHGroup:      "ECP_256",
+			Established:  1523,
+			RekeyTime:    12413,
+			ChildSAs: []ChildSA{
+				{
+					Name:        "beijing.edge1-n2p",
+					UniqueID:    "10",
+					ReqID:       "4",
+					State:       "REKEYED",
+					Mode:        "TUNNEL",
+					Protocol:    "ESP",
+					SPIIn:       "c9b2d3e4",
+					SPIOut:      "c1a2b3c4",
+					EncrAlg:     "AES_GCM_16",
+					EncrKeysize: "128",
+					LifeTime:    7,
+					InstallTime: 3593,
+					LocalTS:     []string{"10.22.46.47/32"},
+					RemoteTS:    []string{"10.233.67.0/24"},
+				},
+				{
+					Name:        "beijing.edge1-p2p",
+					UniqueID:    "9",
+					ReqID:       "3",
+					State:       ChildStateInstalled,
+					Mode:        "TUNNEL",
+					Protocol:    "ESP",
+					SPIIn:       "c8e1a6f2",
+					SPIOut:      "c27b9d41",
+					EncrAlg:     "AES_GCM_16",
+					EncrKeysize: "128",
+					BytesIn:     20160,
+					PacketsIn:   240,
+					BytesOut:    17724,
+					PacketsOut:  211,
+					InstallTime: 1043,
+					RekeyTime:   2011,
+					LifeTime:    2557,
+					LocalTS:     []string{"10.233.64.0/18"},
+					RemoteTS:    []string{"10.233.67.0/24"},
+				},
+			},
+		},
+		{
+			Name:         "beijing.edge2",
+			UniqueID:     "4",
+			Version:      "2",
+			State:        IKEStateConnecting,
+			LocalHost:    "10.22.46.47",
+			LocalPort:    "500",
+			LocalID:      "C=CN, O=fabedge.io, CN=beijing.connector",
+			RemoteHost:   "10.22.46.45",
+			RemotePort:   "500",
+			RemoteID:     "%any",
+			Initiator:    true,
+			InitiatorSPI: "0a1b2c3d4e5f6a7b",
+			ResponderSPI: "0000000000000000",
+			ChildSAs:     []ChildSA{},
+		},
+	}
+
+	if !reflect.DeepEqual(sas, expected) {
+		t.Errorf("expected %+v, got %+v", expected, sas)
+	}
+}
+
+func TestParseSAsEmpty(t *testing.T) {
+	testCases := []struct {
+		name   string
+		output string
+	}{
+		{"empty output", ""},
+		{"reply only", "list-sas reply {\n}\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sas, err := ParseSAs(tc.output)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if len(sas) != 0 {
+				t.Errorf("expected no SAs, got %+v", sas)
+			}
+		})
+	}
+}
+
+func TestIKESA(t *testing.T) {
+	testCases := []struct {
+		name              string
+		sa                IKESA
+		algorithms        string
+		established       bool
+		installedChildSAs int
+	}{
+		{
+			name: "established",
+			sa: IKESA{
+				State:       IKEStateEstablished,
+				EncrAlg:     "AES_CBC",
+				EncrKeysize: "128",
+				IntegAlg:    "HMAC_SHA2_256_128",
+				PRFAlg:      "PRF_AES128_XCBC",
+				DHGroup:     "ECP_256",
+				ChildSAs: []ChildSA{
+					{State: ChildStateInstalled},
+					{State: "REKEYED"},
+					{State: ChildStateInstalled},
+				},
+			},
+			algorithms:        "AES_CBC-128/HMAC_SHA2_256_128/PRF_AES128_XCBC/ECP_256",
+			established:       true,
+			installedChildSAs: 2,
+		},
+		{
+			name:       "connecting",
+			sa:         IKESA{State: IKEStateConnecting},
+			algorithms: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.sa.Algorithms(); got != tc.algorithms {
+				t.Errorf("expected algorithms %q, got %q", tc.algorithms, got)
+			}
+
+			if got := tc.sa.IsEstablished(); got != tc.established {
+				t.Errorf("expected established %t, got %t", tc.established, got)
+			}
+
+			if got := tc.sa.InstalledChildSAs(); got != tc.installedChildSAs {
+				t.Errorf("expected %d installed CHILD SAs, got %d", tc.installedChildSAs, got)
+			}
+		})
+	}
+}
+
+func TestChildSAAlgorithms(t *testing.T) {
+	testCases := []struct {
+		name     string
+		sa       ChildSA
+		expected string
+	}{
+		{
+			name:     "AEAD",
+			sa:       ChildSA{EncrAlg: "AES_GCM_16", EncrKeysize: "128"},
+			expected: "AES_GCM_16-128",
+		},
+		{
+			name:     "with integrity and DH group",
+			sa:       ChildSA{EncrAlg: "AES_CBC", EncrKeysize: "256", IntegAlg: "HMAC_SHA2_256_128", DHGroup: "MODP_2048"},
+			expected: "AES_CBC-256/HMAC_SHA2_256_128/MODP_2048",
+		},
+		{
+			name:     "without keysize",
+			sa:       ChildSA{EncrAlg: "CHACHA20_POLY1305"},
+			expected: "CHACHA20_POLY1305",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.sa.Algorithms(); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package strongswan
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Section is a section of VICI message, it contains key-values, lists and sub-sections
+type Section struct {
+	Name     string
+	Values   map[string]string
+	Lists    map[string][]string
+	Sections []*Section
+}
+
+func newSection(name string) *Section {
+	return &Section{
+		Name:   name,
+		Values: make(map[string]string),
+		Lists:  make(map[string][]string),
+	}
+}
+
+// Section returns the sub-section with specified name, nil is returned if not found
+func (s *Section) Section(name string) *Section {
+	for _, sub := range s.Sections {
+		if sub.Name == name {
+			return sub
+		}
+	}
+
+	return nil
+}
+
+func (s *Section) Int(key string) int64 {
+	v, _ := strconv.ParseInt(s.Values[key], 10, 64)
+	return v
+}
+
+func (s *Section) Uint(key string) uint64 {
+	v, _ := strconv.ParseUint(s.Values[key], 10, 64)
+	return v
+}
+
+func (s *Section) Bool(key string) bool {
+	return s.Values[key] == "yes"
+}
+
+// ParseVICI parses VICI messages printed by swanctl with --pretty flag, e.g.
+//
+//	list-sa event {
+//	  beijing.connector {
+//	    state = ESTABLISHED
+//	    child-sas {
+//	      beijing.connector-p2p-2842 {
+//	        local-ts [
+//	          10.233.67.0/24
+//	        ]
+//	      }
+//	    }
+//	  }
+//	}
+//
+// Each event is returned as a section, lines not belonging to any event are ignored.
+func ParseVICI(output string) ([]*Section, error) {
+	var (
+		events  []*Section
+		stack   []*Section
+		list    string
+		inList  bool
+		lineNum int
+	)
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if inList {
+			if line == "]" {
+				inList = false
+			} else {
+				current := stack[len(stack)-1]
+				current.Lists[list] = append(current.Lists[list], line)
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasSuffix(line, " {") || line == "{":
+			section := newSection(strings.TrimSpace(strings.TrimSuffix(line, "{")))
+			if len(stack) == 0 {
+				events = append(events, section)
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Sections = append(parent.Sections, section)
+			}
+			stack = append(stack, section)
+		case line == "}":
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: unexpected }", lineNum)
+			}
+			stack = stack[:len(stack)-1]
+		case len(stack) == 0:
+			// messages which are not VICI data, e.g. errors or notices
+			continue
+		case strings.HasSuffix(line, " ["):
+			list, inList = strings.TrimSuffix(line, " ["), true
+			current := stack[len(stack)-1]
+			// make sure empty lists are recorded
+			if _, ok := current.Lists[list]; !ok {
+				current.Lists[list] = []string{}
+			}
+		default:
+			index := strings.Index(line, " =")
+			if index == -1 {
+				return nil, fmt.Errorf("line %d: unexpected content: %s", lineNum, line)
+			}
+
+			key, value := line[:index], strings.TrimSpace(line[index+2:])
+			stack[len(stack)-1].Values[key] = value
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(stack) > 0 || inList {
+		return nil, fmt.Errorf("unexpected end of output")
+	}
+
+	return events, nil
+}
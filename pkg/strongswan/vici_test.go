@@ -0,0 +1,134 @@
+package strongswan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVICI(t *testing.T) {
+	output := `no files found matching '/etc/swanctl/conf.d/*.conf'
+list-sa event {
+  beijing.connector {
+    uniqueid = 1
+    state = ESTABLISHED
+    local-id = C=CN, O=fabedge.io, CN=beijing.edge1
+    child-sas {
+      beijing.connector-p2p-2 {
+        name = beijing.connector-p2p
+        local-ts [
+          10.233.67.0/24
+        ]
+        remote-ts [
+        ]
+      }
+    }
+  }
+}
+list-sas reply {
+}
+`
+
+	events, err := ParseVICI(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+
+	if events[0].Name != "list-sa event" || events[1].Name != "list-sas reply" {
+		t.Errorf("unexpected event names: %q, %q", events[0].Name, events[1].Name)
+	}
+
+	sa := events[0].Section("beijing.connector")
+	if sa == nil {
+		t.Fatalf("section beijing.connector not found")
+	}
+
+	if sa.Int("uniqueid") != 1 {
+		t.Errorf("expected uniqueid 1, got %d", sa.Int("uniqueid"))
+	}
+
+	if sa.Values["local-id"] != "C=CN, O=fabedge.io, CN=beijing.edge1" {
+		t.Errorf("unexpected local-id: %q", sa.Values["local-id"])
+	}
+
+	child := sa.Section("child-sas").Section("beijing.connector-p2p-2")
+	if child == nil {
+		t.Fatalf("section beijing.connector-p2p-2 not found")
+	}
+
+	if !reflect.DeepEqual(child.Lists["local-ts"], []string{"10.233.67.0/24"}) {
+		t.Errorf("unexpected local-ts: %v", child.Lists["local-ts"])
+	}
+
+	if ts, ok := child.Lists["remote-ts"]; !ok || len(ts) != 0 {
+		t.Errorf("expected an empty remote-ts, got %v, %t", ts, ok)
+	}
+
+	if sa.Section("not-exist") != nil {
+		t.Errorf("expected nil for a section which doesn't exist")
+	}
+}
+
+func TestParseVICIErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		output string
+	}{
+		{
+			name:   "unexpected close brace",
+			output: "}\n",
+		},
+		{
+			name:   "unexpected content",
+			output: "list-sa event {\n  state ESTABLISHED\n}\n",
+		},
+		{
+			name:   "unclosed section",
+			output: "list-sa event {\n  beijing.connector {\n  }\n",
+		},
+		{
+			name:   "unclosed list",
+			output: "list-sa event {\n  local-ts [\n    10.233.67.0/24\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseVICI(tc.output); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestSectionValues(t *testing.T) {
+	s := newSection("test")
+	s.Values["int"] = "-10"
+	s.Values["uint"] = "18446744073709551615"
+	s.Values["yes"] = "yes"
+	s.Values["no"] = "no"
+	s.Values["invalid"] = "abc"
+
+	testCases := []struct {
+		name     string
+		got      interface{}
+		expected interface{}
+	}{
+		{"int", s.Int("int"), int64(-10)},
+		{"uint", s.Uint("uint"), uint64(18446744073709551615)},
+		{"invalid int", s.Int("invalid"), int64(0)},
+		{"missing int", s.Int("missing"), int64(0)},
+		{"yes", s.Bool("yes"), true},
+		{"no", s.Bool("no"), false},
+		{"missing bool", s.Bool("missing"), false},
+	}
+
+	for _, tc := range testCases {
+		if tc.got != tc.expected {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, tc.got)
+		}
+	}
+}
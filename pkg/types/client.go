@@ -1,15 +1,23 @@
 package types
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"strings"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	apisv1 "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
@@ -18,9 +26,37 @@ import (
 type ObjectKey = client.ObjectKey
 
 type ExecResult struct {
-	Stdout string
-	Stderr string
-	Err    error
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Err      error
+}
+
+// AsError returns an error if cmd failed to execute or exited with non-zero code
+func (r ExecResult) AsError() error {
+	if r.Err != nil {
+		return r.Err
+	}
+
+	if r.ExitCode != 0 {
+		msg := strings.TrimSpace(r.Stderr)
+		if msg == "" {
+			msg = strings.TrimSpace(r.Stdout)
+		}
+		return fmt.Errorf("command exited with code %d: %s", r.ExitCode, msg)
+	}
+
+	return nil
+}
+
+// ExecOptions provides streams for ExecStream, nil streams are not attached
+type ExecOptions struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	TTY               bool
+	TerminalSizeQueue remotecommand.TerminalSizeQueue
 }
 
 type Client struct {
@@ -61,6 +97,18 @@ func (c Client) ListNodes(ctx context.Context, labels client.MatchingLabels) ([]
 	return nodes.Items, err
 }
 
+// ListNodesBySelector returns nodes matched by a label selector like "a=b,c!=d"
+func (c Client) ListNodesBySelector(ctx context.Context, selector string) ([]corev1.Node, error) {
+	s, err := labels.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes corev1.NodeList
+	err = c.List(ctx, &nodes, client.MatchingLabelsSelector{Selector: s})
+	return nodes.Items, err
+}
+
 func (c Client) ListClusters(ctx context.Context) ([]apisv1.Cluster, error) {
 	var clusters apisv1.ClusterList
 	err := c.List(context.Background(), &clusters)
@@ -68,7 +116,68 @@ func (c Client) ListClusters(ctx context.Context) ([]apisv1.Cluster, error) {
 	return clusters.Items, err
 }
 
+// ListAgentPods returns fabedge-agent pods in FabEdge namespace
+func (c Client) ListAgentPods(ctx context.Context) ([]corev1.Pod, error) {
+	var podList corev1.PodList
+	if err := c.List(ctx, &podList, client.InNamespace(c.namespace)); err != nil {
+		return nil, err
+	}
+
+	var pods []corev1.Pod
+	for _, pod := range podList.Items {
+		if strings.HasPrefix(pod.Name, "fabedge-agent-") {
+			pods = append(pods, pod)
+		}
+	}
+
+	return pods, nil
+}
+
+func (c Client) ListConnectorPods(ctx context.Context) ([]corev1.Pod, error) {
+	var podList corev1.PodList
+	err := c.List(ctx, &podList, client.InNamespace(c.namespace), client.MatchingLabels{
+		"app": "fabedge-connector",
+	})
+
+	return podList.Items, err
+}
+
 func (c Client) Exec(podName, containerName string, cmd []string) error {
+	return c.ExecStream(context.Background(), podName, containerName, cmd, ExecOptions{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+}
+
+// ExecCapture executes cmd in specified container and captures its output. A non-zero exit code
+// is saved in ExitCode instead of Err, Err is only set when cmd can't be executed or ctx is done.
+func (c Client) ExecCapture(ctx context.Context, podName, containerName string, cmd []string) ExecResult {
+	var stdout, stderr bytes.Buffer
+	err := c.ExecStream(ctx, podName, containerName, cmd, ExecOptions{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+
+	result := ExecResult{Err: err}
+
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitStatus()
+		result.Err = nil
+	}
+
+	// buffers are still written in background if ctx is done before cmd exits
+	if err == nil || err != ctx.Err() {
+		result.Stdout, result.Stderr = stdout.String(), stderr.String()
+	}
+
+	return result
+}
+
+// ExecStream executes cmd in specified container with streams provided by opts. Since
+// the executor can't be cancelled, ExecStream returns ctx.Err() when ctx is done
+// and leaves cmd running in background.
+func (c Client) ExecStream(ctx context.Context, podName, containerName string, cmd []string, opts ExecOptions) error {
 	req := c.clientset.CoreV1().RESTClient().Post().
 		Resource("pods").
 		Name(podName).
@@ -78,10 +187,11 @@ func (c Client) Exec(podName, containerName string, cmd []string) error {
 	req.VersionedParams(&corev1.PodExecOptions{
 		Container: containerName,
 		Command:   cmd,
-		Stdin:     false,
-		Stdout:    true,
-		Stderr:    true,
-		TTY:       false,
+		Stdin:     opts.Stdin != nil,
+		Stdout:    opts.Stdout != nil,
+		// stderr is merged into stdout when TTY is allocated
+		Stderr: opts.Stderr != nil && !opts.TTY,
+		TTY:    opts.TTY,
 	}, scheme.ParameterCodec)
 
 	exec, err := remotecommand.NewSPDYExecutor(c.config, "POST", req.URL())
@@ -89,14 +199,39 @@ func (c Client) Exec(podName, containerName string, cmd []string) error {
 		return err
 	}
 
-	err = exec.Stream(remotecommand.StreamOptions{
-		Stdin:  nil,
-		Stdout: os.Stdout,
-		Stderr: os.Stderr,
-		Tty:    false,
-	})
+	streamOptions := remotecommand.StreamOptions{
+		Stdin:             opts.Stdin,
+		Stdout:            opts.Stdout,
+		Stderr:            opts.Stderr,
+		Tty:               opts.TTY,
+		TerminalSizeQueue: opts.TerminalSizeQueue,
+	}
+	if opts.TTY {
+		streamOptions.Stderr = nil
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- exec.Stream(streamOptions)
+	}()
+
+	select {
+	case err = <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// GetLogs returns logs of a container, if since is positive, only logs newer than since are returned
+func (c Client) GetLogs(ctx context.Context, podName, containerName string, since time.Duration) ([]byte, error) {
+	opts := &corev1.PodLogOptions{Container: containerName}
+	if since > 0 {
+		seconds := int64(since.Seconds())
+		opts.SinceSeconds = &seconds
+	}
 
-	return err
+	return c.clientset.CoreV1().Pods(c.namespace).GetLogs(podName, opts).DoRaw(ctx)
 }
 
 func (c Client) GetNamespace() string {
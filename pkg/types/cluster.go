@@ -2,17 +2,21 @@ package types
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	apisv1 "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
 	"github.com/fabedge/fabedge/pkg/common/constants"
 	ftypes "github.com/fabedge/fabedge/pkg/operator/types"
 	nodeutil "github.com/fabedge/fabedge/pkg/util/node"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 )
 
 type Cluster struct {
 	client            *Client
 	Name              string
+	Role              string
 	CNIType           string
 	EdgeLabels        map[string]string
 	EndpointIDFormat  string
@@ -37,9 +41,10 @@ func (cluster *Cluster) ExtractArgumentsFromFabEdge() error {
 
 	args := NewArgs(operator.Spec.Template.Spec.Containers[0].Args)
 	cluster.Name = args.GetValue("cluster")
+	cluster.Role = args.GetValue("cluster-role")
 	cluster.CNIType = args.GetValue("cni-type")
 	cluster.EndpointIDFormat = args.GetValueOrDefault("endpoint-id-format", "C=CN, O=fabedge.io, CN={node}")
-	cluster.EdgeLabels = parseLabels(args.GetValueOrDefault("edge-labels", "C=CN, O=fabedge.io, CN={node}"))
+	cluster.EdgeLabels = parseLabels(args.GetValueOrDefault("edge-labels", "node-role.kubernetes.io/edge"))
 
 	var getPodCIDR ftypes.PodCIDRsGetter
 	switch cluster.CNIType {
@@ -71,12 +76,61 @@ func (cluster *Cluster) LoadCommunities() error {
 	return nil
 }
 
+// ConnectorName returns the endpoint name of connector of this cluster
+func (cluster *Cluster) ConnectorName() string {
+	return fmt.Sprintf("%s.connector", cluster.Name)
+}
+
+// IsEdgeNode returns true if node has all edge labels of this cluster
+func (cluster *Cluster) IsEdgeNode(node corev1.Node) bool {
+	for key, value := range cluster.EdgeLabels {
+		if v, ok := node.Labels[key]; !ok || v != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CommunityPeers returns names of endpoints which share at least one community with specified endpoint
+func (cluster *Cluster) CommunityPeers(endpointName string) sets.String {
+	peers := sets.NewString()
+	for _, name := range cluster.EdgeToCommunities[endpointName] {
+		peers.Insert(cluster.Communities[name].Spec.Members...)
+	}
+	peers.Delete(endpointName)
+
+	return peers
+}
+
+// EdgePeers returns names of endpoints which an edge endpoint is expected to have tunnels with,
+// they are connector and community peers of the edge endpoint
+func (cluster *Cluster) EdgePeers(endpointName string) sets.String {
+	return cluster.CommunityPeers(endpointName).Insert(cluster.ConnectorName())
+}
+
+// ConnectorPeers returns names of endpoints which connector is expected to have tunnels with,
+// they are endpoints of all edge nodes and community peers of connector
+func (cluster *Cluster) ConnectorPeers(edgeNodes []corev1.Node) sets.String {
+	peers := cluster.CommunityPeers(cluster.ConnectorName())
+	for _, node := range edgeNodes {
+		peers.Insert(cluster.NewEndpoint(node).Name)
+	}
+
+	return peers
+}
+
 func parseLabels(labels string) map[string]string {
 	labels = strings.TrimSpace(labels)
 
 	parsedEdgeLabels := make(map[string]string)
 	for _, label := range strings.Split(labels, ",") {
-		parts := strings.SplitN(label, "=", 1)
+		label = strings.TrimSpace(label)
+		if label == "" {
+			continue
+		}
+
+		parts := strings.SplitN(label, "=", 2)
 		switch len(parts) {
 		case 1:
 			parsedEdgeLabels[parts[0]] = ""
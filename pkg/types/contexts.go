@@ -0,0 +1,74 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/fabedge/fabctl/pkg/printer"
+)
+
+// AnnotationMultiContexts marks commands which support --contexts and --all-contexts,
+// other commands fail if either of them is provided
+const AnnotationMultiContexts = "fabctl/multi-contexts"
+
+// MultiContexts returns annotations of commands which support --contexts and --all-contexts
+func MultiContexts() map[string]string {
+	return map[string]string{AnnotationMultiContexts: "true"}
+}
+
+// ResultFunc executes a command against the cluster provided by getter and returns a printable result
+type ResultFunc func(getter ClientGetter) (interface{}, error)
+
+// PrintInContexts executes fn against every context selected by --contexts or --all-contexts
+// concurrently and prints the merged results. If no context is selected, fn is executed
+// against current context and its result is printed as usual.
+func PrintInContexts(getter ClientGetter, p printer.Printer, fn ResultFunc) error {
+	contexts, err := getter.Contexts()
+	if err != nil {
+		return err
+	}
+
+	if len(contexts) == 0 {
+		result, err := fn(getter)
+		if err != nil {
+			return err
+		}
+
+		return p.Print(result)
+	}
+
+	results := RunInContexts(getter, contexts, fn)
+	if err = p.Print(results); err != nil {
+		return err
+	}
+
+	if errs := results.Errors(); len(errs) > 0 {
+		return fmt.Errorf("command failed in %d of %d contexts:\n%s", len(errs), len(results), strings.Join(errs, "\n"))
+	}
+
+	return nil
+}
+
+// RunInContexts executes fn against contexts concurrently and
+// returns results in the same order of contexts
+func RunInContexts(getter ClientGetter, contexts []string, fn ResultFunc) printer.ClusterResults {
+	results := make(printer.ClusterResults, len(contexts))
+
+	var wg sync.WaitGroup
+	for i, name := range contexts {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+
+			result, err := fn(getter.ForContext(name))
+			results[i] = printer.ClusterResult{Cluster: name, Result: result}
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		}(i, name)
+	}
+	wg.Wait()
+
+	return results
+}
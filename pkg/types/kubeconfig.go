@@ -1,22 +1,52 @@
 package types
 
 import (
-	"flag"
+	"fmt"
+	"sort"
+	"sync"
 
+	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	"github.com/fabedge/fabctl/pkg/printer"
 )
 
 type ClientGetter interface {
 	GetConfig() (*rest.Config, error)
 	GetClient() (*Client, error)
+	GetPrinter() (printer.Printer, error)
+
+	// Contexts returns kubeconfig contexts selected by --contexts or --all-contexts,
+	// if none is selected, commands work against current context only
+	Contexts() ([]string, error)
+	// ForContext returns a ClientGetter which works against the specified context
+	ForContext(name string) ClientGetter
 }
 
 type ClientFactory struct {
 	Namespace string
+	Output    string
+
+	KubeConfig  string
+	Context     string
+	Cluster     string
+	User        string
+	Server      string
+	Token       string
+	Impersonate string
+
+	AllContexts bool
+	ContextList []string
+
+	mux       sync.Mutex
+	config    *rest.Config
+	client    *Client
+	configErr error
 }
 
 func NewClientFlags() *ClientFactory {
@@ -24,20 +54,148 @@ func NewClientFlags() *ClientFactory {
 }
 
 func (cfg *ClientFactory) AddFlags(fs *pflag.FlagSet) {
-	fs.AddGoFlagSet(flag.CommandLine)
+	fs.StringVar(&cfg.KubeConfig, "kubeconfig", "", "Path to the kubeconfig file to use, if not specified, KUBECONFIG env and ~/.kube/config are used")
+	fs.StringVar(&cfg.Context, "context", "", "The name of the kubeconfig context to use")
+	fs.StringVar(&cfg.Cluster, "cluster", "", "The name of the kubeconfig cluster to use")
+	fs.StringVar(&cfg.User, "user", "", "The name of the kubeconfig user to use")
+	fs.StringVarP(&cfg.Server, "server", "s", "", "The address and port of the Kubernetes API server")
+	fs.StringVar(&cfg.Token, "token", "", "Bearer token for authentication to the API server")
+	fs.StringVar(&cfg.Impersonate, "as", "", "Username to impersonate for the operation")
+	fs.BoolVar(&cfg.AllContexts, "all-contexts", false, "Execute command against all contexts in kubeconfig, only some commands support it")
+	fs.StringSliceVar(&cfg.ContextList, "contexts", nil, "Execute command against specified contexts, e.g. host,member1. Only some commands support it")
 	fs.StringVarP(&cfg.Namespace, "namespace", "n", "fabedge", "The namespace where FabEdge is deployed.")
+	fs.StringVarP(&cfg.Output, "output", "o", "", fmt.Sprintf("Output format, possible values: %s. If not specified, a human-readable description is printed.", printer.FormatNames()))
+}
+
+// ClientConfig returns a kubeconfig loader which merges kubeconfig files and
+// overrides from command line flags
+func (cfg *ClientFactory) ClientConfig() clientcmd.ClientConfig {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = cfg.KubeConfig
+
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: cfg.Context,
+		Context: clientcmdapi.Context{
+			Cluster:  cfg.Cluster,
+			AuthInfo: cfg.User,
+		},
+		ClusterInfo: clientcmdapi.Cluster{
+			Server: cfg.Server,
+		},
+		AuthInfo: clientcmdapi.AuthInfo{
+			Token:       cfg.Token,
+			Impersonate: cfg.Impersonate,
+		},
+	}
+
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+}
+
+// GetConfig returns the rest config built from kubeconfig and flags, the config
+// is only built once, later calls return the same instance
+func (cfg *ClientFactory) GetConfig() (*rest.Config, error) {
+	cfg.mux.Lock()
+	defer cfg.mux.Unlock()
+
+	return cfg.getConfig()
+}
+
+func (cfg *ClientFactory) getConfig() (*rest.Config, error) {
+	if cfg.config == nil && cfg.configErr == nil {
+		cfg.config, cfg.configErr = cfg.ClientConfig().ClientConfig()
+	}
+
+	return cfg.config, cfg.configErr
+}
+
+func (cfg *ClientFactory) GetClient() (*Client, error) {
+	cfg.mux.Lock()
+	defer cfg.mux.Unlock()
+
+	if cfg.client != nil {
+		return cfg.client, nil
+	}
+
+	restConfig, err := cfg.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cli, err := NewClient(restConfig, cfg.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	cfg.client = cli
+
+	return cli, nil
 }
 
-func (cfg ClientFactory) GetConfig() (*rest.Config, error) {
-	return config.GetConfig()
+func (cfg *ClientFactory) GetPrinter() (printer.Printer, error) {
+	return printer.New(cfg.Output)
 }
 
-func (cfg ClientFactory) GetClient() (*Client, error) {
-	restConfig, err := config.GetConfig()
+func (cfg *ClientFactory) Contexts() ([]string, error) {
+	if !cfg.AllContexts {
+		return cfg.ContextList, nil
+	}
+
+	rawConfig, err := cfg.ClientConfig().RawConfig()
 	if err != nil {
 		return nil, err
 	}
 
+	var names []string
+	for name := range rawConfig.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Validate checks if cmd supports multiple contexts when --contexts or --all-contexts is provided,
+// flags which override cluster or user of a context are not allowed with multiple contexts
+func (cfg *ClientFactory) Validate(cmd *cobra.Command) error {
+	if !cfg.AllContexts && len(cfg.ContextList) == 0 {
+		return nil
+	}
+
+	if cmd.Annotations[AnnotationMultiContexts] != "true" {
+		return fmt.Errorf("--contexts and --all-contexts are not supported by %s", cmd.CommandPath())
+	}
+
+	overrides := []struct {
+		flag  string
+		value string
+	}{
+		{"--context", cfg.Context},
+		{"--cluster", cfg.Cluster},
+		{"--user", cfg.User},
+		{"--server", cfg.Server},
+		{"--token", cfg.Token},
+	}
+	for _, o := range overrides {
+		if o.value != "" {
+			return fmt.Errorf("%s can't be used with --contexts or --all-contexts", o.flag)
+		}
+	}
+
+	return nil
+}
+
+// ForContext returns a ClientFactory of the context, flags which override cluster or user
+// are not passed, because they are not allowed with multiple contexts
+func (cfg *ClientFactory) ForContext(name string) ClientGetter {
+	return &ClientFactory{
+		Namespace:   cfg.Namespace,
+		Output:      cfg.Output,
+		KubeConfig:  cfg.KubeConfig,
+		Context:     name,
+		Impersonate: cfg.Impersonate,
+	}
+}
+
+func NewClient(restConfig *rest.Config, namespace string) (*Client, error) {
 	cli, err := client.New(restConfig, client.Options{})
 	if err != nil {
 		return nil, err
@@ -52,6 +210,6 @@ func (cfg ClientFactory) GetClient() (*Client, error) {
 		config:    restConfig,
 		Client:    cli,
 		clientset: clientset,
-		namespace: cfg.Namespace,
+		namespace: namespace,
 	}, nil
 }
@@ -3,6 +3,8 @@ package util
 import (
 	"fmt"
 	"os"
+	"sync"
+	"time"
 )
 
 func Exitf(format string, args ...interface{}) {
@@ -19,3 +21,32 @@ func CheckError(err error) {
 	fmt.Fprintln(os.Stderr)
 	os.Exit(1)
 }
+
+// Parallel calls fn with every index in [0, count) using at most workers goroutines
+// and waits for all calls to finish
+func Parallel(workers, count int, fn func(i int)) {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// FormatSeconds formats seconds as a duration, e.g. 1h2m3s
+func FormatSeconds(seconds int64) string {
+	return (time.Duration(seconds) * time.Second).String()
+}